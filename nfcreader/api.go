@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// errNoNDEF is returned when the data area holds no NDEF Message TLV
+var errNoNDEF = errors.New("no NDEF message TLV found")
+
+// errRecordNotFound is returned when the message holds no record of the requested kind
+var errRecordNotFound = errors.New("no matching record on tag")
+
+// readNDEFMessage reads the capability container and walks the TLVs of the
+// data area (starting at page 4), returning the value of the first NDEF
+// Message TLV. Pages are read lazily, only up to the end of the message.
+func readNDEFMessage(card transceiver) ([]byte, error) {
+	cc, err := readPage(card, 0x03)
+	if err != nil {
+		return nil, fmt.Errorf("read capability container: %w", err)
+	}
+	if len(cc) < 4 || cc[0] != 0xE1 {
+		return nil, fmt.Errorf("tag is not NDEF formatted (CC: % X)", cc)
+	}
+	dataSize := int(cc[2]) * 8
+
+	var area []byte
+	need := func(n int) error {
+		for len(area) < n {
+			if len(area) >= dataSize {
+				return errors.New("TLV runs past the end of the data area")
+			}
+			page := byte(0x04 + len(area)/4)
+			data, err := readPage(card, page)
+			if err != nil {
+				return fmt.Errorf("read page %d: %w", page, err)
+			}
+			area = append(area, data...)
+		}
+		return nil
+	}
+
+	offset := 0
+	for {
+		if err := need(offset + 1); err != nil {
+			return nil, err
+		}
+		tlvType := area[offset]
+		switch tlvType {
+		case 0x00: // NULL TLV
+			offset++
+			continue
+		case 0xFE: // Terminator
+			return nil, errNoNDEF
+		}
+
+		if err := need(offset + 2); err != nil {
+			return nil, err
+		}
+		length, header := int(area[offset+1]), 2
+		if length == 0xFF {
+			if err := need(offset + 4); err != nil {
+				return nil, err
+			}
+			length, header = int(area[offset+2])<<8|int(area[offset+3]), 4
+		}
+		if err := need(offset + header + length); err != nil {
+			return nil, err
+		}
+		if tlvType == 0x03 {
+			return area[offset+header : offset+header+length], nil
+		}
+		offset += header + length // Skip Lock/Memory Control and proprietary TLVs
+	}
+}
+
+// readFirstRecord reads and decodes the tag's NDEF message and returns the
+// first record of the given kind (see recordKinds)
+func readFirstRecord(card transceiver, kind string) (ndefRecord, error) {
+	msg, err := readNDEFMessage(card)
+	if err != nil {
+		return ndefRecord{}, err
+	}
+	records, err := decodeNDEFRecords(msg)
+	for _, rec := range records {
+		if rec.Kind() == kind {
+			return rec, nil
+		}
+	}
+	if err != nil {
+		return ndefRecord{}, fmt.Errorf("parse NDEF: %w", err)
+	}
+	return ndefRecord{}, fmt.Errorf("%w: no %s record", errRecordNotFound, kind)
+}
+
+// ReadFirstURI returns the full URI of the first URI record on the tag
+func ReadFirstURI(card transceiver) (string, error) {
+	rec, err := readFirstRecord(card, "uri")
+	if err != nil {
+		return "", err
+	}
+	if len(rec.Payload) == 0 {
+		return "", errors.New("empty URI payload")
+	}
+	if rec.Payload[0] > 0x23 {
+		return "", fmt.Errorf("unknown URI identifier code 0x%02X", rec.Payload[0])
+	}
+	return getURIPrefix(rec.Payload[0]) + string(rec.Payload[1:]), nil
+}
+
+// ReadFirstText returns the text and language code of the first Text record on the tag
+func ReadFirstText(card transceiver) (string, string, error) {
+	rec, err := readFirstRecord(card, "text")
+	if err != nil {
+		return "", "", err
+	}
+	return decodeTextPayload(rec.Payload)
+}
+
+// decodeTextPayload returns the text and language code of a Text record
+// payload, converting UTF-16 text (big-endian unless a BOM says otherwise)
+func decodeTextPayload(payload []byte) (string, string, error) {
+	if len(payload) == 0 {
+		return "", "", errors.New("empty text payload")
+	}
+	langLen := int(payload[0] & 0x3F)
+	if 1+langLen > len(payload) {
+		return "", "", errors.New("text payload shorter than its language code")
+	}
+	lang, text := string(payload[1:1+langLen]), payload[1+langLen:]
+	if payload[0]&0x80 == 0 {
+		return string(text), lang, nil
+	}
+
+	if len(text)%2 != 0 {
+		return "", "", errors.New("odd-length UTF-16 text")
+	}
+	littleEndian := false
+	if len(text) >= 2 && (text[0] == 0xFF && text[1] == 0xFE || text[0] == 0xFE && text[1] == 0xFF) {
+		littleEndian = text[0] == 0xFF
+		text = text[2:]
+	}
+	units := make([]uint16, len(text)/2)
+	for i := range units {
+		if littleEndian {
+			units[i] = uint16(text[2*i]) | uint16(text[2*i+1])<<8
+		} else {
+			units[i] = uint16(text[2*i])<<8 | uint16(text[2*i+1])
+		}
+	}
+	return string(utf16.Decode(units)), lang, nil
+}
+
+// PageReadError reports the page at which ReadPages stopped
+type PageReadError struct {
+	Page byte
+	Err  error
+}
+
+func (e *PageReadError) Error() string {
+	return fmt.Sprintf("read page %02X: %v", e.Page, e.Err)
+}
+
+func (e *PageReadError) Unwrap() error {
+	return e.Err
+}
+
+// ReadPages reads pages start through end into one contiguous buffer, in
+// FAST_READ ranges or 16 bytes per transmit where the reader supports it and
+// with the alternative read methods for pages that fail. It stops at the
+// first unreadable page, typically the end of tag memory, returning the pages
+// read before it and a *PageReadError naming that page. On a tag type with a
+// known size, pages past its last page fail without being read, so a block
+// read's rollover to page 0 is never returned as data.
+func ReadPages(card transceiver, start, end byte) ([]byte, error) {
+	if end < start {
+		return nil, fmt.Errorf("invalid page range %02X-%02X", start, end)
+	}
+	pages := newBlockReader(card)
+	tagType := identifyTagType(card)
+	if tagType != "Type2-compatible" && tagType != "unknown" {
+		pages.lastPage = int(maxPageFor(tagType))
+	}
+	pages.enableFastRead(tagType)
+	buf := make([]byte, 0, (int(end)-int(start)+1)*4)
+	for p := int(start); p <= int(end); p++ { // int so an end page of 0xFF can't wrap
+		data, err := pages.read(byte(p))
+		if err != nil && !errors.Is(err, errPastEnd) {
+			data, err = readPageAlternative(card, byte(p))
+		}
+		if err == nil && len(data) < 4 {
+			err = fmt.Errorf("short read (%d bytes)", len(data))
+		}
+		if err != nil {
+			return buf, &PageReadError{Page: byte(p), Err: err}
+		}
+		buf = append(buf, data[:4]...)
+	}
+	return buf, nil
+}
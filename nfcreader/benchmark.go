@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// readAllPages performs a full per-page dump from page 0 to maxPage
+func readAllPages(card transceiver, maxPage byte) ([]byte, error) {
+	var mem []byte
+	for page := 0; page <= int(maxPage); page++ {
+		data, err := readPage(card, byte(page))
+		if err != nil {
+			return mem, fmt.Errorf("page %02X: %w", page, err)
+		}
+		mem = append(mem, data...)
+	}
+	return mem, nil
+}
+
+// readAllBlocks dumps page 0 to maxPage the way the analysis reads: with
+// FAST_READ where the reader and tag type support it, else 16-byte reads
+func readAllBlocks(card transceiver, tagType string, maxPage byte) ([]byte, error) {
+	pages := newBlockReader(card)
+	pages.lastPage = int(maxPage)
+	pages.enableFastRead(tagType)
+	var mem []byte
+	for page := 0; page <= int(maxPage); page++ {
+		data, err := pages.read(byte(page))
+		if err != nil {
+			return mem, fmt.Errorf("page %02X: %w", page, err)
+		}
+		mem = append(mem, data...)
+	}
+	return mem, nil
+}
+
+// benchmarkStats are the timings of one read method over the benchmark runs
+type benchmarkStats struct {
+	completed       int
+	total, min, max time.Duration
+	transmits       int
+	bytesRead       int
+}
+
+// timeReads runs read runs times and collects its timings
+func timeReads(runs int, name string, read func() ([]byte, error)) benchmarkStats {
+	var st benchmarkStats
+	for i := 0; i < runs; i++ {
+		startCount := transmitCount
+		start := time.Now()
+		mem, err := read()
+		elapsed := time.Since(start)
+		if err != nil {
+			fmt.Printf("%s run %d: ❌ %v\n", name, i+1, err)
+			continue
+		}
+
+		st.completed++
+		st.total += elapsed
+		st.transmits += transmitCount - startCount
+		st.bytesRead = len(mem)
+		if st.completed == 1 || elapsed < st.min {
+			st.min = elapsed
+		}
+		if elapsed > st.max {
+			st.max = elapsed
+		}
+	}
+	return st
+}
+
+// runBenchmark times runs full reads of the presented tag, page by page and
+// in blocks (FAST_READ or 16-byte reads), and prints min/avg/max duration and
+// the number of transmits per read for each
+func runBenchmark(card transceiver, runs int) {
+	tagType := identifyTagType(card)
+	maxPage := maxPageFor(tagType)
+
+	fmt.Printf("\n=== READ BENCHMARK ===\n")
+	fmt.Printf("Tag Type: %s (%d pages), %d runs\n", tagType, int(maxPage)+1, runs)
+
+	blockName := "16-byte"
+	if passThroughOK && opts.PagesPerRead > 0 && supportsFastRead(tagType) {
+		blockName = "FAST_READ"
+	}
+	perPage := timeReads(runs, "Per page", func() ([]byte, error) { return readAllPages(card, maxPage) })
+	block := timeReads(runs, blockName, func() ([]byte, error) { return readAllBlocks(card, tagType, maxPage) })
+
+	if perPage.completed == 0 && block.completed == 0 {
+		fmt.Printf("❌ No run completed\n")
+		return
+	}
+	fmt.Printf("\n%-18s %12s %12s\n", "Metric", "Per page", blockName)
+	row := func(metric string, value func(benchmarkStats) string) {
+		cells := [2]string{"-", "-"}
+		for i, st := range []benchmarkStats{perPage, block} {
+			if st.completed > 0 {
+				cells[i] = value(st)
+			}
+		}
+		fmt.Printf("%-18s %12s %12s\n", metric, cells[0], cells[1])
+	}
+	avg := func(st benchmarkStats) time.Duration { return st.total / time.Duration(st.completed) }
+	fmt.Printf("%-18s %12s %12s\n", "Completed runs",
+		fmt.Sprintf("%d/%d", perPage.completed, runs), fmt.Sprintf("%d/%d", block.completed, runs))
+	row("Min", func(st benchmarkStats) string { return st.min.Round(time.Microsecond).String() })
+	row("Avg", func(st benchmarkStats) string { return avg(st).Round(time.Microsecond).String() })
+	row("Max", func(st benchmarkStats) string { return st.max.Round(time.Microsecond).String() })
+	row("Transmits/read", func(st benchmarkStats) string { return fmt.Sprint(st.transmits / st.completed) })
+	row("Bytes/read", func(st benchmarkStats) string { return fmt.Sprint(st.bytesRead) })
+	row("Bytes/second", func(st benchmarkStats) string {
+		if avg(st) <= 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f", float64(st.bytesRead)/avg(st).Seconds())
+	})
+}
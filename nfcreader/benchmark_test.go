@@ -0,0 +1,23 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestBenchmarkBlockRun(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	tag := &fastReadTag{memoryTag: memoryTag{pages: pages, rollover: true}, maxBytes: 240}
+	passThroughOK, opts = true, Options{PagesPerRead: 60}
+	defer func() { passThroughOK, opts = false, Options{} }()
+
+	out := captureStdout(t, func() { runBenchmark(tag, 2) })
+	// 231 single pages against four FAST_READs of up to 60 pages
+	if !regexp.MustCompile(`Transmits/read +231 +4\n`).MatchString(out) {
+		t.Errorf("benchmark table:\n%s", out)
+	}
+	if !regexp.MustCompile(`Bytes/read +924 +924\n`).MatchString(out) {
+		t.Errorf("benchmark table:\n%s", out)
+	}
+}
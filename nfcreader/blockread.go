@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errBlockLength is returned when a 16-byte read answers with another length
+var errBlockLength = errors.New("16-byte read returned the wrong length")
+
+// errPastEnd is returned for a page beyond the last page of tag memory
+var errPastEnd = errors.New("past the end of tag memory")
+
+// readFourPages reads pages page..page+3 with one 16-byte READ (Ultralight
+// READ 0x30 returns 4 pages) and splits the result into pages. On NTAG the
+// read rolls over past the last page, so callers ignore pages beyond it.
+func readFourPages(card transceiver, page byte) ([][]byte, error) {
+	data, err := transmit(card, []byte{0xFF, 0xB0, 0x00, page, 0x10})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 16 {
+		return nil, fmt.Errorf("%w (%d bytes)", errBlockLength, len(data))
+	}
+	return [][]byte{data[0:4], data[4:8], data[8:12], data[12:16]}, nil
+}
+
+// fastRead reads pages start through end with one FAST_READ (3A) through
+// reader pass-through and splits the answer into pages
+func fastRead(card transceiver, start, end byte) ([][]byte, error) {
+	data, err := passThrough(card, []byte{0x3A, start, end})
+	if err != nil {
+		return nil, err
+	}
+	n := int(end) - int(start) + 1
+	if len(data) != n*4 {
+		return nil, fmt.Errorf("FAST_READ of %d pages returned %d bytes", n, len(data))
+	}
+	pages := make([][]byte, n)
+	for i := range pages {
+		pages[i] = data[i*4 : i*4+4]
+	}
+	return pages, nil
+}
+
+// defaultPagesPerRead picks how many pages each FAST_READ requests for a
+// reader: PN53x readers known to take a full 240-byte answer get 60 pages,
+// others 15 pages (60 bytes), which fits the smallest buffers seen
+func defaultPagesPerRead(reader string) int {
+	for _, name := range []string{"ACR122", "SCL3711"} {
+		if strings.Contains(reader, name) {
+			return 60
+		}
+	}
+	return 15
+}
+
+// blockReader serves page reads from 16-byte reads, cutting transmits 4x.
+// A failed block read falls back to a single-page read; a reader that
+// answers with the wrong length is only asked for single pages afterwards.
+// With FAST_READ enabled, pages come in ranges of up to fast pages instead.
+// NTAG rolls a multi-page read over to page 0, so with the last page of
+// memory known, pages past it are neither cached nor read.
+type blockReader struct {
+	card     transceiver
+	cache    map[int][]byte
+	noBlock  bool
+	fast     int  // Pages per FAST_READ, 0 = FAST_READ off
+	last     byte // Last page FAST_READ may request
+	lastPage int  // Last page of tag memory, -1 = unknown
+}
+
+// newBlockReader returns a page reader for card with an empty cache
+func newBlockReader(card transceiver) *blockReader {
+	return &blockReader{card: card, cache: make(map[int][]byte), lastPage: -1}
+}
+
+// store caches pages read from first on, dropping any past the last page
+func (b *blockReader) store(first byte, pages [][]byte) {
+	for i, data := range pages {
+		if b.lastPage >= 0 && int(first)+i > b.lastPage {
+			break
+		}
+		b.cache[int(first)+i] = data
+	}
+}
+
+// supportsFastRead reports whether a tag type implements FAST_READ. Plain
+// Ultralight and unidentified Type 2 tags answer it with a NAK.
+func supportsFastRead(tagType string) bool {
+	switch tagType {
+	case "NTAG213", "NTAG215", "NTAG216", ntagI2C1K, ntagI2CPlus1K, ntagI2C2K, ntagI2CPlus2K:
+		return true
+	}
+	return false
+}
+
+// enableFastRead reads the pages of a tagType tag with FAST_READ,
+// opts.PagesPerRead pages at a time, when the reader supports pass-through
+// and the tag type implements it. Ranges end at the type's last page.
+func (b *blockReader) enableFastRead(tagType string) {
+	if passThroughOK && opts.PagesPerRead > 0 && supportsFastRead(tagType) {
+		b.fast, b.last = opts.PagesPerRead, maxPageFor(tagType)
+	}
+}
+
+// cached reports whether page comes from an earlier multi-page read
+func (b *blockReader) cached(page byte) bool {
+	_, ok := b.cache[int(page)]
+	return ok
+}
+
+// read returns one page, from the cache when a block read already covered it
+func (b *blockReader) read(page byte) ([]byte, error) {
+	if data, ok := b.cache[int(page)]; ok {
+		return data, nil
+	}
+	if b.lastPage >= 0 && int(page) > b.lastPage {
+		return nil, errPastEnd
+	}
+	for b.fast > 0 && page <= b.last {
+		end := min(int(page)+b.fast-1, int(b.last))
+		pages, err := fastRead(b.card, page, byte(end))
+		if err == nil {
+			b.store(page, pages)
+			return pages[0], nil
+		}
+		if tagNAKed(err) {
+			// The tag refused it, a smaller range won't help: it is idle
+			// until re-selected, then read without FAST_READ
+			logger.Debugf("FAST_READ %02X-%02X refused by the tag, not using FAST_READ: %v", page, end, err)
+			reselectTag(b.card)
+			b.fast = 0
+			break
+		}
+		// Too many pages for the reader's buffer: halve the range and retry
+		b.fast /= 2
+		logger.Debugf("FAST_READ %02X-%02X failed, now %d pages per read: %v", page, end, b.fast, err)
+	}
+	if !b.noBlock {
+		pages, err := readFourPages(b.card, page)
+		if err == nil {
+			b.store(page, pages)
+			return pages[0], nil
+		}
+		logger.Debugf("16-byte read at page %02X failed, reading single page: %v", page, err)
+		if errors.Is(err, errBlockLength) {
+			b.noBlock = true
+		}
+	}
+	return readPage(b.card, page)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ebfe/scard"
+)
+
+func TestBlockReader(t *testing.T) {
+	tag := &memoryTag{}
+	for p := 0; p < 10; p++ {
+		tag.pages = append(tag.pages, []byte{byte(p), byte(p), byte(p), byte(p)})
+	}
+
+	b := newBlockReader(tag)
+	start := transmitCount
+	for p := 0; p < 8; p++ {
+		data, err := b.read(byte(p))
+		if err != nil {
+			t.Fatalf("page %d: %v", p, err)
+		}
+		if !bytes.Equal(data, tag.pages[p]) {
+			t.Errorf("page %d: got % X", p, data)
+		}
+	}
+	if n := transmitCount - start; n != 2 {
+		t.Errorf("8 pages took %d transmits, want 2", n)
+	}
+
+	// Pages 8-9 are the last two: the short block falls back to single-page reads
+	for p := 8; p < 10; p++ {
+		if data, err := b.read(byte(p)); err != nil || !bytes.Equal(data, tag.pages[p]) {
+			t.Errorf("page %d: got % X, %v", p, data, err)
+		}
+	}
+	if !b.noBlock {
+		t.Error("short 16-byte read did not switch to single-page reads")
+	}
+}
+
+// fastReadTag is a memoryTag that answers FAST_READ through pass-through,
+// failing ranges whose answer exceeds the reader buffer of maxBytes
+type fastReadTag struct {
+	memoryTag
+	maxBytes int
+	ranges   [][2]byte // FAST_READ ranges that succeeded
+}
+
+func (f *fastReadTag) Transmit(cmd []byte) ([]byte, error) {
+	if len(cmd) == 10 && cmd[0] == 0xFF && cmd[5] == 0xD4 && cmd[6] == 0x42 && cmd[7] == 0x3A {
+		start, end := cmd[8], cmd[9]
+		if (int(end)-int(start)+1)*4 > f.maxBytes || int(end) >= len(f.pages) {
+			return []byte{0x6F, 0x00}, nil
+		}
+		f.ranges = append(f.ranges, [2]byte{start, end})
+		resp := []byte{0xD5, 0x43, 0x00}
+		for p := start; p <= end; p++ {
+			resp = append(resp, f.pages[p]...)
+		}
+		return append(resp, 0x90, 0x00), nil
+	}
+	return f.memoryTag.Transmit(cmd)
+}
+
+func TestBlockReaderFastRead(t *testing.T) {
+	tag := &fastReadTag{maxBytes: 60}
+	for p := 0; p < 45; p++ {
+		tag.pages = append(tag.pages, []byte{byte(p), byte(p), byte(p), byte(p)})
+	}
+	passThroughOK, opts = true, Options{PagesPerRead: 60}
+	defer func() { passThroughOK, opts = false, Options{} }()
+
+	b := newBlockReader(tag)
+	b.enableFastRead("NTAG213") // Pages 00-2C
+	for p := 0; p < 45; p++ {
+		if data, err := b.read(byte(p)); err != nil || !bytes.Equal(data, tag.pages[p]) {
+			t.Fatalf("page %d: got % X, %v", p, data, err)
+		}
+	}
+	// 60 and 30 pages overflow the 60-byte buffer, 15 fit
+	want := [][2]byte{{0x00, 0x0E}, {0x0F, 0x1D}, {0x1E, 0x2C}}
+	if len(tag.ranges) != len(want) {
+		t.Fatalf("FAST_READ ranges %X, want %X", tag.ranges, want)
+	}
+	for i := range want {
+		if tag.ranges[i] != want[i] {
+			t.Errorf("FAST_READ ranges %X, want %X", tag.ranges, want)
+		}
+	}
+}
+
+// nakFastReadTag NAKs FAST_READ, like a clone that reports itself as NTAG
+// without implementing it, and counts the attempts and re-selections
+type nakFastReadTag struct {
+	memoryTag
+	fastReads, reconnects int
+}
+
+func (n *nakFastReadTag) Transmit(cmd []byte) ([]byte, error) {
+	if len(cmd) == 10 && cmd[0] == 0xFF && cmd[5] == 0xD4 && cmd[6] == 0x42 && cmd[7] == 0x3A {
+		n.fastReads++
+		return []byte{0xD5, 0x43, 0x01, 0x90, 0x00}, nil
+	}
+	return n.memoryTag.Transmit(cmd)
+}
+
+func (n *nakFastReadTag) Reconnect(scard.ShareMode, scard.Protocol, scard.Disposition) error {
+	n.reconnects++
+	return nil
+}
+
+func TestBlockReaderFastReadGating(t *testing.T) {
+	tag := &nakFastReadTag{}
+	for p := 0; p < 45; p++ {
+		tag.pages = append(tag.pages, []byte{byte(p), byte(p), byte(p), byte(p)})
+	}
+	passThroughOK, opts = true, Options{PagesPerRead: 60}
+	defer func() { passThroughOK, opts = false, Options{} }()
+
+	// A plain Ultralight is never sent FAST_READ
+	b := newBlockReader(tag)
+	b.enableFastRead(ultralightTagType)
+	if _, err := b.read(0); err != nil || tag.fastReads != 0 {
+		t.Errorf("Ultralight: %d FAST_READs, %v", tag.fastReads, err)
+	}
+
+	// A NAK turns FAST_READ off after one attempt, without halving down
+	b = newBlockReader(tag)
+	b.enableFastRead("NTAG213")
+	if b.last != 0x2C {
+		t.Errorf("FAST_READ ends at page %02X, want the NTAG213 last page 2C", b.last)
+	}
+	for p := 0; p < 45; p++ {
+		if data, err := b.read(byte(p)); err != nil || !bytes.Equal(data, tag.pages[p]) {
+			t.Fatalf("page %d: got % X, %v", p, data, err)
+		}
+	}
+	if tag.fastReads != 1 || tag.reconnects != 1 {
+		t.Errorf("%d FAST_READs and %d re-selections after a NAK, want 1 and 1", tag.fastReads, tag.reconnects)
+	}
+}
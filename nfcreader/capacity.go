@@ -0,0 +1,84 @@
+package main
+
+import "fmt"
+
+// standardCCSize returns the CC data area size byte NXP ships for known tag
+// types (user memory / 8), or 0 when the type is not known
+func standardCCSize(tagType string) byte {
+	switch tagType {
+	case ultralightTagType:
+		return 0x06
+	case "NTAG213":
+		return 0x12
+	case "NTAG215":
+		return 0x3E
+	case "NTAG216":
+		return 0x6D
+	case ntagI2C1K, ntagI2CPlus1K:
+		return 0x6D
+	case ntagI2C2K, ntagI2CPlus2K:
+		return 0xEA
+	}
+	return 0
+}
+
+// ndefTLVExtent finds the first NDEF Message TLV in the data area and returns
+// the offset just past its value and the declared message length
+func ndefTLVExtent(data []byte) (end, length int, ok bool) {
+	offset := 0
+	for offset < len(data) {
+		switch data[offset] {
+		case 0x00: // NULL TLV
+			offset++
+			continue
+		case 0xFE: // Terminator
+			return 0, 0, false
+		}
+		length, header, ok := tlvLength(data, offset)
+		if !ok {
+			return 0, 0, false
+		}
+		if data[offset] == tlvNDEF {
+			return offset + header + length, length, true
+		}
+		offset += header + length
+	}
+	return 0, 0, false
+}
+
+// checkNDEFCapacity cross-checks the NDEF TLV against the data area size
+// declared in the CC, and the CC against the tag's real memory size
+func checkNDEFCapacity(data []byte, ccSize byte, tagType string) {
+	fmt.Printf("\n=== NDEF CAPACITY CHECK ===\n")
+	declared := int(ccSize) * 8
+	fmt.Printf("CC-declared data area: %d bytes\n", declared)
+
+	if want := standardCCSize(tagType); want != 0 {
+		switch {
+		case ccSize > want:
+			fmt.Printf("❌ CC size %02X is larger than %s's %02X (%d bytes): CC claims memory the tag does not have\n",
+				ccSize, tagType, want, int(want)*8)
+		case ccSize < want:
+			fmt.Printf("⚠️  CC size %02X is smaller than %s's %02X (%d bytes): CC under-declares the capacity\n",
+				ccSize, tagType, want, int(want)*8)
+		default:
+			fmt.Printf("✅ CC size matches %s\n", tagType)
+		}
+	}
+
+	end, length, ok := ndefTLVExtent(data)
+	if !ok {
+		fmt.Printf("No NDEF TLV to check\n")
+		return
+	}
+	fmt.Printf("NDEF message: %d bytes, TLV ends at data area byte %d\n", length, end)
+	if end > declared {
+		fmt.Printf("❌ NDEF TLV exceeds the CC-declared data area by %d bytes (malformed or oversized)\n", end-declared)
+		return
+	}
+	percent := 0
+	if declared > 0 {
+		percent = end * 100 / declared
+	}
+	fmt.Printf("✅ NDEF TLV fits the CC-declared data area (%d%% used, %d bytes free)\n", percent, declared-end)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cloneSignal is one -probe-clone check and how strongly its outcome points
+// to a clone: 0 = consistent with a genuine tag, 1 = suspicious, 3 = decisive
+type cloneSignal struct {
+	name   string
+	result string
+	weight int
+}
+
+// cloneSignals runs the clone checks on the tag. Page 0 is written back to
+// itself to test for a writable UID, like -probe-magic, after every other
+// check. GET_VERSION and the originality signature need pass-through and are
+// reported as not checked without it.
+func cloneSignals(card transceiver, passThrough bool) []cloneSignal {
+	var signals []cloneSignal
+	add := func(name string, weight int, format string, args ...any) {
+		signals = append(signals, cloneSignal{name: name, result: fmt.Sprintf(format, args...), weight: weight})
+	}
+
+	page0, err0 := readPage(card, 0x00)
+	page1, err1 := readPage(card, 0x01)
+	page2, err2 := readPage(card, 0x02)
+	if err0 != nil || err1 != nil || err2 != nil || len(page0) < 4 || len(page1) < 4 || len(page2) < 4 {
+		add("UID pages", 1, "could not read pages 00-02")
+		return signals
+	}
+	uid := append(append([]byte{}, page0[:3]...), page1...)
+
+	// Manufacturer byte
+	if page0[0] == 0x04 {
+		add("Manufacturer", 0, "04 (NXP)")
+	} else {
+		add("Manufacturer", 1, "%02X, not NXP: cannot be a genuine NTAG/Ultralight", page0[0])
+	}
+
+	// Block check characters (ISO 14443-3): BCC0 = CT 88 ^ UID0-2, BCC1 = UID3-6
+	bcc0 := 0x88 ^ page0[0] ^ page0[1] ^ page0[2]
+	bcc1 := page1[0] ^ page1[1] ^ page1[2] ^ page1[3]
+	switch {
+	case page0[3] != bcc0:
+		add("BCC", 3, "BCC0 is %02X, want %02X: UID written without a valid checksum", page0[3], bcc0)
+	case page2[0] != bcc1:
+		add("BCC", 3, "BCC1 is %02X, want %02X: UID written without a valid checksum", page2[0], bcc1)
+	default:
+		add("BCC", 0, "BCC0 %02X and BCC1 %02X valid", bcc0, bcc1)
+	}
+
+	if passThrough {
+		signals = append(signals, passThroughCloneSignals(card, uid)...)
+	} else {
+		add("GET_VERSION", 0, "not checked, needs reader pass-through")
+		add("Originality signature", 0, "not checked, needs reader pass-through")
+	}
+
+	// Writable UID page, probed last: a genuine tag NAKs the write and goes
+	// idle, so commands after it would fail on a real NTAG
+	if err := writePage(card, 0x00, page0); err != nil {
+		add("UID page write", 0, "rejected, UID is read-only")
+	} else {
+		add("UID page write", 3, "accepted: \"magic\" tag with a writable UID")
+	}
+	return signals
+}
+
+// passThroughCloneSignals checks GET_VERSION and the originality signature
+func passThroughCloneSignals(card transceiver, uid []byte) []cloneSignal {
+	var signals []cloneSignal
+	add := func(name string, weight int, format string, args ...any) {
+		signals = append(signals, cloneSignal{name: name, result: fmt.Sprintf(format, args...), weight: weight})
+	}
+
+	// GET_VERSION
+	if v, err := getVersion(card); err != nil {
+		add("GET_VERSION", 1, "not supported (%v): genuine NTAG21x always answer", err)
+	} else if v[1] != 0x04 {
+		add("GET_VERSION", 1, "vendor %02X in % X does not match NXP", v[1], v)
+	} else {
+		add("GET_VERSION", 0, "% X", v)
+	}
+
+	// Originality signature
+	keyHex := nxpNTAG21xKey
+	if opts.SigKey != "" {
+		keyHex = opts.SigKey
+	}
+	key, keyErr := parseSigKey(keyHex)
+	switch sig, err := readSignature(card); {
+	case err != nil:
+		add("Originality signature", 1, "READ_SIG failed (%v)", err)
+	case keyErr != nil:
+		add("Originality signature", 0, "not verified, invalid public key: %v", keyErr)
+	case !verifyOriginality(uid, sig, key):
+		add("Originality signature", 2, "does not match the UID: UID changed or signature forged")
+	default:
+		add("Originality signature", 0, "valid for this UID (clones can copy it along with the UID)")
+	}
+	return signals
+}
+
+// cloneLikelihood sums the signal weights into low, medium or high
+func cloneLikelihood(signals []cloneSignal) string {
+	score := 0
+	for _, s := range signals {
+		score += s.weight
+	}
+	switch {
+	case score >= 3:
+		return "high"
+	case score > 0:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// probeClone prints every clone signal and the overall verdict, for -probe-clone
+func probeClone(card transceiver) {
+	fmt.Printf("\n=== CLONE PROBE ===\n")
+	signals := cloneSignals(card, passThroughOK)
+	width := 0
+	for _, s := range signals {
+		if len(s.name) > width {
+			width = len(s.name)
+		}
+	}
+	for _, s := range signals {
+		mark := "✅"
+		if s.weight > 0 {
+			mark = "🚩"
+		}
+		fmt.Printf("%s %-*s  %s\n", mark, width, s.name, s.result)
+	}
+	fmt.Printf("%s\n", strings.Repeat("-", 40))
+	fmt.Printf("Clone likelihood: %s\n", cloneLikelihood(signals))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloneSignals(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+
+	signals := cloneSignals(&memoryTag{pages: pages}, false)
+	if got := cloneLikelihood(signals); got != "low" {
+		t.Errorf("genuine dump: likelihood %s, want low (%+v)", got, signals)
+	}
+
+	// A UID rewritten without fixing BCC1
+	bad := append([][]byte{}, pages...)
+	bad[2] = []byte{0x05, 0x48, 0x00, 0x00}
+	signals = cloneSignals(&memoryTag{pages: bad}, false)
+	if got := cloneLikelihood(signals); got != "high" {
+		t.Errorf("bad BCC1: likelihood %s, want high (%+v)", got, signals)
+	}
+
+	// Non-NXP manufacturer with a consistent BCC0
+	other := append([][]byte{}, pages...)
+	other[0] = []byte{0x05, 0xA1, 0xB2, 0x88 ^ 0x05 ^ 0xA1 ^ 0xB2}
+	if got := cloneLikelihood(cloneSignals(&memoryTag{pages: other}, false)); got != "medium" {
+		t.Errorf("non-NXP: likelihood %s, want medium", got)
+	}
+}
+
+// idleAfterNAKTag is a genuine NTAG216 behind a pass-through reader: it
+// answers GET_VERSION and READ_SIG, NAKs a write to page 0 and, like the real
+// tag, ignores every command after the NAK until it is re-selected
+type idleAfterNAKTag struct {
+	memoryTag
+	sig  []byte
+	idle bool
+}
+
+func (g *idleAfterNAKTag) Transmit(cmd []byte) ([]byte, error) {
+	switch {
+	case g.idle:
+		return []byte{0xD5, 0x43, 0x01, 0x90, 0x00}, nil // Timeout
+	case len(cmd) >= 4 && cmd[0] == 0xFF && cmd[1] == 0xD6 && cmd[3] < 0x03:
+		g.idle = true
+		return []byte{0x63, 0x00}, nil
+	case len(cmd) >= 8 && cmd[0] == 0xFF && cmd[5] == 0xD4 && cmd[6] == 0x42:
+		resp := []byte{0xD5, 0x43, 0x00}
+		switch cmd[7] {
+		case 0x60:
+			resp = append(resp, 0x00, 0x04, 0x04, 0x02, 0x01, 0x00, 0x13, 0x03)
+		case 0x3C:
+			resp = append(resp, g.sig...)
+		default:
+			return []byte{0xD5, 0x43, 0x01, 0x90, 0x00}, nil
+		}
+		return append(resp, 0x90, 0x00), nil
+	}
+	return g.memoryTag.Transmit(cmd)
+}
+
+func TestCloneSignalsPassThrough(t *testing.T) {
+	uid, _ := hex.DecodeString("04E10CDA993C80")
+	sig, _ := hex.DecodeString("8B76052EE42F5567BEB53238B3E3F9950707C0DCC956B5C5EFCFDB709B2D82B3")
+	pages := [][]byte{
+		{uid[0], uid[1], uid[2], 0x88 ^ uid[0] ^ uid[1] ^ uid[2]},
+		uid[3:7],
+		{uid[3] ^ uid[4] ^ uid[5] ^ uid[6], 0x48, 0x00, 0x00},
+		{0xE1, 0x10, 0x6D, 0x00},
+	}
+	tag := &idleAfterNAKTag{memoryTag: memoryTag{pages: pages}, sig: sig}
+
+	signals := cloneSignals(tag, true)
+	if got := cloneLikelihood(signals); got != "low" {
+		t.Errorf("genuine NTAG216: likelihood %s, want low (%+v)", got, signals)
+	}
+	if !tag.idle {
+		t.Error("page 0 write was not probed")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// tagSnapshot is one tag held in memory for -compare-two
+type tagSnapshot struct {
+	summary tagSummary
+	readErr error    // Why the NDEF message could not be read, nil if it was
+	pages   [][]byte // Raw pages from 0 up to the first unreadable page
+}
+
+// takeSnapshot reads the tag summary and, for Type 2 tags, the raw pages
+func takeSnapshot(card transceiver) (tagSnapshot, bool) {
+	summary, readErr, ok := quietSummary(card)
+	if !ok {
+		return tagSnapshot{readErr: readErr}, false
+	}
+	snap := tagSnapshot{summary: summary, readErr: readErr}
+	if summary.TagType == "Type 4" || summary.TagType == "unknown" {
+		return snap, true
+	}
+	maxPage := maxPageFor(summary.TagType)
+	reader := newBlockReader(card)
+	reader.enableFastRead(summary.TagType)
+	for page := 0; page <= int(maxPage); page++ {
+		data, err := reader.read(byte(page))
+		if err != nil {
+			break
+		}
+		snap.pages = append(snap.pages, data)
+	}
+	return snap, true
+}
+
+// compareRecords lists the differences between two NDEF messages, record by record
+func compareRecords(ref, cand []ndefRecord) []string {
+	var diffs []string
+	if len(ref) != len(cand) {
+		diffs = append(diffs, fmt.Sprintf("record count: reference %d, candidate %d", len(ref), len(cand)))
+	}
+	for i := 0; i < len(ref) || i < len(cand); i++ {
+		switch {
+		case i >= len(cand):
+			diffs = append(diffs, fmt.Sprintf("record %d: only on the reference (%s: %s)", i+1, ref[i].Kind(), recordSummary(ref[i])))
+		case i >= len(ref):
+			diffs = append(diffs, fmt.Sprintf("record %d: only on the candidate (%s: %s)", i+1, cand[i].Kind(), recordSummary(cand[i])))
+		case ref[i].TNF != cand[i].TNF || !bytes.Equal(ref[i].Type, cand[i].Type):
+			diffs = append(diffs, fmt.Sprintf("record %d: type %d/%q vs %d/%q", i+1, ref[i].TNF, ref[i].Type, cand[i].TNF, cand[i].Type))
+		case !bytes.Equal(ref[i].ID, cand[i].ID):
+			diffs = append(diffs, fmt.Sprintf("record %d: ID % X vs % X", i+1, ref[i].ID, cand[i].ID))
+		case !bytes.Equal(ref[i].Payload, cand[i].Payload):
+			diffs = append(diffs, fmt.Sprintf("record %d: %s %q vs %q", i+1, ref[i].Kind(), recordSummary(ref[i]), recordSummary(cand[i])))
+		}
+	}
+	return diffs
+}
+
+// comparePages lists the pages that differ between two dumps. Pages 0-1 and
+// the first two bytes of page 2 hold the UID and its check bytes, which
+// differ between any two tags, so only the lock bytes of page 2 are compared.
+func comparePages(ref, cand [][]byte) []string {
+	var diffs []string
+	if len(ref) != len(cand) {
+		diffs = append(diffs, fmt.Sprintf("readable pages: reference %d, candidate %d", len(ref), len(cand)))
+	}
+	for page := 2; page < len(ref) && page < len(cand); page++ {
+		a, b := ref[page], cand[page]
+		if page == 2 && len(a) >= 4 && len(b) >= 4 {
+			a, b = a[2:4], b[2:4]
+		}
+		if !bytes.Equal(a, b) {
+			diffs = append(diffs, fmt.Sprintf("page %02X: % X vs % X", page, ref[page], cand[page]))
+		}
+	}
+	return diffs
+}
+
+// compareSnapshots reports every difference between the reference and the
+// candidate, the UID excepted; withPages adds the raw page comparison
+func compareSnapshots(ref, cand tagSnapshot, withPages bool) []string {
+	var diffs []string
+	if ref.summary.TagType != cand.summary.TagType {
+		diffs = append(diffs, fmt.Sprintf("tag type: %s vs %s", ref.summary.TagType, cand.summary.TagType))
+	}
+	if ref.summary.Capacity != cand.summary.Capacity {
+		diffs = append(diffs, fmt.Sprintf("capacity: %d vs %d bytes", ref.summary.Capacity, cand.summary.Capacity))
+	}
+	if ref.summary.Verdict != cand.summary.Verdict {
+		diffs = append(diffs, fmt.Sprintf("NDEF: %s vs %s", verdictName(ref.summary.Verdict), verdictName(cand.summary.Verdict)))
+	}
+	if (ref.readErr == nil) != (cand.readErr == nil) {
+		diffs = append(diffs, fmt.Sprintf("NDEF read: %v vs %v", ref.readErr, cand.readErr))
+	}
+	diffs = append(diffs, compareRecords(ref.summary.Records, cand.summary.Records)...)
+	if withPages {
+		diffs = append(diffs, comparePages(ref.pages, cand.pages)...)
+	}
+	return diffs
+}
+
+// scanOne waits for a tag, snapshots it and waits for it to be removed
+func scanOne(ctx context.Context, pcsc *scard.Context, reader, role string) (tagSnapshot, error) {
+	logger.Infof("🔄 Place the %s tag on the reader", role)
+	if err := waitForCardPresent(ctx, pcsc, reader); err != nil {
+		return tagSnapshot{}, err
+	}
+	card, err := connectCard(pcsc, reader)
+	if err != nil {
+		return tagSnapshot{}, fmt.Errorf("connect: %w", describePCSCError(err))
+	}
+	passThroughOK, tagVersion = checkPassThrough(card, reader)
+	snap, ok := takeSnapshot(card)
+	card.Disconnect(scard.LeaveCard)
+	if !ok {
+		return tagSnapshot{}, fmt.Errorf("%s tag: %w", role, snap.readErr)
+	}
+	logger.Infof("📥 %s tag %s (%s) read, remove it", role, snap.summary.UID, snap.summary.TagType)
+	if err := waitForCardRemoval(ctx, pcsc, reader, 0); err != nil {
+		return tagSnapshot{}, err
+	}
+	return snap, nil
+}
+
+// runCompareTwo reads a reference tag and then a candidate, for -compare-two,
+// and prints their differences. It returns the exit code: 0 when the tags
+// match, 1 when they differ or could not be read.
+func runCompareTwo(ctx context.Context, pcsc *scard.Context, reader string, withPages bool) int {
+	ref, err := scanOne(ctx, pcsc, reader, "reference")
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.Errorf("❌ %v", err)
+		}
+		return 1
+	}
+	cand, err := scanOne(ctx, pcsc, reader, "candidate")
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.Errorf("❌ %v", err)
+		}
+		return 1
+	}
+	if ref.summary.UID == cand.summary.UID {
+		logger.Warnf("⚠️  Both tags have UID %s: the same tag twice, or a clone with a copied UID", ref.summary.UID)
+	}
+
+	fmt.Printf("\n=== TAG COMPARISON ===\n")
+	fmt.Printf("Reference: %s (%s)\n", ref.summary.UID, ref.summary.TagType)
+	fmt.Printf("Candidate: %s (%s)\n", cand.summary.UID, cand.summary.TagType)
+	diffs := compareSnapshots(ref, cand, withPages)
+	if len(diffs) == 0 {
+		if withPages {
+			fmt.Printf("✅ NDEF content and pages match (UID ignored)\n")
+		} else {
+			fmt.Printf("✅ NDEF content matches\n")
+		}
+		return 0
+	}
+	for _, d := range diffs {
+		fmt.Printf("  ❌ %s\n", d)
+	}
+	fmt.Printf("%d difference(s)\n", len(diffs))
+	return 1
+}
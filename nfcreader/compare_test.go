@@ -0,0 +1,53 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	passThroughOK = false
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	clone := func(edit func(pages [][]byte)) tagSnapshot {
+		t.Helper()
+		cp := make([][]byte, len(pages))
+		for i, p := range pages {
+			cp[i] = slices.Clone(p)
+		}
+		edit(cp)
+		snap, ok := takeSnapshot(&memoryTag{pages: cp})
+		if !ok {
+			t.Fatal("snapshot failed")
+		}
+		return snap
+	}
+	ref := clone(func([][]byte) {})
+
+	// Another UID (and BCC bytes) with the same content is a match
+	other := clone(func(p [][]byte) {
+		p[0] = []byte{0x04, 0x11, 0x22, 0xBB}
+		p[1] = []byte{0x33, 0x44, 0x55, 0x66}
+		p[2][0] = 0x44
+	})
+	if diffs := compareSnapshots(ref, other, true); len(diffs) != 0 {
+		t.Errorf("UID change reported: %q", diffs)
+	}
+
+	// Page 09 holds "r/04" of the URL
+	edited := clone(func(p [][]byte) { p[9][0] = 's' })
+	want := []string{`record 1: uri "https://dnd.qrand.me/r/04A1B2C3D4E5F6" vs "https://dnd.qrand.me/s/04A1B2C3D4E5F6"`}
+	if diffs := compareSnapshots(ref, edited, false); !slices.Equal(diffs, want) {
+		t.Errorf("NDEF only: got %q, want %q", diffs, want)
+	}
+	want = append(want, "page 09: 72 2F 30 34 vs 73 2F 30 34")
+	if diffs := compareSnapshots(ref, edited, true); !slices.Equal(diffs, want) {
+		t.Errorf("with pages: got %q, want %q", diffs, want)
+	}
+
+	// Static lock bytes on page 02 are compared
+	locked := clone(func(p [][]byte) { p[2][2] = 0xF0 })
+	if diffs := compareSnapshots(ref, locked, true); len(diffs) != 1 || diffs[0] != "page 02: 04 48 00 00 vs 04 48 F0 00" {
+		t.Errorf("lock bytes: %q", diffs)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+)
+
+// defaultRescanWindow is how soon after removal the same UID counts as the
+// same tag placed again rather than a second tag with that UID
+const defaultRescanWindow = 5 * time.Second
+
+// dupTracker counts the UIDs seen this session for -dup-check
+type dupTracker struct {
+	window  time.Duration
+	counts  map[string]int // Presentations per UID, re-scans excluded
+	lastUID string         // UID of the tag that was last on the reader
+	removed time.Time      // When that tag left the reader
+}
+
+func newDupTracker(window time.Duration) *dupTracker {
+	return &dupTracker{window: window, counts: make(map[string]int)}
+}
+
+// see records a tag presentation at now. A UID that comes back within the
+// window of its own removal is a re-scan and isn't counted again; any other
+// repeat is a duplicate. It returns how many times the UID has been seen.
+func (d *dupTracker) see(uidHex string, now time.Time) (count int, duplicate bool) {
+	rescan := uidHex == d.lastUID && now.Sub(d.removed) < d.window
+	d.lastUID = uidHex
+	d.removed = time.Time{}
+	if !rescan || d.counts[uidHex] == 0 {
+		d.counts[uidHex]++
+	}
+	count = d.counts[uidHex]
+	return count, count > 1 && !rescan
+}
+
+// markRemoved notes when the last tag left the reader
+func (d *dupTracker) markRemoved(now time.Time) {
+	d.removed = now
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDupTracker(t *testing.T) {
+	d := newDupTracker(5 * time.Second)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+
+	steps := []struct {
+		uid       string
+		placed    int // Seconds after start
+		removed   int
+		count     int
+		duplicate bool
+	}{
+		{"04A1B2C3D4E5F6", 0, 1, 1, false},
+		{"04A1B2C3D4E5F6", 3, 4, 1, false}, // Same tag back within the window: re-scan
+		{"04112233445566", 10, 11, 1, false},
+		{"04A1B2C3D4E5F6", 20, 21, 2, true}, // Another tag with the first UID
+		{"04A1B2C3D4E5F6", 30, 31, 3, true}, // Same UID after the window
+		{"04112233445566", 32, 33, 2, true},
+	}
+	for i, s := range steps {
+		count, duplicate := d.see(s.uid, at(s.placed))
+		if count != s.count || duplicate != s.duplicate {
+			t.Errorf("step %d (%s): count %d duplicate %v, want %d %v", i+1, s.uid, count, duplicate, s.count, s.duplicate)
+		}
+		d.markRemoved(at(s.removed))
+	}
+}
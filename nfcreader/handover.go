@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// carrierPowerStates names the Carrier Power State values of an Alternative
+// Carrier record (Connection Handover spec 6.3)
+var carrierPowerStates = [4]string{"inactive", "active", "activating", "unknown"}
+
+// isHandoverType reports whether a well-known type is a handover record
+// whose nested message lists alternative carriers
+func isHandoverType(recordType string) bool {
+	switch recordType {
+	case "Hs", "Hr", "Hm", "Hi":
+		return true
+	}
+	return false
+}
+
+// alternativeCarrier is the decoded payload of an "ac" record
+type alternativeCarrier struct {
+	PowerState byte     // Carrier Power State, index into carrierPowerStates
+	DataRef    []byte   // ID of the carrier configuration record
+	AuxRefs    [][]byte // IDs of auxiliary data records
+}
+
+// parseAlternativeCarrier decodes an "ac" payload: power state, carrier data
+// reference, then a count of auxiliary data references, each length-prefixed
+func parseAlternativeCarrier(payload []byte) (alternativeCarrier, error) {
+	var ac alternativeCarrier
+	ref := func(offset int) ([]byte, int, error) {
+		if offset >= len(payload) {
+			return nil, 0, errors.New("alternative carrier record truncated")
+		}
+		end := offset + 1 + int(payload[offset])
+		if end > len(payload) {
+			return nil, 0, errors.New("alternative carrier reference runs past the payload")
+		}
+		return payload[offset+1 : end], end, nil
+	}
+
+	if len(payload) < 1 {
+		return ac, errors.New("empty alternative carrier record")
+	}
+	ac.PowerState = payload[0] & 0x03
+	dataRef, offset, err := ref(1)
+	if err != nil {
+		return ac, err
+	}
+	ac.DataRef = dataRef
+	if offset >= len(payload) {
+		return ac, errors.New("alternative carrier record has no auxiliary reference count")
+	}
+	count := int(payload[offset])
+	offset++
+	for i := 0; i < count; i++ {
+		aux, next, err := ref(offset)
+		if err != nil {
+			return ac, err
+		}
+		ac.AuxRefs = append(ac.AuxRefs, aux)
+		offset = next
+	}
+	return ac, nil
+}
+
+// handoverCarrierLines lists the alternative carriers of a handover record
+// in its order, which is the order of preference, with their power states.
+// Carrier data references are resolved against message, the records around
+// the handover record.
+func handoverCarrierLines(rec ndefRecord, message []ndefRecord) []string {
+	off, ok := nestedMessageOffset(rec)
+	if !ok {
+		return nil
+	}
+	nested, _ := decodeNDEFRecords(rec.Payload[off:]) // Keep the carriers decoded before an error
+	var lines []string
+	for _, sub := range nested {
+		if sub.TNF != 0x01 || string(sub.Type) != "ac" {
+			continue
+		}
+		rank := len(lines) + 1
+		label := fmt.Sprintf("Carrier %d", rank)
+		if rank == 1 {
+			label += " (preferred)"
+		}
+		ac, err := parseAlternativeCarrier(sub.Payload)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: ❌ %v", label, err))
+			continue
+		}
+		line := fmt.Sprintf("%s: %s, power state %s (%d)", label, carrierName(ac.DataRef, message), carrierPowerStates[ac.PowerState], ac.PowerState)
+		if len(ac.AuxRefs) > 0 {
+			line += fmt.Sprintf(", %d auxiliary record(s)", len(ac.AuxRefs))
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return []string{"No alternative carriers"}
+	}
+	return lines
+}
+
+// carrierName describes the carrier configuration record a data reference
+// points to by its type, or the reference itself when no record has that ID
+func carrierName(ref []byte, message []ndefRecord) string {
+	for _, rec := range message {
+		if rec.IL && bytes.Equal(rec.ID, ref) {
+			return fmt.Sprintf("%s (record %d, ID %s)", rec.Type, rec.Index, formatRecordID(ref))
+		}
+	}
+	return fmt.Sprintf("⚠️  reference %s matches no record", formatRecordID(ref))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// memoryTag is an in-memory Type 2 tag answering the PC/SC pseudo-APDUs the
+// reader sends (FF CA UID, FF B0 READ BINARY), used to analyze hex dumps
+type memoryTag struct {
+	pages    [][]byte
+	rollover bool // Multi-page reads wrap to page 0 past the last page, as on NTAG21x
+}
+
+func (m *memoryTag) Transmit(cmd []byte) ([]byte, error) {
+	switch {
+	case len(cmd) >= 2 && cmd[0] == 0xFF && cmd[1] == 0xCA: // GET UID
+		if len(m.pages) < 2 || len(m.pages[0]) < 3 || len(m.pages[1]) < 4 {
+			return []byte{0x6A, 0x82}, nil // UID pages missing from the dump
+		}
+		uid := append(append([]byte{}, m.pages[0][:3]...), m.pages[1]...)
+		return append(uid, 0x90, 0x00), nil
+	case len(cmd) >= 4 && cmd[0] == 0xFF && cmd[1] == 0xB0: // READ BINARY
+		page := int(cmd[3])
+		if page >= len(m.pages) || m.pages[page] == nil { // nil: missing from a page dump
+			return []byte{0x63, 0x00}, nil
+		}
+		n := 4
+		if len(cmd) >= 5 && cmd[4] > 0 {
+			n = int(cmd[4])
+		}
+		var resp []byte
+		for p := page; len(resp) < n; p++ {
+			if m.rollover {
+				p %= len(m.pages)
+			}
+			if p >= len(m.pages) || m.pages[p] == nil {
+				break
+			}
+			resp = append(resp, m.pages[p]...)
+		}
+		return append(resp[:min(n, len(resp))], 0x90, 0x00), nil
+	}
+	return []byte{0x6D, 0x00}, nil
+}
+
+// hexDump is the content of a hex dump file
+type hexDump struct {
+	data      []byte   // All bytes, in page order for a paged dump
+	pages     [][]byte // Pages by page number when lines carry "PP:" page prefixes
+	firstPage int      // Lowest page number of a paged dump
+}
+
+// parseHexDump reads hex bytes, ignoring "#" comments and whitespace. Lines
+// may start with a hex page number and a colon ("04: 03 0C D1 01"); such a
+// dump is read with parsePageDump, so every line is placed at its own page
+// address, and must cover a contiguous range of pages.
+func parseHexDump(text string) (hexDump, error) {
+	var d hexDump
+	if hasPageAddresses(text) {
+		pages, err := parsePageDump(text)
+		if err != nil {
+			return d, err
+		}
+		for d.firstPage < len(pages) && pages[d.firstPage] == nil {
+			d.firstPage++
+		}
+		for p := d.firstPage; p < len(pages); p++ {
+			if pages[p] == nil {
+				return d, fmt.Errorf("page %02X missing from the dump (-parse-pages analyzes dumps with gaps)", p)
+			}
+			d.data = append(d.data, pages[p]...)
+		}
+		d.pages = pages
+		return d, nil
+	}
+
+	for n, line := range strings.Split(text, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.Join(strings.Fields(line), "")
+		if line == "" {
+			continue
+		}
+		b, err := hex.DecodeString(line)
+		if err != nil {
+			return d, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		d.data = append(d.data, b...)
+	}
+	return d, nil
+}
+
+// hasPageAddresses reports whether any line of a dump, comments aside,
+// starts with a "PP:" page address
+func hasPageAddresses(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		for _, marker := range []string{"#", "//"} {
+			if i := strings.Index(line, marker); i >= 0 {
+				line = line[:i]
+			}
+		}
+		if strings.Contains(line, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+// runParse analyzes a hex dump without a reader. A page dump starting at
+// page 0 is analyzed like a presented tag; a dump of the data area goes
+// through the TLV analysis; anything starting with a record header is
+// parsed as a raw NDEF message.
+func runParse(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	d, err := parseHexDump(string(raw))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(d.data) == 0 {
+		return fmt.Errorf("%s: no hex data", path)
+	}
+
+	switch {
+	case len(d.pages) >= 4 && d.firstPage == 0 && opts.Markdown:
+		return printMarkdownReport(&memoryTag{pages: d.pages})
+	case len(d.pages) >= 4 && d.firstPage == 0:
+		readFullTag(&memoryTag{pages: d.pages})
+	case d.data[0]&0x80 != 0 && d.data[0] != 0xFE:
+		// MB set: a record header, not a TLV type
+		parseNDEFMessage(d.data)
+	default:
+		start := 4
+		if len(d.pages) > 0 {
+			start = d.firstPage
+		}
+		analyzeNDEFStructure(d.data, start)
+	}
+	return nil
+}
+
+// parsePageDump reads a page-addressed dump: one "PP: B0 B1 B2 B3" line per
+// page with a hex page number, optionally written "Page PP:" as in the ideal
+// format. A line may hold several consecutive pages. Lines can come in any
+// order; pages not in the dump are left nil and read back as errors. "#" and
+// "//" start comments, lines without a page address are rejected.
+func parsePageDump(text string) ([][]byte, error) {
+	var pages [][]byte
+	for n, line := range strings.Split(text, "\n") {
+		for _, marker := range []string{"#", "//"} {
+			if i := strings.Index(line, marker); i >= 0 {
+				line = line[:i]
+			}
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		addr, data, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing \"PP:\" page address", n+1)
+		}
+		addr = strings.TrimSpace(addr)
+		if len(addr) > 5 && strings.EqualFold(addr[:5], "page ") {
+			addr = strings.TrimSpace(addr[5:])
+		}
+		page, err := strconv.ParseUint(addr, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad page number %q", n+1, addr)
+		}
+		b, err := hex.DecodeString(strings.Join(strings.Fields(data), ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		if len(b) == 0 || len(b)%4 != 0 {
+			return nil, fmt.Errorf("line %d: %d bytes, want whole 4-byte pages", n+1, len(b))
+		}
+
+		for i := 0; i < len(b); i += 4 {
+			p := int(page) + i/4
+			if p > 0xFF {
+				return nil, fmt.Errorf("line %d: data runs past page FF", n+1)
+			}
+			for len(pages) <= p {
+				pages = append(pages, nil)
+			}
+			if pages[p] != nil && !bytes.Equal(pages[p], b[i:i+4]) {
+				return nil, fmt.Errorf("line %d: page %02X given twice with different data", n+1, p)
+			}
+			pages[p] = b[i : i+4]
+		}
+	}
+	return pages, nil
+}
+
+// runParsePages analyzes a page-addressed dump like a tag presented to the
+// reader. Pages 00-03 (UID and CC) must be in the dump.
+func runParsePages(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pages, err := parsePageDump(string(raw))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for p := 0; p < 4; p++ {
+		if p >= len(pages) || pages[p] == nil {
+			return fmt.Errorf("%s: page %02X missing, pages 00-03 are required", path, p)
+		}
+	}
+	if opts.Markdown {
+		return printMarkdownReport(&memoryTag{pages: pages})
+	}
+	readFullTag(&memoryTag{pages: pages})
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonRecord is one NDEF record in a -jsonl line
+type jsonRecord struct {
+	TNF     byte   `json:"tnf"`
+	Kind    string `json:"kind"`
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`      // Hex
+	Payload string `json:"payload"`           // Hex
+	Summary string `json:"summary,omitempty"` // URI, text or app, as in the Markdown report
+}
+
+// jsonTag is one -jsonl line, the tag summary with its records
+type jsonTag struct {
+	Time     string       `json:"time"`
+	UID      string       `json:"uid"`
+	TagType  string       `json:"tag_type"`
+	Capacity int          `json:"capacity"`
+	Records  []jsonRecord `json:"records"`
+	Verdict  string       `json:"verdict"`
+	Error    string       `json:"error,omitempty"` // Why the NDEF message could not be read
+}
+
+// jsonLine converts a summary to its -jsonl form
+func jsonLine(s tagSummary, readErr error) jsonTag {
+	line := jsonTag{
+		Time:     s.Time.Format(time.RFC3339),
+		UID:      s.UID,
+		TagType:  s.TagType,
+		Capacity: s.Capacity,
+		Records:  []jsonRecord{},
+		Verdict:  verdictName(s.Verdict),
+	}
+	for _, rec := range s.Records {
+		r := jsonRecord{
+			TNF:     rec.TNF,
+			Kind:    rec.Kind(),
+			Type:    string(rec.Type),
+			Payload: strings.ToUpper(hex.EncodeToString(rec.Payload)),
+		}
+		if len(rec.ID) > 0 {
+			r.ID = strings.ToUpper(hex.EncodeToString(rec.ID))
+		}
+		if r.Kind == "uri" || r.Kind == "text" || r.Kind == "external" && string(rec.Type) == aarType {
+			r.Summary = recordSummary(rec)
+		}
+		line.Records = append(line.Records, r)
+	}
+	if readErr != nil {
+		line.Error = readErr.Error()
+	}
+	return line
+}
+
+// quietSummary reads the tag summary without printing the analysis: the UID,
+// the tag type, the capacity from the CC and the NDEF message. readErr says
+// why the message could not be read; ok is false when not even the UID could.
+func quietSummary(card transceiver) (summary tagSummary, readErr error, ok bool) {
+	uid, err := getUID(card)
+	if err != nil {
+		return summary, fmt.Errorf("read UID: %w", err), false
+	}
+	summary = tagSummary{Time: time.Now(), UID: strings.ToUpper(hex.EncodeToString(uid))}
+
+	summary.TagType = identifyTagType(card)
+	var msg []byte
+	if summary.TagType == "unknown" {
+		cc, err := readType4CC(card)
+		if err != nil {
+			return summary, errors.New("no Type 2 pages or Type 4 NDEF application"), true
+		}
+		summary.TagType, summary.Capacity = "Type 4", cc.MaxNDEFSize-2
+		msg, err = readType4NDEF(card, cc)
+		if err != nil {
+			return summary, err, true
+		}
+	} else {
+		summary.Capacity = int(standardCCSize(summary.TagType)) * 8
+		if cc, err := readPage(card, 0x03); err == nil && len(cc) >= 4 && cc[0] == 0xE1 {
+			summary.Capacity = int(cc[2]) * 8
+		}
+		msg, err = readNDEFMessage(card)
+		if errors.Is(err, errNoNDEF) {
+			return summary, nil, true
+		}
+		if err != nil {
+			return summary, err, true
+		}
+	}
+
+	summary.Verdict = ndefEmpty
+	if len(msg) > 0 {
+		summary.Verdict = ndefPresent
+		summary.addMessage(msg)
+	}
+	return summary, nil, true
+}
+
+// printJSONLine writes one compact JSON line for the tag to stdout, for
+// -jsonl. Stdout is unbuffered, so each line reaches the consumer as soon as
+// the tag is read.
+func printJSONLine(card transceiver) error {
+	summary, readErr, ok := quietSummary(card)
+	if !ok {
+		return readErr
+	}
+	if readErr != nil {
+		logger.Warnf("⚠️  %s: %v", summary.UID, readErr)
+	}
+	return json.NewEncoder(os.Stdout).Encode(jsonLine(summary, readErr))
+}
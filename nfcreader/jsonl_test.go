@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONLine(t *testing.T) {
+	tests := []struct {
+		vector string
+		want   string // The line without its time field
+	}{
+		{"ntag216_url", `{"uid":"04A1B2C3D4E5F6","tag_type":"NTAG216","capacity":872,"records":[{"tnf":1,"kind":"uri","type":"U","payload":"04646E642E7172616E642E6D652F722F3034413142324333443445354636","summary":"https://dnd.qrand.me/r/04A1B2C3D4E5F6"}],"verdict":"present"}`},
+		{"type2_empty", `{"uid":"05112244556677","tag_type":"Type2-compatible","capacity":48,"records":[],"verdict":"empty"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.vector, func(t *testing.T) {
+			_, pages := readHexFile(t, filepath.Join("testdata", "tags", tt.vector+".hex"))
+			got := captureStdout(t, func() {
+				if err := printJSONLine(&memoryTag{pages: pages}); err != nil {
+					t.Error(err)
+				}
+			})
+			if strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+				t.Fatalf("want exactly one line, got %q", got)
+			}
+			var line map[string]any
+			if err := json.Unmarshal([]byte(got), &line); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := line["time"]; !ok {
+				t.Error("no time field")
+			}
+			_, rest, _ := strings.Cut(got, `","`) // Drop the time field
+			if rest = `{"` + strings.TrimSpace(rest); rest != tt.want {
+				t.Errorf("line = %s\nwant   %s", rest, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lockLayout describes where a Type 2 tag's lock bits apply
+type lockLayout struct {
+	lastUserPage int // Last user data page
+	dynLockPage  int // Page holding the dynamic lock bytes, 0 if the tag has none
+	pagesPerBit  int // Pages covered by each dynamic lock bit
+}
+
+// lockLayouts maps tag types to their lock layout (NTAG213/215/216 datasheet 8.5)
+var lockLayouts = map[string]lockLayout{
+	"NTAG213":         {lastUserPage: 0x27, dynLockPage: 0x28, pagesPerBit: 2},
+	"NTAG215":         {lastUserPage: 0x81, dynLockPage: 0x82, pagesPerBit: 16},
+	"NTAG216":         {lastUserPage: 0xE1, dynLockPage: 0xE2, pagesPerBit: 16},
+	ultralightTagType: {lastUserPage: 0x0F},
+}
+
+// lockLayoutFor returns the layout for a tag type; unknown types only get
+// the static lock bytes, which cover pages 3-15
+func lockLayoutFor(tagType string) (lockLayout, bool) {
+	l, ok := lockLayouts[tagType]
+	if !ok {
+		return lockLayout{lastUserPage: 0x0F}, false
+	}
+	return l, true
+}
+
+// staticLockedPages decodes the static lock bytes (page 2 bytes 2-3): lock0
+// bit 3 locks the CC (page 3), bits 4-7 pages 4-7, lock1 bits 0-7 pages 8-15.
+// Lock0 bits 0-2 are block-locking bits that freeze the lock bits themselves.
+func staticLockedPages(lock0, lock1 byte) []int {
+	var pages []int
+	for bit := 3; bit < 8; bit++ {
+		if lock0&(1<<bit) != 0 {
+			pages = append(pages, bit)
+		}
+	}
+	for bit := 0; bit < 8; bit++ {
+		if lock1&(1<<bit) != 0 {
+			pages = append(pages, 8+bit)
+		}
+	}
+	return pages
+}
+
+// dynamicLockedPages decodes dynamic lock bytes 0-1, each bit locking
+// pagesPerBit pages from page 16 up to the last user page
+func dynamicLockedPages(l lockLayout, dyn []byte) []int {
+	var pages []int
+	if l.dynLockPage == 0 || len(dyn) < 2 {
+		return nil
+	}
+	for bit := 0; bit < 16; bit++ {
+		first := 16 + bit*l.pagesPerBit
+		if first > l.lastUserPage {
+			break
+		}
+		if dyn[bit/8]&(1<<(bit%8)) == 0 {
+			continue
+		}
+		for p := first; p < first+l.pagesPerBit && p <= l.lastUserPage; p++ {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// pageAccess is the effective write state of a page
+type pageAccess int
+
+const (
+	pageWritable    pageAccess = iota
+	pagePassword               // Writable after PWD_AUTH (AUTH0 and above)
+	pageCCReadOnly             // CC access byte marks the data area read-only
+	pageDynamicLock            // Permanently locked by a dynamic lock bit
+	pageStaticLock             // Permanently locked by a static lock bit
+)
+
+// String returns the state as shown in the page map
+func (a pageAccess) String() string {
+	switch a {
+	case pagePassword:
+		return "🔑 password required"
+	case pageCCReadOnly:
+		return "🚫 read-only (CC access)"
+	case pageDynamicLock:
+		return "🔒 locked (dynamic lock bits)"
+	case pageStaticLock:
+		return "🔒 locked (static lock bits)"
+	default:
+		return "✅ writable"
+	}
+}
+
+// pageWritability combines the static and dynamic lock bits, the CC access
+// byte and AUTH0 (-1 when there is none) into the state of pages 3 up to the
+// last user page. The strongest restriction wins.
+func pageWritability(l lockLayout, lock0, lock1 byte, dyn []byte, ccAccess byte, auth0 int) []pageAccess {
+	states := make([]pageAccess, l.lastUserPage+1)
+	raise := func(p int, a pageAccess) {
+		if p >= 3 && p < len(states) && a > states[p] {
+			states[p] = a
+		}
+	}
+	if auth0 >= 0 {
+		for p := auth0; p <= l.lastUserPage; p++ {
+			raise(p, pagePassword)
+		}
+	}
+	if ccAccess&0x0F != 0 {
+		for p := 4; p <= l.lastUserPage; p++ {
+			raise(p, pageCCReadOnly)
+		}
+	}
+	for _, p := range dynamicLockedPages(l, dyn) {
+		raise(p, pageDynamicLock)
+	}
+	for _, p := range staticLockedPages(lock0, lock1) {
+		raise(p, pageStaticLock)
+	}
+	return states[3:]
+}
+
+// printPageWritabilityMap prints pages 3 and up as ranges of equal state
+func printPageWritabilityMap(states []pageAccess) {
+	fmt.Printf("\n=== PAGE WRITABILITY MAP ===\n")
+	writable := 0
+	for start := 0; start < len(states); {
+		end := start
+		for end+1 < len(states) && states[end+1] == states[start] {
+			end++
+		}
+		span := fmt.Sprintf("0x%02X", start+3)
+		if end > start {
+			span += fmt.Sprintf("-0x%02X", end+3)
+		}
+		fmt.Printf("  %-11s %s\n", span, states[start])
+		if states[start] == pageWritable {
+			writable += end - start + 1
+		}
+		start = end + 1
+	}
+	fmt.Printf("Writable: %d of %d pages (CC + user data)\n", writable, len(states))
+}
+
+// formatPageList joins page numbers for display
+func formatPageList(pages []int) string {
+	s := make([]string, len(pages))
+	for i, p := range pages {
+		s[i] = fmt.Sprintf("%d", p)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPageWritability(t *testing.T) {
+	ntag213 := lockLayouts["NTAG213"]
+
+	// L-CC and L4 in lock0, L15 in lock1
+	if got, want := staticLockedPages(0x18, 0x80), []int{3, 4, 15}; !slices.Equal(got, want) {
+		t.Errorf("static: got %v, want %v", got, want)
+	}
+	// NTAG213: bit 0 locks 16-17, bit 11 (byte 1 bit 3) locks 38-39
+	if got, want := dynamicLockedPages(ntag213, []byte{0x01, 0x08, 0x00}), []int{16, 17, 38, 39}; !slices.Equal(got, want) {
+		t.Errorf("dynamic NTAG213: got %v, want %v", got, want)
+	}
+	// NTAG216: byte 1 bit 5 locks 224-225, capped at the last user page
+	if got, want := dynamicLockedPages(lockLayouts["NTAG216"], []byte{0x00, 0x20, 0x00}), []int{224, 225}; !slices.Equal(got, want) {
+		t.Errorf("dynamic NTAG216: got %v, want %v", got, want)
+	}
+
+	// Static lock on page 4 beats CC read-only; AUTH0 0x20 only shows where nothing stronger applies
+	states := pageWritability(ntag213, 0x10, 0x00, []byte{0x00, 0x08, 0x00}, 0x00, 0x20)
+	check := map[int]pageAccess{3: pageWritable, 4: pageStaticLock, 5: pageWritable, 0x1F: pageWritable,
+		0x20: pagePassword, 0x26: pageDynamicLock, 0x27: pageDynamicLock}
+	for page, want := range check {
+		if got := states[page-3]; got != want {
+			t.Errorf("page 0x%02X: got %v, want %v", page, got, want)
+		}
+	}
+	if len(states) != 0x27-3+1 {
+		t.Errorf("mapped %d pages, want %d", len(states), 0x27-3+1)
+	}
+
+	states = pageWritability(ntag213, 0x00, 0x00, nil, 0x0F, -1)
+	if states[0] != pageWritable || states[1] != pageCCReadOnly {
+		t.Errorf("CC read-only: got %v %v", states[0], states[1])
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// writePage writes one 4-byte page using FF D6 00 <page> 04 <data>
+func writePage(card transceiver, page byte, data []byte) error {
+	apdu := append([]byte{0xFF, 0xD6, 0x00, page, 0x04}, data...)
+	_, err := transmit(card, apdu)
+	return err
+}
+
+// probeMagicUID checks whether the UID pages accept writes by writing page 0
+// back with the bytes just read from it. Genuine tags reject the write; a tag
+// that accepts it is a likely "magic" (gen2 / direct-write) clone. The write
+// is a no-op on the data, but it is still a write, so this only runs with
+// -probe-magic. Gen1a tags, which need a backdoor command, are not detected.
+func probeMagicUID(card transceiver) {
+	fmt.Printf("\n=== MAGIC / WRITABLE-UID PROBE ===\n")
+
+	before, err := readPage(card, 0x00)
+	if err != nil {
+		fmt.Printf("❌ Could not read page 00: %v\n", err)
+		return
+	}
+	fmt.Printf("Page 00: % X (writing the same bytes back)\n", before)
+
+	if err := writePage(card, 0x00, before); err != nil {
+		fmt.Printf("✅ Page 00 rejected the write (%v)\n", err)
+		fmt.Printf("   UID pages are read-only, consistent with a genuine tag\n")
+		return
+	}
+
+	after, err := readPage(card, 0x00)
+	switch {
+	case err != nil:
+		fmt.Printf("⚠️  Write accepted but page 00 could not be read back: %v\n", err)
+	case !bytes.Equal(before, after):
+		fmt.Printf("⚠️  Write accepted and page 00 now reads % X (was % X)\n", after, before)
+	default:
+		fmt.Printf("Page 00 read back unchanged: % X\n", after)
+	}
+	fmt.Printf("🚩 UID page accepted a write: likely a \"magic\"/clone tag with a writable UID\n")
+}
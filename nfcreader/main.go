@@ -1,17 +1,64 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ebfe/scard"
 )
 
+// Options holds the reader settings parsed from the command line
+type Options struct {
+	ExtractMediaDir string          // Save image/* media record payloads into this directory
+	ExtractDir      string          // Save every record's raw payload into this directory
+	Benchmark       int             // Time this many full reads per tag instead of analyzing it
+	OnlyTypes       map[string]bool // Only print records of these kinds (nil = all)
+	DoubleRead      bool            // Read memory twice and flag pages that differ
+	Pick            bool            // List readers with their card status and ask which to use
+	ProbeMagic      bool            // Write page 0 back to itself to detect writable-UID clone tags
+	ProbeClone      bool            // Only run the clone checks and print a likelihood verdict
+	CounterTest     bool            // Check that a read advances the NTAG21x NFC counter
+	PagesPerRead    int             // Pages per FAST_READ with pass-through (0 = reader default, -1 = off)
+	ListReaders     bool            // Print the attached readers with their card status and exit
+	Timing          bool            // Print per-page read latency and the total analysis time
+	NoRemovalWait   bool            // Move on when a different UID appears instead of waiting for removal
+	Distinct        bool            // Skip UIDs already analyzed this session
+	Tree            bool            // Print NDEF messages as an indented tree instead of field by field
+	Map             bool            // Print an ASCII memory map of the tag's regions after the dump
+	WaitForReader   bool            // Poll until a reader is connected instead of failing at startup
+	ReaderTimeout   time.Duration   // With WaitForReader, give up after this long (0 = wait forever)
+	DistinctTarget  int             // With Distinct, exit after this many distinct UIDs (0 = keep going)
+	UIDAttrib       scard.Attrib    // SCardGetAttrib id to read the UID from when FF CA fails (0 = off)
+	VerifySig       bool            // Verify the originality signature over the UID
+	SigKey          string          // Hex public key to verify against instead of NXP's NTAG21x key
+	ParseFile       string          // Analyze this hex dump instead of reading tags
+	ParsePagesFile  string          // Analyze this page-addressed dump ("PP: B0 B1 B2 B3" lines) instead of reading tags
+	CSVOut          string          // Append one summary row per read tag to this CSV file
+	RemovalTimeout  time.Duration   // Stop waiting for a tag to be removed after this long (0 = wait forever)
+	NDEFHex         bool            // Print only the raw NDEF message as hex instead of analyzing the tag
+	Once            bool            // Exit after the first tag
+	TailFile        string          // Only log time, UID and tag type per tag presence to this file
+	Markdown        bool            // Print a Markdown report (summary, pages, records) instead of the analysis
+	JSONLines       bool            // Print one compact JSON line per tag (UID, type, records, verdict) instead of the analysis
+	CompareTwo      bool            // Read a reference tag, then a candidate, print their differences and exit
+	ComparePages    bool            // With CompareTwo, also compare the raw pages (UID pages excepted)
+	DupCheck        bool            // Warn when a UID seen earlier this session comes back on another tag
+	RescanWindow    time.Duration   // With DupCheck, a UID back within this long of its removal is a re-scan
+}
+
+// opts is the active configuration, set once in main before any tag is read
+var opts Options
+
 // min returns the smaller of two integers (for compatibility with older Go versions)
 func min(a, b int) int {
 	if a < b {
@@ -20,35 +67,114 @@ func min(a, b int) int {
 	return b
 }
 
+// transceiver is the part of *scard.Card used to talk to a tag. Taking the
+// interface lets the analysis run against an in-memory tag in tests.
+type transceiver interface {
+	Transmit(cmd []byte) ([]byte, error)
+}
+
+// transmitCount counts APDUs sent to the reader, used by the benchmark mode
+var transmitCount int
+
 // APDU helpers
-func transmit(card *scard.Card, apdu []byte) ([]byte, error) {
-	resp, err := card.Transmit(apdu)
-	if err != nil {
+func transmit(card transceiver, apdu []byte) ([]byte, error) {
+	data, _, err := transmitSW(card, apdu, 0x9000)
+	return data, err
+}
+
+// maxGetResponse bounds how many 61xx GET RESPONSE rounds one APDU may take
+const maxGetResponse = 32
+
+// transmitSW sends an APDU and returns the response data and status word.
+// 61xx (more data available) is followed with GET RESPONSE (00 C0 00 00 xx)
+// and the data concatenated; any SW in accept counts as success, which lets
+// callers handle e.g. DESFire 91AF ("additional frame") themselves.
+func transmitSW(card transceiver, apdu []byte, accept ...uint16) ([]byte, uint16, error) {
+	var data []byte
+	for round := 0; ; round++ {
+		transmitCount++
+		logger.Debugf("APDU > % X", apdu)
+		start := time.Now()
+		resp, err := card.Transmit(apdu)
+		traceAPDU(apduTrace, apdu, resp, err, time.Since(start))
+		if err != nil {
+			return nil, 0, describePCSCError(err)
+		}
+		logger.Debugf("APDU < % X", resp)
+		if len(resp) < 2 {
+			return nil, 0, errors.New("short APDU response")
+		}
+		sw1 := resp[len(resp)-2]
+		sw2 := resp[len(resp)-1]
+		sw := uint16(sw1)<<8 | uint16(sw2)
+		data = append(data, resp[:len(resp)-2]...)
+
+		if sw1 == 0x61 && !slices.Contains(accept, sw) {
+			if round == maxGetResponse {
+				return nil, sw, fmt.Errorf("APDU failed: still SW=%04X after %d GET RESPONSE rounds", sw, maxGetResponse)
+			}
+			apdu = []byte{0x00, 0xC0, 0x00, 0x00, sw2}
+			continue
+		}
+		if !slices.Contains(accept, sw) {
+			return nil, sw, fmt.Errorf("APDU failed: SW=%02X%02X", sw1, sw2)
+		}
+		return data, sw, nil
+	}
+}
+
+// attribGetter is implemented by cards that expose SCardGetAttrib (*scard.Card)
+type attribGetter interface {
+	GetAttrib(id scard.Attrib) ([]byte, error)
+}
+
+// getUID uses the ACR/PCSC pseudo-APDU FF CA 00 00 00 to fetch UID. Readers
+// without FF CA support can expose the UID as a (vendor) attribute instead,
+// which is read when -uid-attrib is set.
+func getUID(card transceiver) ([]byte, error) {
+	uid, err := transmit(card, []byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
+	if err == nil && len(uid) > 0 {
+		return uid, nil
+	}
+	if err == nil {
+		err = errors.New("reader returned an empty UID")
+	}
+	ag, ok := card.(attribGetter)
+	if opts.UIDAttrib == 0 || !ok {
 		return nil, err
 	}
-	if len(resp) < 2 {
-		return nil, errors.New("short APDU response")
+	uid, aerr := ag.GetAttrib(opts.UIDAttrib)
+	if aerr == nil && len(uid) == 0 {
+		aerr = errors.New("empty attribute")
 	}
-	sw1 := resp[len(resp)-2]
-	sw2 := resp[len(resp)-1]
-	if sw1 != 0x90 || sw2 != 0x00 {
-		return nil, fmt.Errorf("APDU failed: SW=%02X%02X", sw1, sw2)
+	if aerr != nil {
+		return nil, fmt.Errorf("FF CA: %w; GetAttrib 0x%X: %v", err, uint32(opts.UIDAttrib), describePCSCError(aerr))
 	}
-	return resp[:len(resp)-2], nil
+	return uid, nil
 }
 
-// getUID uses the ACR/PCSC pseudo-APDU FF CA 00 00 00 to fetch UID
-func getUID(card *scard.Card) ([]byte, error) {
-	return transmit(card, []byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
+// uidSizeLabel describes a UID by its length and ISO 14443-3 cascade level
+// (4 bytes = single size, 7 = double size, 10 = triple size)
+func uidSizeLabel(uid []byte) string {
+	switch len(uid) {
+	case 4:
+		return "4-byte, single size"
+	case 7:
+		return "7-byte, double size"
+	case 10:
+		return "10-byte, triple size"
+	default:
+		return fmt.Sprintf("%d-byte, non-standard size", len(uid))
+	}
 }
 
 // readPage reads one 4-byte page from a Type 2 tag using FF B0 00 <page> 04
-func readPage(card *scard.Card, page byte) ([]byte, error) {
+func readPage(card transceiver, page byte) ([]byte, error) {
 	return transmit(card, []byte{0xFF, 0xB0, 0x00, page, 0x04})
 }
 
 // readPageAlternative tries alternative methods to read a page if standard method fails
-func readPageAlternative(card *scard.Card, page byte) ([]byte, error) {
+func readPageAlternative(card transceiver, page byte) ([]byte, error) {
 	// Try standard method first
 	if data, err := readPage(card, page); err == nil {
 		return data, nil
@@ -72,7 +198,7 @@ func readPageAlternative(card *scard.Card, page byte) ([]byte, error) {
 }
 
 // identifyTagType attempts to identify the specific tag type
-func identifyTagType(card *scard.Card) string {
+func identifyTagType(card transceiver) string {
 	page0, err := readPage(card, 0x00)
 	if err != nil {
 		return "unknown"
@@ -83,14 +209,23 @@ func identifyTagType(card *scard.Card) string {
 		// Check for NTAG213/215/216 by first UID byte and memory size
 		switch uid0 {
 		case 0x04:
+			// NTAG I2C can only be told apart by its GET_VERSION product code
+			if tagVersion != nil {
+				if t := ntagI2CType(tagVersion); t != "" {
+					return t
+				}
+			}
 			// Test memory boundaries to determine exact type
-			if _, err := readPage(card, 0x2C); err != nil {
-				return "NTAG213" // 180 bytes total, can't read beyond page 44 (0x2C)
+			if _, err := readPage(card, 0x10); err != nil {
+				return ultralightTagType // 64 bytes total, pages 0x00-0x0F
+			}
+			if _, err := readPage(card, 0x2D); err != nil {
+				return "NTAG213" // 180 bytes total, pages 0x00-0x2C
 			}
-			if _, err := readPage(card, 0x86); err != nil {
-				return "NTAG215" // 540 bytes total, can't read beyond page 134 (0x86)
+			if _, err := readPage(card, 0x87); err != nil {
+				return "NTAG215" // 540 bytes total, pages 0x00-0x86
 			}
-			return "NTAG216" // 930 bytes total
+			return "NTAG216" // 924 bytes total, pages 0x00-0xE6
 		default:
 			return "Type2-compatible"
 		}
@@ -98,19 +233,62 @@ func identifyTagType(card *scard.Card) string {
 	return "unknown"
 }
 
+// maxPageFor returns the last readable page for a tag type. On NTAG21x it
+// is the PACK page, 4 pages after the dynamic lock bytes (datasheet 8.5).
+func maxPageFor(tagType string) byte {
+	switch tagType {
+	case "NTAG213":
+		return 0x2C
+	case "NTAG215":
+		return 0x86
+	case "NTAG216":
+		return 0xE6
+	case ultralightTagType:
+		return 0x0F
+	case ntagI2C1K, ntagI2CPlus1K, ntagI2C2K, ntagI2CPlus2K:
+		return ntagI2CMaxPage(tagType)
+	default:
+		return 0x10 // Default for basic Type 2
+	}
+}
+
+// ndefVerdict summarizes what analyzeNDEFStructure found in the data area
+type ndefVerdict int
+
+const (
+	ndefMissing ndefVerdict = iota // No NDEF Message TLV
+	ndefEmpty                      // Formatted, NDEF Message TLV with length 0
+	ndefPresent                    // NDEF Message TLV with content
+)
+
+// String returns the verdict as shown in the analysis summary
+func (v ndefVerdict) String() string {
+	switch v {
+	case ndefEmpty:
+		return "✅ Tag is formatted and empty"
+	case ndefPresent:
+		return "✅ NDEF message present"
+	default:
+		return "⚠️  No NDEF message"
+	}
+}
+
 // analyzeNDEFStructure parses and explains NDEF TLV structure
-func analyzeNDEFStructure(data []byte, startPage int) {
+func analyzeNDEFStructure(data []byte, startPage int) ndefVerdict {
 	fmt.Printf("\n=== NDEF TLV STRUCTURE ANALYSIS ===\n")
 
 	if len(data) == 0 {
 		fmt.Printf("No data to analyze\n")
-		return
+		return ndefMissing
 	}
 
 	offset := 0
 	pageOffset := startPage
-	foundNDEF := false
+	verdict := ndefMissing
+	ndefTLVs := 0
 
+	// Walk every TLV by its length: control and proprietary TLVs are skipped,
+	// and all NDEF TLVs are decoded, until the Terminator TLV
 	for offset < len(data) {
 		tlvType := data[offset]
 		currentPage := pageOffset + (offset / 4)
@@ -119,71 +297,89 @@ func analyzeNDEFStructure(data []byte, startPage int) {
 		fmt.Printf("Page %02d, Byte %d: TLV Type = 0x%02X ", currentPage, byteInPage, tlvType)
 
 		switch tlvType {
-		case 0x00:
+		case tlvNull:
 			fmt.Printf("(NULL/Padding)\n")
 			offset++
-		case 0x03:
-			fmt.Printf("(NDEF Message)\n")
-			foundNDEF = true
-			if offset+1 >= len(data) {
-				fmt.Printf("  ❌ Error: Missing length byte\n")
-				offset++
-				continue
+			continue
+		case tlvTerminator:
+			fmt.Printf("(Terminator)\n")
+			if ndefTLVs == 0 {
+				fmt.Printf("⚠️  No NDEF TLV found in data area\n")
+				return ndefMissing
+			}
+			if ndefTLVs > 1 {
+				fmt.Printf("ℹ️  %d NDEF Message TLVs found (readers normally use only the first)\n", ndefTLVs)
 			}
+			if verdict == ndefEmpty {
+				fmt.Printf("✅ Tag is formatted and empty\n")
+			} else {
+				fmt.Printf("✅ NDEF TLV structure complete\n")
+			}
+			return verdict
+		case 0xFF:
+			fmt.Printf("(Invalid TLV type)\n")
+			offset++
+			continue
+		}
 
-			length := data[offset+1]
+		fmt.Printf("(%s)\n", tlvName(tlvType))
+		length, header, ok := tlvLength(data, offset)
+		if !ok {
+			fmt.Printf("  ❌ Error: Missing length byte\n")
+			break
+		}
+		if header == 4 {
+			fmt.Printf("  Length: %d bytes (3-byte length field)\n", length)
+		} else {
 			fmt.Printf("  Length: %d bytes\n", length)
+		}
 
-			if length == 0 {
-				fmt.Printf("  (Empty NDEF message)\n")
-				offset += 2
-				continue
+		if offset+header+length > len(data) {
+			remaining := len(data) - offset - header
+			if tlvType != tlvNDEF {
+				fmt.Printf("  ❌ Error: TLV length (%d) exceeds available data (%d bytes remaining)\n", length, remaining)
+				break
 			}
-
-			if offset+2+int(length) > len(data) {
-				fmt.Printf("  ❌ Error: NDEF length (%d) exceeds available data (%d bytes remaining)\n",
-					length, len(data)-offset-2)
-				// Try to parse what we have
-				remaining := len(data) - offset - 2
-				if remaining > 0 {
-					ndefData := data[offset+2 : offset+2+remaining]
-					fmt.Printf("  Partial NDEF Data: % X\n", ndefData)
-					parseNDEFMessage(ndefData)
-				}
-				return
+			fmt.Printf("  ❌ Error: NDEF length (%d) exceeds available data (%d bytes remaining)\n", length, remaining)
+			// Try to parse what we have
+			if remaining > 0 {
+				ndefData := data[offset+header:]
+				fmt.Printf("  Partial NDEF Data: % X\n", ndefData)
+				parseNDEFMessage(ndefData)
 			}
+			return ndefPresent
+		}
 
-			ndefData := data[offset+2 : offset+2+int(length)]
-			fmt.Printf("  NDEF Data: % X\n", ndefData)
-			parseNDEFMessage(ndefData)
-			offset += 2 + int(length)
-		case 0xFE:
-			fmt.Printf("(Terminator)\n")
-			if foundNDEF {
-				fmt.Printf("✅ NDEF TLV structure complete\n")
-			}
-			return // Stop parsing at terminator
-		default:
-			if tlvType >= 0x01 && tlvType <= 0xFD {
-				fmt.Printf("(Proprietary TLV)\n")
-				// Try to read length if available
-				if offset+1 < len(data) {
-					length := data[offset+1]
-					fmt.Printf("  Length: %d bytes\n", length)
-					offset += 2 + int(length)
-				} else {
-					offset++
+		value := data[offset+header : offset+header+length]
+		switch tlvType {
+		case tlvNDEF:
+			ndefTLVs++
+			if length == 0 {
+				fmt.Printf("  (Empty NDEF message)\n")
+				if verdict == ndefMissing {
+					verdict = ndefEmpty
 				}
-			} else {
-				fmt.Printf("(Invalid TLV type)\n")
-				offset++
+				break
 			}
+			fmt.Printf("  NDEF Data: % X\n", value)
+			parseNDEFMessage(value)
+			verdict = ndefPresent
+		case tlvLockControl, tlvMemoryControl:
+			printControlTLV(tlvType, value)
 		}
+		offset += header + length
 	}
 
-	if !foundNDEF {
+	if ndefTLVs == 0 {
 		fmt.Printf("⚠️  No NDEF TLV found in data area\n")
+		return ndefMissing
+	}
+	if offset >= len(data) {
+		// Ran off the end of the data without an error: readers that stop at
+		// the Terminator TLV may read past the message or refuse the tag
+		fmt.Printf("⚠️  Missing Terminator TLV (FE) after the last TLV\n")
 	}
+	return verdict
 }
 
 // parseNDEFMessage parses NDEF message structure
@@ -192,12 +388,34 @@ func parseNDEFMessage(data []byte) {
 		fmt.Printf("  (Empty NDEF message)\n")
 		return
 	}
+	if opts.Tree {
+		printNDEFTree(data)
+		return
+	}
 
 	fmt.Printf("  === NDEF MESSAGE ANALYSIS ===\n")
 	offset := 0
 	recordNum := 1
 
+	// Pre-decode records so the -only-types filter can skip whole records
+	var decoded []ndefRecord
+	hidden := 0
+	if opts.OnlyTypes != nil {
+		decoded, _ = decodeNDEFRecords(data)
+	}
+
 	for offset < len(data) {
+		if recordNum <= len(decoded) && !opts.OnlyTypes[decoded[recordNum-1].Kind()] {
+			rec := decoded[recordNum-1]
+			offset += rec.Size
+			hidden++
+			recordNum++
+			if rec.ME {
+				break
+			}
+			continue
+		}
+
 		fmt.Printf("    --- Record %d ---\n", recordNum)
 
 		if offset >= len(data) {
@@ -266,6 +484,18 @@ func parseNDEFMessage(data []byte) {
 			fmt.Printf("      ID Length: %d\n", idLength)
 			offset++
 		}
+		switch tnf {
+		case 0x00:
+			fmt.Printf("      📭 Empty record\n")
+		case 0x05:
+			fmt.Printf("      ❓ Unknown type: payload is opaque data\n")
+		case 0x07:
+			fmt.Printf("      ❓ Reserved TNF: not defined by the NDEF spec, payload handled as Unknown\n")
+		}
+		if problem := recordTNFProblem(tnf, int(typeLength), int(idLength), int(payloadLength)); problem != "" {
+			// Fields are still skipped by their lengths to stay in sync
+			fmt.Printf("    ⚠️  Malformed: %s\n", problem)
+		}
 
 		// Type
 		var recordType []byte
@@ -275,7 +505,11 @@ func parseNDEFMessage(data []byte) {
 				break
 			}
 			recordType = data[offset : offset+int(typeLength)]
-			fmt.Printf("      Type: %s (% X)\n", string(recordType), recordType)
+			if tnfHasType(tnf) {
+				fmt.Printf("      Type: %s (% X)\n", string(recordType), recordType)
+			} else {
+				fmt.Printf("      Type field: % X (ignored, TNF %d has no type)\n", recordType, tnf)
+			}
 			offset += int(typeLength)
 		} else {
 			fmt.Printf("      Type: (none)\n")
@@ -288,7 +522,7 @@ func parseNDEFMessage(data []byte) {
 				break
 			}
 			id := data[offset : offset+int(idLength)]
-			fmt.Printf("      ID: %s\n", string(id))
+			fmt.Printf("      ID: %s\n", formatRecordID(id))
 			offset += int(idLength)
 		}
 
@@ -311,10 +545,29 @@ func parseNDEFMessage(data []byte) {
 			}
 
 			payload := data[offset : offset+int(payloadLength)]
-			fmt.Printf("      Payload: % X\n", payload)
+			if tnf == 0x02 && isImageType(string(recordType)) {
+				// Summarize embedded images instead of flooding the report with hex
+				summarizeImagePayload(string(recordType), payload, recordNum)
+			} else {
+				fmt.Printf("      Payload: % X\n", payload)
+			}
 
 			// Parse payload based on record type
-			if typeLength == 1 && len(recordType) > 0 {
+			if tnf == 0x01 && string(recordType) == "Sp" {
+				parseSmartPosterPayload(payload)
+			} else if tnf == 0x04 && string(recordType) == aarType {
+				fmt.Printf("        📱 Android app: %s\n", payload)
+				fmt.Printf("        Play Store: %s\n", playStoreURL(string(payload)))
+			} else if tnf == 0x01 && isHandoverType(string(recordType)) {
+				// Carriers reference records around this one, so decode the whole message
+				message, _ := decodeNDEFRecords(data)
+				if recordNum <= len(message) {
+					fmt.Printf("        🔀 Handover %s, alternative carriers in order of preference:\n", recordType)
+					for _, line := range handoverCarrierLines(message[recordNum-1], message) {
+						fmt.Printf("          %s\n", line)
+					}
+				}
+			} else if tnf == 0x01 && typeLength == 1 && len(recordType) > 0 {
 				switch recordType[0] {
 				case 'U':
 					parseURIPayload(payload)
@@ -337,6 +590,14 @@ func parseNDEFMessage(data []byte) {
 			break
 		}
 	}
+
+	if hidden > 0 {
+		fmt.Printf("    (%d of %d record(s) hidden by -only-types)\n", hidden, len(decoded))
+	}
+
+	if opts.ExtractDir != "" {
+		extractRecords(data, opts.ExtractDir)
+	}
 }
 
 // parseURIPayload parses URI record payload
@@ -355,6 +616,9 @@ func parseURIPayload(payload []byte) {
 		fmt.Printf("        🌐 URI: %s\n", fullURI)
 		fmt.Printf("        Prefix Code: 0x%02X (%s)\n", identifierCode, prefix)
 		fmt.Printf("        Suffix: %s\n", suffix)
+		if phone, ok := parsePhoneURI(fullURI); ok {
+			printPhoneURI(phone)
+		}
 	} else {
 		if prefix != "" {
 			fmt.Printf("        🌐 URI: %s\n", prefix)
@@ -467,117 +731,161 @@ func getURIPrefix(code byte) string {
 }
 
 // analyzeLockBytes analyzes static and dynamic lock bytes
-func analyzeLockBytes(card *scard.Card, tagType string) {
+func analyzeLockBytes(card transceiver, tagType string) {
 	fmt.Printf("\n=== LOCK BYTES ANALYSIS ===\n")
+	layout, mapped := lockLayoutFor(tagType)
 
 	// Static lock bytes (page 2, bytes 2-3)
+	var lock0, lock1 byte
 	pg2, err := readPage(card, 0x02)
 	if err == nil && len(pg2) == 4 {
-		lock0 := pg2[2]
-		lock1 := pg2[3]
+		lock0 = pg2[2]
+		lock1 = pg2[3]
 		fmt.Printf("Static Lock Bytes (Page 2, bytes 2-3): %02X %02X\n", lock0, lock1)
 
 		// Decode locked pages for Type 2 tags
-		var lockedPages []string
-		for i := 0; i < 16; i++ {
-			var bitSet bool
-			if i < 8 {
-				bitSet = ((lock0 >> uint(i)) & 1) == 1
-			} else {
-				bitSet = ((lock1 >> uint(i-8)) & 1) == 1
-			}
-			if bitSet {
-				lockedPages = append(lockedPages, fmt.Sprintf("%d", 3+i))
-			}
-		}
-
-		if len(lockedPages) > 0 {
-			fmt.Printf("  Locked pages: %s\n", strings.Join(lockedPages, ", "))
+		if lockedPages := staticLockedPages(lock0, lock1); len(lockedPages) > 0 {
+			fmt.Printf("  Locked pages: %s\n", formatPageList(lockedPages))
 		} else {
 			fmt.Printf("  No pages locked by static lock bytes\n")
 		}
 	}
 
+	var ccAccess byte
+	if cc, err := readPage(card, 0x03); err == nil && len(cc) == 4 && cc[0] == 0xE1 {
+		ccAccess = cc[3]
+	}
+	var dynLock []byte
+	auth0 := -1
+
 	// Dynamic lock bytes for NTAG
-	if strings.Contains(tagType, "NTAG") {
-		dynamicLockPage := byte(0x2A) // NTAG213 dynamic lock page
-		if tagType == "NTAG215" {
-			dynamicLockPage = 0x82
-		} else if tagType == "NTAG216" {
-			dynamicLockPage = 0xE2
-		}
+	if isNTAG21x(tagType) {
+		dynamicLockPage := byte(layout.dynLockPage)
 
-		if dynLock, err := readPage(card, dynamicLockPage); err == nil {
+		if dl, err := readPage(card, dynamicLockPage); err == nil {
+			dynLock = dl
 			fmt.Printf("Dynamic Lock Bytes (Page %02X): % X\n", dynamicLockPage, dynLock)
+			if lockedPages := dynamicLockedPages(layout, dynLock); len(lockedPages) > 0 {
+				fmt.Printf("  Locked pages: %s\n", formatPageList(lockedPages))
+			}
 		}
 
 		// Configuration pages
+		var cfg0 []byte
 		configPage := dynamicLockPage + 1
 		if cfg, err := readPage(card, configPage); err == nil {
 			fmt.Printf("Configuration (Page %02X): % X\n", configPage, cfg)
 			if len(cfg) >= 4 {
-				fmt.Printf("  MIRROR: %02X\n", cfg[0])
-				fmt.Printf("  RFUI: %02X\n", cfg[1])
-				fmt.Printf("  MIRROR_PAGE: %02X\n", cfg[2])
+				for _, line := range decodeCFG0(cfg) {
+					fmt.Printf("  %s\n", line)
+				}
+				cfg0 = cfg
 				fmt.Printf("  AUTH0: %02X", cfg[3])
 				if cfg[3] == 0xFF {
 					fmt.Printf(" (password protection disabled)")
 				} else {
 					fmt.Printf(" (password protection starts at page %d)", cfg[3])
+					auth0 = int(cfg[3])
 				}
 				fmt.Printf("\n")
 			}
 		}
+
+		// ACCESS byte follows in the next configuration page
+		accessPage := configPage + 1
+		if cfg, err := readPage(card, accessPage); err == nil && len(cfg) >= 1 {
+			fmt.Printf("Configuration (Page %02X): % X\n", accessPage, cfg)
+			for _, line := range decodeCFG1(cfg) {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+		if cfg0 != nil {
+			verifyMirror(card, layout, cfg0)
+		}
+	}
+
+	printPageWritabilityMap(pageWritability(layout, lock0, lock1, dynLock, ccAccess, auth0))
+	if !mapped {
+		fmt.Printf("  (Lock layout unknown for %s: only pages 3-15, covered by the static lock bytes, are mapped)\n", tagType)
+	}
+}
+
+// printNDEFHex prints the tag's NDEF message as one line of continuous hex
+// and nothing else, for -ndef-hex
+func printNDEFHex(card transceiver) error {
+	msg, err := readNDEFMessage(card)
+	if err != nil {
+		return err
 	}
+	fmt.Println(strings.ToUpper(hex.EncodeToString(msg)))
+	return nil
 }
 
-// readFullTag reads and analyzes the complete NFC tag structure
-func readFullTag(card *scard.Card) {
-	fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
+// readFullTag reads and analyzes the complete NFC tag structure and returns
+// its summary; ok is false when not even the UID could be read
+func readFullTag(card transceiver) (summary tagSummary, ok bool) {
+	fmt.Print("\n" + strings.Repeat("=", 60) + "\n")
 	fmt.Printf("COMPREHENSIVE NFC TAG ANALYSIS\n")
-	fmt.Printf(strings.Repeat("=", 60) + "\n")
+	fmt.Print(strings.Repeat("=", 60) + "\n")
+	timer := newReadTimer()
 
 	// Get UID
 	uid, err := getUID(card)
 	if err != nil {
 		fmt.Printf("❌ Failed to get UID: %v\n", err)
-		return
+		return summary, false
 	}
 	uidHex := strings.ToUpper(hex.EncodeToString(uid))
-	fmt.Printf("🏷️  Tag UID: %s\n", uidHex)
+	fmt.Printf("🏷️  Tag UID: %s (%s)\n", uidHex, uidSizeLabel(uid))
+	summary = tagSummary{Time: time.Now(), UID: uidHex}
 
 	// Identify tag type
 	tagType := identifyTagType(card)
+	if tagType == "unknown" {
+		// No Type 2 pages: try the Type 4 NDEF application instead
+		if verdict, ok := analyzeType4Tag(card); ok {
+			fmt.Print("\n" + strings.Repeat("=", 60) + "\n")
+			fmt.Printf("NDEF: %s\n", verdict)
+			fmt.Printf("✅ ANALYSIS COMPLETE\n")
+			fmt.Print(strings.Repeat("=", 60) + "\n")
+			summary.TagType, summary.Verdict = "Type 4", verdict
+			return summary, true
+		}
+	}
 	fmt.Printf("📋 Tag Type: %s\n", tagType)
+	summary.TagType = tagType
 
 	// Determine memory layout
-	var maxPage byte = 0x10 // Default for basic Type 2
-	switch tagType {
-	case "NTAG213":
-		maxPage = 0x2C
-	case "NTAG215":
-		maxPage = 0x86
-	case "NTAG216":
-		maxPage = 0xE7
-	}
+	maxPage := maxPageFor(tagType)
 
-	fmt.Printf("💾 Memory Layout: %d pages (0x00 to 0x%02X)\n", maxPage+1, maxPage)
+	fmt.Printf("💾 Memory Layout: %d pages (0x00 to 0x%02X)\n", int(maxPage)+1, maxPage)
 
 	// Read header pages (0-3)
 	fmt.Printf("\n=== HEADER PAGES (0-3) ===\n")
+	var ccSize byte
+	hasCC := false
+	pages := newBlockReader(card)
+	pages.enableFastRead(tagType)
 	for page := byte(0x00); page <= 0x03; page++ {
-		data, err := readPage(card, page)
+		pageStart, cached := time.Now(), pages.cached(page)
+		data, err := pages.read(page)
 		if err != nil {
 			fmt.Printf("Page %02d: ❌ Error: %v", page, err)
 			// Try alternative method for header pages
 			if altData, altErr := readPageAlternative(card, page); altErr == nil {
 				fmt.Printf("Page %02d: ✅ Alternative read: % X", page, altData)
+				timer.record(int(page), pageStart, true, true)
 				data = altData
 				err = nil
 			} else {
 				fmt.Printf("Page %02d: ❌ All read methods failed\n", page)
+				timer.record(int(page), pageStart, true, false)
 				continue
 			}
+		} else if cached {
+			timer.recordCached(int(page))
+		} else {
+			timer.record(int(page), pageStart, false, true)
 		}
 
 		if err == nil {
@@ -608,6 +916,7 @@ func readFullTag(card *scard.Card) {
 					fmt.Printf("\n    Magic: %02X %02X", magic1, magic2)
 					fmt.Printf("\n    Size: %02X (data area = %d bytes)", size, int(size)*8)
 					fmt.Printf("\n    Access: %02X", access)
+					ccSize, hasCC = size, magic1 == 0xE1
 
 					if magic1 == 0xE1 && magic2 == 0x10 {
 						fmt.Printf("\n    ✅ Valid NDEF CC (Type 2 Tag)")
@@ -622,6 +931,10 @@ func readFullTag(card *scard.Card) {
 						}
 					}
 				}
+				if tagType == ultralightTagType {
+					// On plain Ultralight page 3 is the OTP area (NDEF reuses it as the CC)
+					printOTPPage(data)
+				}
 			}
 			fmt.Printf("\n")
 		}
@@ -634,17 +947,21 @@ func readFullTag(card *scard.Card) {
 
 	// Read pages until we hit terminator or max pages
 	consecutiveErrors := 0
-	for page := byte(startDataPage); page <= maxPage; page++ {
-		data, err := readPage(card, page)
+	for p := startDataPage; p <= int(maxPage); p++ { // int so a 0xFF last page can't wrap
+		page := byte(p)
+		pageStart, cached := time.Now(), pages.cached(page)
+		data, err := pages.read(page)
 		if err != nil {
 			fmt.Printf("Page %02d: ❌ Error: %v", page, err)
 			// Try alternative reading method
 			if altData, altErr := readPageAlternative(card, page); altErr == nil {
 				fmt.Printf("Page %02d: ✅ Alternative read: % X\n", page, altData)
+				timer.record(p, pageStart, true, true)
 				data = altData
 				err = nil
 			} else {
 				fmt.Printf("Page %02d: ❌ Alternative read also failed: %v\n", page, altErr)
+				timer.record(p, pageStart, true, false)
 				consecutiveErrors++
 				// If we can't read beyond a certain point, we might have hit memory boundary
 				// But continue trying a few more pages in case it's a temporary issue
@@ -654,6 +971,10 @@ func readFullTag(card *scard.Card) {
 				}
 				continue
 			}
+		} else if cached {
+			timer.recordCached(p)
+		} else {
+			timer.record(p, pageStart, false, true)
 		}
 
 		if err == nil {
@@ -661,26 +982,26 @@ func readFullTag(card *scard.Card) {
 			fmt.Printf("Page %02d: % X\n", page, data)
 			allNDEFData = append(allNDEFData, data...)
 
-			// Stop if we hit terminator TLV
-			for _, b := range data {
-				if b == 0xFE {
-					goto analyzeNDEF
-				}
+			// Stop once the TLVs read so far end in a Terminator TLV
+			if tlvAreaComplete(allNDEFData) {
+				goto analyzeNDEF
 			}
 		}
 	}
 
 analyzeNDEF:
 	// Analyze NDEF structure
+	verdict := ndefMissing
 	if len(allNDEFData) > 0 {
-		analyzeNDEFStructure(allNDEFData, startDataPage)
+		verdict = analyzeNDEFStructure(allNDEFData, startDataPage)
 	} else {
 		fmt.Printf("⚠️  No NDEF data found in standard location (pages 4+)\n")
 		fmt.Printf("🔍 Attempting to scan entire memory for NDEF patterns...\n")
 
 		// Try to find NDEF data in other locations
 		foundAlternativeData := false
-		for page := byte(0x00); page <= maxPage; page++ {
+		for p := 0; p <= int(maxPage); p++ {
+			page := byte(p)
 			if data, err := readPageAlternative(card, page); err == nil {
 				// Look for NDEF TLV pattern (0x03)
 				for i, b := range data {
@@ -699,8 +1020,8 @@ analyzeNDEF:
 							ndefData = append(ndefData, data[i+2:]...)
 							bytesNeeded := int(length) - remainingInPage
 
-							for nextPage := page + 1; bytesNeeded > 0 && nextPage <= maxPage; nextPage++ {
-								if nextData, err := readPageAlternative(card, nextPage); err == nil {
+							for nextPage := p + 1; bytesNeeded > 0 && nextPage <= int(maxPage); nextPage++ {
+								if nextData, err := readPageAlternative(card, byte(nextPage)); err == nil {
 									take := min(bytesNeeded, len(nextData))
 									ndefData = append(ndefData, nextData[:take]...)
 									bytesNeeded -= take
@@ -713,6 +1034,7 @@ analyzeNDEF:
 						if len(ndefData) > 0 {
 							fmt.Printf("🔍 Alternative NDEF Data: % X\n", ndefData)
 							parseNDEFMessage(ndefData)
+							verdict = ndefPresent
 						}
 						break
 					}
@@ -725,51 +1047,69 @@ analyzeNDEF:
 		}
 	}
 
+	if hasCC {
+		checkNDEFCapacity(allNDEFData, ccSize, tagType)
+		summary.Capacity = int(ccSize) * 8
+	} else {
+		summary.Capacity = int(standardCCSize(tagType)) * 8
+	}
+	if end, length, found := ndefTLVExtent(allNDEFData); found && end <= len(allNDEFData) {
+		summary.addMessage(allNDEFData[end-length : end])
+	}
+	summary.Verdict = verdict
+
+	if isNTAGI2C(tagType) {
+		printNTAGI2CMemoryMap(card, tagType)
+	}
+
 	// Analyze lock bytes
 	analyzeLockBytes(card, tagType)
 
 	// Show configuration pages for NTAG
-	if strings.Contains(tagType, "NTAG") {
-		fmt.Printf("\n=== NTAG CONFIGURATION PAGES ===\n")
-		configStart := byte(0x29) // NTAG213
-		if tagType == "NTAG215" {
-			configStart = 0x83
-		} else if tagType == "NTAG216" {
-			configStart = 0xE3
-		}
-
-		for i := byte(0); i < 4; i++ {
-			page := configStart + i
-			if page <= maxPage {
-				data, err := readPage(card, page)
-				if err != nil {
-					fmt.Printf("Page %02X: ❌ Error: %v\n", page, err)
-				} else {
-					fmt.Printf("Page %02X: % X", page, data)
-					switch i {
-					case 1:
-						fmt.Printf(" (Dynamic Lock)")
-					case 2:
-						fmt.Printf(" (Configuration)")
-					case 3:
-						fmt.Printf(" (Password)")
-					}
-					fmt.Printf("\n")
-				}
-			}
-		}
+	if isNTAG21x(tagType) {
+		printNTAGConfigPages(card, tagType, maxPage)
+	}
+
+	if passThroughOK {
+		printVersionInfo()
+	}
+	// READ_SIG only on request: tags without it NAK and drop to IDLE
+	if opts.VerifySig && passThroughOK {
+		printOriginality(card, uid)
+	} else if opts.VerifySig {
+		fmt.Printf("\n⚠️  Originality check skipped: READ_SIG needs reader pass-through\n")
+	}
+
+	if opts.DoubleRead {
+		checkReadConsistency(card, maxPage)
+	}
+
+	if opts.ProbeMagic {
+		probeMagicUID(card)
 	}
 
-	fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
+	if opts.CounterTest && isNTAG21x(tagType) {
+		testNFCCounter(card, tagType)
+	}
+
+	if opts.Map {
+		printMemoryMap(pages, tagType, maxPage, allNDEFData)
+	}
+
+	timer.print()
+
+	fmt.Print("\n" + strings.Repeat("=", 60) + "\n")
+	fmt.Printf("NDEF: %s\n", verdict)
 	fmt.Printf("✅ ANALYSIS COMPLETE\n")
-	fmt.Printf(strings.Repeat("=", 60) + "\n")
+	fmt.Print(strings.Repeat("=", 60) + "\n")
+	return summary, true
 }
 
 // showIdealNFCFormat demonstrates what a properly formatted NFC tag should look like
 func showIdealNFCFormat() {
-	fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
+	fmt.Print("\n" + strings.Repeat("=", 60) + "\n")
 	fmt.Printf("IDEAL NFC TAG FORMAT STRUCTURE\n")
-	fmt.Printf(strings.Repeat("=", 60) + "\n")
+	fmt.Print(strings.Repeat("=", 60) + "\n")
 
 	fmt.Printf(`
 📋 NTAG213 MEMORY LAYOUT (180 bytes total, 45 pages of 4 bytes each):
@@ -846,93 +1186,554 @@ Page 04: 03 0F D1 01 0B 55 04 65 78 61 6D 70 6C 65 2E 63  // TLV + NDEF
 Page 05: 6F 6D FE 00                 // "om" + Terminator + padding
 `)
 
-	fmt.Printf(strings.Repeat("=", 60) + "\n")
+	fmt.Print(strings.Repeat("=", 60) + "\n")
 }
 
 func main() {
-	log.SetFlags(0)
-
 	// Check for demo mode
 	if len(os.Args) > 1 && os.Args[1] == "demo" {
 		showIdealNFCFormat()
 		return
 	}
 
+	// Parse command line arguments
+	for i := 1; i < len(os.Args); i++ {
+		switch os.Args[i] {
+		case "-extract-media":
+			if i+1 < len(os.Args) {
+				opts.ExtractMediaDir = os.Args[i+1]
+				i++ // Skip next argument as it's the directory
+			}
+		case "-extract":
+			if i+1 < len(os.Args) {
+				opts.ExtractDir = os.Args[i+1]
+				i++
+			}
+		case "-benchmark":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n < 1 {
+					logger.Fatalf("invalid -benchmark run count: %s", os.Args[i+1])
+				}
+				opts.Benchmark = n
+				i++
+			}
+		case "-only-types":
+			if i+1 < len(os.Args) {
+				types, err := parseRecordKinds(os.Args[i+1])
+				if err != nil {
+					logger.Fatalf("invalid -only-types: %v", err)
+				}
+				opts.OnlyTypes = types
+				i++
+			}
+		case "-double-read":
+			opts.DoubleRead = true
+		case "-list-readers":
+			opts.ListReaders = true
+		case "-pick":
+			opts.Pick = true
+		case "-probe-magic":
+			opts.ProbeMagic = true
+		case "-probe-clone":
+			opts.ProbeClone = true
+		case "-counter-test":
+			opts.CounterTest = true
+		case "-pages-per-read":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n < -1 || n == 0 || n > 63 {
+					logger.Fatalf("invalid -pages-per-read: %s (1-63, or -1 to turn FAST_READ off)", os.Args[i+1])
+				}
+				opts.PagesPerRead = n
+				i++
+			}
+		case "-markdown":
+			opts.Markdown = true
+		case "-jsonl":
+			opts.JSONLines = true
+		case "-compare-two":
+			opts.CompareTwo = true
+			// Optional "pages" to compare raw pages too
+			if i+1 < len(os.Args) && os.Args[i+1] == "pages" {
+				opts.ComparePages = true
+				i++
+			}
+		case "-timing":
+			opts.Timing = true
+		case "-no-removal-wait":
+			opts.NoRemovalWait = true
+		case "-wait-for-reader":
+			opts.WaitForReader = true
+			// Optional timeout, e.g. 30s
+			if i+1 < len(os.Args) {
+				if d, err := time.ParseDuration(os.Args[i+1]); err == nil {
+					opts.ReaderTimeout = d
+					i++
+				}
+			}
+		case "-tree":
+			opts.Tree = true
+		case "-map":
+			opts.Map = true
+		case "-distinct":
+			opts.Distinct = true
+			// Optional target count of distinct tags
+			if i+1 < len(os.Args) {
+				if n, err := strconv.Atoi(os.Args[i+1]); err == nil {
+					if n < 1 {
+						logger.Fatalf("invalid -distinct target: %s", os.Args[i+1])
+					}
+					opts.DistinctTarget = n
+					i++
+				}
+			}
+		case "-dup-check":
+			opts.DupCheck = true
+			opts.RescanWindow = defaultRescanWindow
+			// Optional re-scan window
+			if i+1 < len(os.Args) {
+				if d, err := time.ParseDuration(os.Args[i+1]); err == nil {
+					if d < 0 {
+						logger.Fatalf("invalid -dup-check window: %s", os.Args[i+1])
+					}
+					opts.RescanWindow = d
+					i++
+				}
+			}
+		case "-removal-timeout":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d < 0 {
+					logger.Fatalf("invalid -removal-timeout: %s", os.Args[i+1])
+				}
+				opts.RemovalTimeout = d
+				i++
+			}
+		case "-ndef-hex":
+			opts.NDEFHex = true
+		case "-once":
+			opts.Once = true
+		case "-tail":
+			if i+1 < len(os.Args) {
+				opts.TailFile = os.Args[i+1]
+				i++
+			}
+		case "-csv-out":
+			if i+1 < len(os.Args) {
+				opts.CSVOut = os.Args[i+1]
+				i++
+			}
+		case "-parse":
+			if i+1 < len(os.Args) {
+				opts.ParseFile = os.Args[i+1]
+				i++
+			}
+		case "-parse-pages":
+			if i+1 < len(os.Args) {
+				opts.ParsePagesFile = os.Args[i+1]
+				i++
+			}
+		case "-verify-sig":
+			opts.VerifySig = true
+		case "-sig-key":
+			if i+1 < len(os.Args) {
+				if _, err := parseSigKey(os.Args[i+1]); err != nil {
+					logger.Fatalf("invalid -sig-key: %v", err)
+				}
+				opts.SigKey = os.Args[i+1]
+				opts.VerifySig = true
+				i++
+			}
+		case "-debug":
+			logger.SetLevel(levelDebug)
+		case "-trace":
+			apduTrace = os.Stderr
+		case "-quiet":
+			logger.SetLevel(levelWarn)
+		case "-log-level":
+			if i+1 < len(os.Args) {
+				level, err := parseLogLevel(os.Args[i+1])
+				if err != nil {
+					logger.Fatalf("invalid -log-level: %v", err)
+				}
+				logger.SetLevel(level)
+				i++
+			}
+		case "-uid-attrib":
+			if i+1 < len(os.Args) {
+				id, err := strconv.ParseUint(os.Args[i+1], 0, 32)
+				if err != nil {
+					logger.Fatalf("invalid -uid-attrib: %s", os.Args[i+1])
+				}
+				opts.UIDAttrib = scard.Attrib(id)
+				i++
+			}
+		}
+	}
+
+	if opts.NDEFHex && (opts.Benchmark > 0 || opts.CSVOut != "") {
+		logger.Fatalf("-ndef-hex prints only the NDEF message and cannot be combined with -benchmark or -csv-out")
+	}
+	if opts.Markdown && (opts.NDEFHex || opts.Benchmark > 0 || opts.CSVOut != "" || opts.TailFile != "" || opts.ProbeClone) {
+		logger.Fatalf("-markdown replaces the analysis and cannot be combined with -ndef-hex, -benchmark, -csv-out, -tail or -probe-clone")
+	}
+	if opts.JSONLines && (opts.NDEFHex || opts.Benchmark > 0 || opts.CSVOut != "" || opts.TailFile != "" || opts.ProbeClone || opts.Markdown) {
+		logger.Fatalf("-jsonl replaces the analysis and cannot be combined with -ndef-hex, -benchmark, -csv-out, -tail, -probe-clone or -markdown")
+	}
+	if opts.CompareTwo && (opts.NDEFHex || opts.Benchmark > 0 || opts.CSVOut != "" || opts.TailFile != "" || opts.ProbeClone || opts.Markdown || opts.JSONLines) {
+		logger.Fatalf("-compare-two reads exactly two tags and cannot be combined with -ndef-hex, -benchmark, -csv-out, -tail, -probe-clone, -markdown or -jsonl")
+	}
+	if opts.ProbeClone && (opts.NDEFHex || opts.Benchmark > 0 || opts.CSVOut != "" || opts.TailFile != "") {
+		logger.Fatalf("-probe-clone only runs the clone checks and cannot be combined with -ndef-hex, -benchmark, -csv-out or -tail")
+	}
+	if opts.TailFile != "" && (opts.NDEFHex || opts.Benchmark > 0 || opts.CSVOut != "") {
+		logger.Fatalf("-tail only logs tag identities and cannot be combined with -ndef-hex, -benchmark or -csv-out")
+	}
+
+	// Offline mode: analyze a hex dump without touching PC/SC
+	if opts.ParseFile != "" {
+		if err := runParse(opts.ParseFile); err != nil {
+			logger.Fatalf("parse: %v", err)
+		}
+		return
+	}
+	if opts.ParsePagesFile != "" {
+		if err := runParsePages(opts.ParsePagesFile); err != nil {
+			logger.Fatalf("parse-pages: %v", err)
+		}
+		return
+	}
+
+	// Cancel blocking waits on Ctrl+C / SIGTERM so the PC/SC context is released cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Establish PC/SC context
-	ctx, err := scard.EstablishContext()
+	pcsc, err := scard.EstablishContext()
 	if err != nil {
-		log.Fatalf("pcsc EstablishContext: %v", err)
+		logger.Fatalf("pcsc EstablishContext: %v", describePCSCError(err))
+	}
+	defer pcsc.Release()
+
+	if opts.ListReaders {
+		code := printReaderList(pcsc)
+		pcsc.Release()
+		os.Exit(code)
 	}
-	defer ctx.Release()
 
 	// Ensure a reader is available
-	readers, err := ctx.ListReaders()
+	readers, err := listReaders(ctx, pcsc, opts.WaitForReader, opts.ReaderTimeout)
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
-		log.Fatalf("pcsc ListReaders: %v", err)
+		logger.Fatalf("pcsc ListReaders: %v", describePCSCError(err))
 	}
 	if len(readers) == 0 {
-		log.Fatalf("no PC/SC readers found")
+		logger.Fatalf("no PC/SC readers found")
 	}
 	reader := readers[0]
-	fmt.Printf("📱 Using reader: %s\n", reader)
-	fmt.Printf("🔄 Waiting for NFC tags... (place tag on reader)\n\n")
-
-	// Loop forever: wait for insertion, process, then wait for removal
+	if opts.Pick {
+		reader, err = pickReader(pcsc, readers, os.Stdin)
+		if err != nil {
+			logger.Fatalf("reader selection: %v", err)
+		}
+	}
+	logger.Infof("📱 Using reader: %s", reader)
+	if opts.PagesPerRead == 0 {
+		opts.PagesPerRead = defaultPagesPerRead(reader)
+	}
+	if opts.CompareTwo {
+		code := runCompareTwo(ctx, pcsc, reader, opts.ComparePages)
+		pcsc.Release()
+		os.Exit(code)
+	}
+	logger.Infof("🔄 Waiting for NFC tags... (place tag on reader)")
+
+	// UIDs processed this session, for -distinct
+	seen := make(map[string]bool)
+	// Tag still on the reader when -removal-timeout expired, skipped until removed
+	var leftBehind []byte
+	// Audit log for -tail
+	tail := &tailLog{path: opts.TailFile}
+	// UID counts for -dup-check
+	dups := newDupTracker(opts.RescanWindow)
+
+	// Loop until cancelled: wait for insertion, process, then wait for removal
 	for {
 		// Wait until a card is present
-		waitForCardPresent(ctx, reader)
+		if err := waitForCardPresent(ctx, pcsc, reader); err != nil {
+			return
+		}
 
 		// Try connecting
-		var card *scard.Card
-		for i := 0; i < 10; i++ {
-			card, err = ctx.Connect(reader, scard.ShareExclusive, scard.ProtocolAny)
-			if err == nil {
-				break
-			}
-			time.Sleep(100 * time.Millisecond)
-		}
+		card, err := connectCard(pcsc, reader)
 		if err != nil {
-			fmt.Printf("❌ Connect failed: %v\n", err)
-			waitForCardRemoval(ctx, reader)
+			logger.Errorf("❌ Connect failed: %v", describePCSCError(err))
+			if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); err != nil && !errors.Is(err, errRemovalTimeout) {
+				return
+			}
 			continue
 		}
+		if leftBehind != nil {
+			if uid, err := getUID(card); err == nil && bytes.Equal(uid, leftBehind) {
+				card.Disconnect(scard.LeaveCard)
+				if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); err != nil && !errors.Is(err, errRemovalTimeout) {
+					return
+				}
+				continue
+			}
+			leftBehind = nil
+		}
 
 		// Process the tag
+		var lastUID []byte
+		done := false
+		failed := false
 		func() {
 			defer card.Disconnect(scard.LeaveCard)
-			readFullTag(card)
+			if opts.NoRemovalWait || opts.Distinct || opts.RemovalTimeout > 0 || opts.DupCheck {
+				lastUID, _ = getUID(card)
+			}
+			if opts.DupCheck && lastUID != nil {
+				key := strings.ToUpper(hex.EncodeToString(lastUID))
+				if count, duplicate := dups.see(key, time.Now()); duplicate {
+					logger.Warnf("🚨 DUPLICATE UID %s: seen %d times this session (clone or factory error?)", key, count)
+				}
+			}
+			if opts.Distinct {
+				if lastUID == nil {
+					logger.Warnf("⚠️  Could not read the UID, skipping")
+					return
+				}
+				key := strings.ToUpper(hex.EncodeToString(lastUID))
+				if seen[key] {
+					logger.Warnf("⚠️  UID %s already scanned this session, skipping (%d distinct)", key, len(seen))
+					return
+				}
+				seen[key] = true
+				defer func() {
+					logger.Infof("📊 Distinct tags: %d", len(seen))
+					done = done || opts.DistinctTarget > 0 && len(seen) >= opts.DistinctTarget
+				}()
+			}
+			done = opts.Once // A skipped repeat UID doesn't count as the one tag
+			passThroughOK, tagVersion = checkPassThrough(card, reader)
+			if opts.Benchmark > 0 {
+				runBenchmark(card, opts.Benchmark)
+				return
+			}
+			if opts.ProbeClone {
+				probeClone(card)
+				return
+			}
+			if opts.JSONLines {
+				if err := printJSONLine(card); err != nil {
+					logger.Errorf("❌ %v", err)
+					failed = true
+				}
+				return
+			}
+			if opts.Markdown {
+				if err := printMarkdownReport(card); err != nil {
+					logger.Errorf("❌ %v", err)
+					failed = true
+				}
+				return
+			}
+			if opts.TailFile != "" {
+				if err := tail.record(card); err != nil {
+					logger.Errorf("❌ Failed to log to %s: %v", opts.TailFile, err)
+					failed = true
+				}
+				return
+			}
+			if opts.NDEFHex {
+				if err := printNDEFHex(card); err != nil {
+					logger.Errorf("❌ %v", err)
+					failed = true
+				}
+				return
+			}
+			if summary, ok := readFullTag(card); ok && opts.CSVOut != "" {
+				if err := appendCSV(opts.CSVOut, summary); err != nil {
+					logger.Errorf("❌ Failed to write %s: %v", opts.CSVOut, err)
+				}
+			}
 		}()
 
+		if done {
+			if opts.DistinctTarget > 0 && len(seen) >= opts.DistinctTarget {
+				logger.Infof("✅ Reached %d distinct tags", opts.DistinctTarget)
+			}
+			if failed {
+				pcsc.Release()
+				os.Exit(1)
+			}
+			return
+		}
+
+		if opts.NoRemovalWait {
+			logger.Infof("🔄 Place another tag to analyze...")
+			if err := waitForDifferentUID(ctx, pcsc, reader, lastUID); err != nil {
+				return
+			}
+			dups.markRemoved(time.Now())
+			continue
+		}
+
 		// Wait until the card is removed before processing the next one
-		fmt.Printf("\n🔄 Remove tag and place another to analyze...\n\n")
-		waitForCardRemoval(ctx, reader)
+		logger.Infof("🔄 Remove tag and place another to analyze...")
+		if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); errors.Is(err, errRemovalTimeout) {
+			// Warned once here; the skip above waits quietly until it is removed
+			logger.Warnf("⚠️  Tag still on the reader after %v, moving on (it is not analyzed again until removed)", opts.RemovalTimeout)
+			leftBehind = lastUID
+		} else if err != nil {
+			return
+		}
+		tail.markRemoved()
+		dups.markRemoved(time.Now())
 	}
 }
 
-// waitForCardPresent blocks until the reader reports a present card
-func waitForCardPresent(ctx *scard.Context, reader string) {
+// noRemovalPoll and noRemovalDebounce tune -no-removal-wait: how often the
+// field is checked for a new tag, and how long it must stay empty before the
+// next tag is treated as new even if it has the same UID
+const (
+	noRemovalPoll     = 250 * time.Millisecond
+	noRemovalDebounce = time.Second
+)
+
+// connectCard connects to the tag on reader, retrying briefly on transient
+// errors. When another application already has the tag open (sharing
+// violation), it connects in shared mode instead of failing.
+func connectCard(pcsc *scard.Context, reader string) (*scard.Card, error) {
+	var card *scard.Card
+	var err error
+	for i := 0; i < 10; i++ {
+		card, err = pcsc.Connect(reader, scard.ShareExclusive, scard.ProtocolAny)
+		if errors.Is(err, scard.ErrSharingViolation) {
+			card, err = pcsc.Connect(reader, scard.ShareShared, scard.ProtocolAny)
+			if err == nil {
+				logger.Warnf("⚠️  Tag is in use by another application, connected in shared mode")
+			}
+		}
+		if err == nil {
+			return card, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil, err
+}
+
+// waitForCardPresent blocks until the reader reports a present card or ctx is cancelled
+func waitForCardPresent(ctx context.Context, pcsc *scard.Context, reader string) error {
 	rs := []scard.ReaderState{{Reader: reader, CurrentState: scard.StateUnaware}}
 	for {
-		_ = ctx.GetStatusChange(rs, time.Second)
+		_ = waitStatusChange(ctx, pcsc, rs, time.Second)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		st := rs[0].EventState
 		rs[0].CurrentState = st
 		if st&scard.StatePresent != 0 {
-			return
+			return nil
+		}
+		if err := sleepContext(ctx, 100*time.Millisecond); err != nil {
+			return err
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 }
 
-// waitForCardRemoval blocks until the reader reports no card present
-func waitForCardRemoval(ctx *scard.Context, reader string) {
+// errRemovalTimeout is returned by waitForCardRemoval when -removal-timeout expires
+var errRemovalTimeout = errors.New("tag not removed in time")
+
+// waitForCardRemoval blocks until the reader reports no card present or ctx
+// is cancelled, or returns errRemovalTimeout after timeout (0 = no limit)
+func waitForCardRemoval(ctx context.Context, pcsc *scard.Context, reader string, timeout time.Duration) error {
 	rs := []scard.ReaderState{{Reader: reader, CurrentState: scard.StateUnaware}}
+	start := time.Now()
 	for {
-		_ = ctx.GetStatusChange(rs, time.Second)
+		if timeout > 0 && time.Since(start) >= timeout {
+			return errRemovalTimeout
+		}
+		_ = waitStatusChange(ctx, pcsc, rs, time.Second)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		st := rs[0].EventState
 		rs[0].CurrentState = st
 		if st&scard.StatePresent == 0 {
-			return
+			return nil
+		}
+		if err := sleepContext(ctx, 150*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForDifferentUID polls the reader until a tag with a UID other than last
+// is in the field, or the field has stayed empty for noRemovalDebounce. A tag
+// that lingers or flickers at the edge of the field is not read again.
+func waitForDifferentUID(ctx context.Context, pcsc *scard.Context, reader string, last []byte) error {
+	var emptySince time.Time
+	for {
+		if err := sleepContext(ctx, noRemovalPoll); err != nil {
+			return err
+		}
+		card, err := pcsc.Connect(reader, scard.ShareShared, scard.ProtocolAny)
+		if err != nil {
+			// No card (or not ready yet): a long enough gap counts as a removal
+			if emptySince.IsZero() {
+				emptySince = time.Now()
+			} else if time.Since(emptySince) >= noRemovalDebounce {
+				return nil
+			}
+			continue
 		}
-		time.Sleep(150 * time.Millisecond)
+		emptySince = time.Time{}
+		uid, err := getUID(card)
+		card.Disconnect(scard.LeaveCard)
+		if err == nil && !bytes.Equal(uid, last) {
+			logger.Debugf("New UID % X in the field", uid)
+			return nil
+		}
+	}
+}
+
+// waitStatusChange calls GetStatusChange and uses the PC/SC cancel mechanism
+// to wake the blocking call early when ctx is cancelled
+func waitStatusChange(ctx context.Context, pcsc *scard.Context, rs []scard.ReaderState, timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			pcsc.Cancel()
+		case <-done:
+		}
+	}()
+
+	if err := pcsc.GetStatusChange(rs, timeout); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return ctx.Err()
+}
+
+// sleepContext pauses for d, returning early with ctx.Err() if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// mdCell escapes text for a Markdown table cell
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// printableASCII renders bytes as ASCII with "." for non-printable bytes
+func printableASCII(data []byte) string {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		if b >= 0x20 && b < 0x7F {
+			out[i] = b
+		} else {
+			out[i] = '.'
+		}
+	}
+	return string(out)
+}
+
+// recordSummary describes a record's content in one line for the records table
+func recordSummary(rec ndefRecord) string {
+	switch rec.Kind() {
+	case "uri":
+		if len(rec.Payload) > 0 && rec.Payload[0] <= 0x23 {
+			return getURIPrefix(rec.Payload[0]) + string(rec.Payload[1:])
+		}
+	case "text":
+		if text, lang, err := decodeTextPayload(rec.Payload); err == nil {
+			return fmt.Sprintf("%s (%s)", text, lang)
+		}
+	case "external":
+		if string(rec.Type) == aarType {
+			return "Android app " + string(rec.Payload)
+		}
+	}
+	return fmt.Sprintf("%d bytes", len(rec.Payload))
+}
+
+// markdownReport renders a Type 2 tag as Markdown for issues and docs: a
+// summary table, the pages read up to the end of the TLV area and a table of
+// the NDEF records
+func markdownReport(card transceiver) (string, error) {
+	uid, err := getUID(card)
+	if err != nil {
+		return "", fmt.Errorf("read UID: %w", err)
+	}
+	tagType := identifyTagType(card)
+	maxPage := maxPageFor(tagType)
+
+	var pages [][]byte
+	var area []byte
+	reader := newBlockReader(card)
+	reader.enableFastRead(tagType)
+	for page := 0; page <= int(maxPage); page++ {
+		data, err := reader.read(byte(page))
+		if err != nil {
+			break
+		}
+		pages = append(pages, data)
+		if page >= 4 {
+			area = append(area, data...)
+			if tlvAreaComplete(area) {
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("## NFC tag report\n\n")
+	b.WriteString("| Field | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| UID | `%s` (%s) |\n", strings.ToUpper(hex.EncodeToString(uid)), uidSizeLabel(uid))
+	fmt.Fprintf(&b, "| Tag type | %s |\n", mdCell(tagType))
+	fmt.Fprintf(&b, "| Memory | %d pages (0x00 to 0x%02X) |\n", int(maxPage)+1, maxPage)
+	if len(pages) > 3 && pages[3][0] == 0xE1 {
+		fmt.Fprintf(&b, "| CC | `% X` (%d byte data area) |\n", pages[3], int(pages[3][2])*8)
+	}
+
+	b.WriteString("\n### Pages\n\n")
+	b.WriteString("| Page | Hex | ASCII |\n|---|---|---|\n")
+	for page, data := range pages {
+		fmt.Fprintf(&b, "| %02X | `% X` | `%s` |\n", page, data, strings.ReplaceAll(printableASCII(data), "`", "."))
+	}
+	if len(pages) <= int(maxPage) {
+		fmt.Fprintf(&b, "\nPages %02X-%02X not read (past the Terminator TLV or unreadable).\n", len(pages), maxPage)
+	}
+
+	b.WriteString("\n### NDEF records\n\n")
+	end, length, ok := ndefTLVExtent(area)
+	if !ok || end > len(area) {
+		b.WriteString("No NDEF message.\n")
+		return b.String(), nil
+	}
+	if length == 0 {
+		b.WriteString("Empty NDEF message (TLV length 0).\n")
+		return b.String(), nil
+	}
+	records, err := decodeNDEFRecords(area[end-length : end])
+	if len(records) > 0 {
+		b.WriteString("| # | TNF | Type | ID | Content |\n|---|---|---|---|---|\n")
+		for i, rec := range records {
+			id := ""
+			if len(rec.ID) > 0 {
+				id = formatRecordID(rec.ID)
+			}
+			fmt.Fprintf(&b, "| %d | %d (%s) | %s | %s | %s |\n", i+1, rec.TNF, rec.Kind(),
+				mdCell(string(rec.Type)), mdCell(id), mdCell(recordSummary(rec)))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\n**Decode error:** %s\n", mdCell(err.Error()))
+	}
+	return b.String(), nil
+}
+
+// printMarkdownReport prints markdownReport to stdout, for -markdown
+func printMarkdownReport(card transceiver) error {
+	report, err := markdownReport(card)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isImageType reports whether a media-type record carries an image/* MIME type
+func isImageType(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(mimeType), "image/")
+}
+
+// mediaExtension guesses a file extension for a MIME type
+func mediaExtension(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "image/png":
+		return "png"
+	case "image/jpeg", "image/jpg":
+		return "jpg"
+	case "image/gif":
+		return "gif"
+	case "image/bmp":
+		return "bmp"
+	case "image/webp":
+		return "webp"
+	case "image/svg+xml":
+		return "svg"
+	default:
+		return "bin"
+	}
+}
+
+// summarizeImagePayload prints a one-line summary of an embedded image by
+// peeking at its header, and saves it when -extract-media is set
+func summarizeImagePayload(mimeType string, payload []byte, recordNum int) {
+	summary := fmt.Sprintf("%s, %d bytes", mimeType, len(payload))
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(payload)); err == nil {
+		summary += fmt.Sprintf(", dimensions %dx%d", cfg.Width, cfg.Height)
+	} else {
+		summary += ", dimensions unknown"
+	}
+	fmt.Printf("        🖼  %s\n", summary)
+
+	if opts.ExtractMediaDir == "" {
+		return
+	}
+	name := fmt.Sprintf("record-%d.%s", recordNum, mediaExtension(mimeType))
+	path, err := savePayload(opts.ExtractMediaDir, name, payload)
+	if err != nil {
+		fmt.Printf("        ❌ Failed to save image: %v\n", err)
+		return
+	}
+	fmt.Printf("        💾 Saved to %s\n", path)
+}
+
+// savePayload writes payload to dir/name, creating dir if needed
+func savePayload(dir, name string, payload []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
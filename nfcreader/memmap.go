@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// regionKind is the role of a range of pages in a Type 2 tag's memory
+type regionKind int
+
+const (
+	regionHeader   regionKind = iota // UID, check bytes and static lock bytes (pages 0-2)
+	regionCC                         // Capability Container, OTP on plain Ultralight
+	regionData                       // User memory holding the NDEF TLVs
+	regionDynLock                    // Dynamic lock bytes
+	regionConfig                     // CFG0/CFG1 (AUTH0, ACCESS)
+	regionPassword                   // PWD, always reads as zeros
+	regionPACK                       // Password acknowledge
+)
+
+// memoryRegion is a labeled page range of the memory map
+type memoryRegion struct {
+	first, last int
+	kind        regionKind
+}
+
+// String returns the region name shown in the map
+func (k regionKind) String() string {
+	switch k {
+	case regionHeader:
+		return "Header/UID"
+	case regionCC:
+		return "CC"
+	case regionData:
+		return "NDEF data"
+	case regionDynLock:
+		return "Dynamic lock"
+	case regionConfig:
+		return "Config"
+	case regionPassword:
+		return "Password"
+	case regionPACK:
+		return "PACK"
+	default:
+		return "Unknown"
+	}
+}
+
+// memoryRegions splits the memory of tagType into its regions, using the
+// same boundaries as the lock map. Tags without a known layout are shown as
+// header, CC and data up to maxPage.
+func memoryRegions(tagType string, maxPage byte) []memoryRegion {
+	regions := []memoryRegion{{0, 2, regionHeader}, {3, 3, regionCC}}
+	l, known := lockLayoutFor(tagType)
+	if !known || l.dynLockPage == 0 {
+		return append(regions, memoryRegion{4, int(maxPage), regionData})
+	}
+	dyn := l.dynLockPage
+	return append(regions,
+		memoryRegion{4, l.lastUserPage, regionData},
+		memoryRegion{dyn, dyn, regionDynLock},
+		memoryRegion{dyn + 1, dyn + 2, regionConfig},
+		memoryRegion{dyn + 3, dyn + 3, regionPassword},
+		memoryRegion{dyn + 4, dyn + 4, regionPACK},
+	)
+}
+
+// summarizeDataArea describes the TLVs at the start of the data area and how
+// many of its bytes they use (up to and including the terminator)
+func summarizeDataArea(data []byte, areaBytes int) string {
+	if len(data) == 0 {
+		return "not read"
+	}
+	content := "no NDEF message"
+	for offset := 0; offset < len(data); {
+		switch data[offset] {
+		case 0x00:
+			offset++
+			continue
+		case 0xFE:
+			return fmt.Sprintf("%s, %d/%d bytes used", content, offset+1, areaBytes)
+		}
+		if offset+1 >= len(data) {
+			break
+		}
+		length, header := int(data[offset+1]), 2
+		if length == 0xFF && offset+3 < len(data) {
+			length, header = int(data[offset+2])<<8|int(data[offset+3]), 4
+		}
+		if data[offset] == 0x03 && content == "no NDEF message" {
+			content = fmt.Sprintf("NDEF message, %d bytes", length)
+			if length == 0 {
+				content = "empty NDEF message"
+			}
+		}
+		offset += header + length
+	}
+	return content + ", no terminator"
+}
+
+// summarizeRegion describes the current content of one region
+func summarizeRegion(r memoryRegion, pages *blockReader, tagType string, ndefData []byte) string {
+	var raw []byte
+	for p := r.first; p <= r.last; p++ {
+		if r.kind == regionData {
+			break // Summarized from the bytes already read
+		}
+		data, err := pages.read(byte(p))
+		if err != nil || len(data) < 4 {
+			return fmt.Sprintf("page %02X unreadable", p)
+		}
+		raw = append(raw, data[:4]...)
+	}
+
+	switch r.kind {
+	case regionHeader:
+		uid := append(append([]byte{}, raw[0:3]...), raw[4:8]...)
+		return fmt.Sprintf("UID % X, static lock %02X %02X", uid, raw[10], raw[11])
+	case regionCC:
+		if tagType == ultralightTagType && raw[0] != 0xE1 {
+			return fmt.Sprintf("OTP % X", raw)
+		}
+		if raw[0] != 0xE1 {
+			return fmt.Sprintf("% X, not NDEF formatted", raw)
+		}
+		access := "read/write"
+		if raw[3]&0x0F != 0 {
+			access = "read-only"
+		}
+		return fmt.Sprintf("% X, %d byte data area, %s", raw, int(raw[2])*8, access)
+	case regionData:
+		return summarizeDataArea(ndefData, (r.last-r.first+1)*4)
+	case regionDynLock:
+		return fmt.Sprintf("lock bytes %02X %02X %02X", raw[0], raw[1], raw[2])
+	case regionConfig:
+		auth := "no password protection"
+		if int(raw[3]) <= r.last+2 { // PACK, two pages on, is the last page
+			auth = fmt.Sprintf("password from page %02X", raw[3])
+		}
+		prot := "writes"
+		if raw[4]&0x80 != 0 {
+			prot = "reads and writes"
+		}
+		return fmt.Sprintf("AUTH0 %02X (%s), ACCESS %02X (PROT: %s)", raw[3], auth, raw[4], prot)
+	case regionPassword:
+		return "PWD, write-only (reads as 00 00 00 00)"
+	case regionPACK:
+		return fmt.Sprintf("PACK %02X %02X", raw[0], raw[1])
+	}
+	return ""
+}
+
+// printMemoryMap draws the tag's page regions as an ASCII table with a
+// summary of each region's content
+func printMemoryMap(pages *blockReader, tagType string, maxPage byte, ndefData []byte) {
+	fmt.Printf("\n=== MEMORY MAP (%s) ===\n", tagType)
+	border := "+-------+--------------+" + strings.Repeat("-", 50)
+	fmt.Println(border)
+	fmt.Printf("| Pages | Region       | Content\n")
+	fmt.Println(border)
+	for _, r := range memoryRegions(tagType, maxPage) {
+		span := fmt.Sprintf("%02X", r.first)
+		if r.last != r.first {
+			span += fmt.Sprintf("-%02X", r.last)
+		}
+		fmt.Printf("| %-5s | %-12s | %s\n", span, r.kind, summarizeRegion(r, pages, tagType, ndefData))
+	}
+	fmt.Println(border)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// expectedMirror returns the ASCII an NTAG21x mirror renders for MIRROR_CONF
+// conf: the UID as uppercase hex, the 24-bit NFC counter as 6 hex digits, or
+// both separated by 'x' (NTAG213/215/216 datasheet 8.7). A negative counter
+// is unknown and rendered as "??????".
+func expectedMirror(conf byte, uid []byte, counter int) string {
+	uidHex := strings.ToUpper(hex.EncodeToString(uid))
+	counterHex := "??????"
+	if counter >= 0 {
+		counterHex = fmt.Sprintf("%06X", counter)
+	}
+	switch conf {
+	case 1:
+		return uidHex
+	case 2:
+		return counterHex
+	case 3:
+		return uidHex + "x" + counterHex
+	}
+	return ""
+}
+
+// mirrorMatches compares mirror bytes read from the tag with want, where a
+// '?' in want stands for any uppercase hex digit
+func mirrorMatches(got []byte, want string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if want[i] == '?' {
+			if !strings.ContainsRune("0123456789ABCDEF", rune(got[i])) {
+				return false
+			}
+		} else if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// readNFCCounter reads the 24-bit NFC counter with READ_CNT (0x39), which
+// needs pass-through and fails while the counter is password protected
+func readNFCCounter(card transceiver) (int, error) {
+	resp, err := passThrough(card, []byte{0x39, 0x02})
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) != 3 {
+		return 0, fmt.Errorf("READ_CNT returned %d bytes, want 3", len(resp))
+	}
+	return int(resp[0]) | int(resp[1])<<8 | int(resp[2])<<16, nil // LSB first
+}
+
+// verifyMirror reads the user memory a configured UID/counter mirror renders
+// into and checks it holds the expected ASCII, catching MIRROR_PAGE and
+// MIRROR_BYTE values that put the mirror somewhere unexpected
+func verifyMirror(card transceiver, layout lockLayout, cfg0 []byte) {
+	conf, mirrorByte, mirrorPage := cfg0[0]>>6, int(cfg0[0]>>4&0x03), int(cfg0[2])
+	if conf == 0 || mirrorPage < 0x04 {
+		return // Mirror off
+	}
+	uid, err := getUID(card)
+	if err != nil {
+		fmt.Printf("  🪞 Mirror check skipped: cannot read UID: %v\n", err)
+		return
+	}
+	counter := -1
+	if conf >= 2 && passThroughOK {
+		if n, err := readNFCCounter(card); err == nil {
+			counter = n
+		} else {
+			logger.Debugf("READ_CNT failed, checking the counter mirror format only: %v", err)
+		}
+	}
+	want := expectedMirror(conf, uid, counter)
+	fmt.Printf("  🪞 Mirror check: %s at page %02X byte %d, expecting %q\n", mirrorConfNames[conf], mirrorPage, mirrorByte, want)
+
+	lastPage := mirrorPage + (mirrorByte+len(want)-1)/4
+	if lastPage > layout.lastUserPage {
+		fmt.Printf("    ❌ Mirror runs to page %02X, past the last user page %02X: the tag will not render it\n", lastPage, layout.lastUserPage)
+		return
+	}
+	var area []byte
+	for page := mirrorPage; page <= lastPage; page++ {
+		data, err := readPage(card, byte(page))
+		if err != nil || len(data) < 4 {
+			fmt.Printf("    ❌ Cannot read mirror page %02X: %v\n", page, err)
+			return
+		}
+		area = append(area, data[:4]...)
+	}
+	got := area[mirrorByte : mirrorByte+len(want)]
+	if mirrorMatches(got, want) {
+		fmt.Printf("    ✅ Tag renders %q\n", got)
+	} else {
+		fmt.Printf("    ❌ Mismatch: tag renders %q (% X)\n", got, got)
+	}
+}
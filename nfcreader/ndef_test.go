@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// readHexFile loads a test vector, ignoring "#" comments and optional "PP:" page prefixes.
+// It returns the bytes and, for page-addressed files, the individual pages.
+func readHexFile(t *testing.T, path string) ([]byte, [][]byte) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := parseHexDump(string(raw))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return d.data, d.pages
+}
+
+// captureStdout returns everything f prints to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+// checkGolden compares got with the .golden file next to the test vector
+func checkGolden(t *testing.T, vector, got string) {
+	t.Helper()
+	golden := strings.TrimSuffix(vector, ".hex") + ".golden"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run go test -update to accept)\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+	}
+}
+
+func TestAnalyzeNDEFStructureGolden(t *testing.T) {
+	vectors, err := filepath.Glob(filepath.Join("testdata", "ndef", "*.hex"))
+	if err != nil || len(vectors) == 0 {
+		t.Fatalf("no test vectors found: %v", err)
+	}
+	for _, vector := range vectors {
+		t.Run(filepath.Base(vector), func(t *testing.T) {
+			opts = Options{}
+			data, _ := readHexFile(t, vector)
+			got := captureStdout(t, func() { analyzeNDEFStructure(data, 4) })
+			checkGolden(t, vector, got)
+		})
+	}
+}
+
+func TestMissingTerminatorWarning(t *testing.T) {
+	opts = Options{}
+	for vector, want := range map[string]bool{"missing_terminator": true, "uri_https": false} {
+		data, _ := readHexFile(t, filepath.Join("testdata", "ndef", vector+".hex"))
+		got := captureStdout(t, func() { analyzeNDEFStructure(data, 4) })
+		if warned := strings.Contains(got, "Missing Terminator TLV"); warned != want {
+			t.Errorf("%s: missing terminator warning = %v, want %v", vector, warned, want)
+		}
+	}
+}
+
+func TestReadFullTagGolden(t *testing.T) {
+	vectors, err := filepath.Glob(filepath.Join("testdata", "tags", "*.hex"))
+	if err != nil || len(vectors) == 0 {
+		t.Fatalf("no tag dumps found: %v", err)
+	}
+	for _, vector := range vectors {
+		t.Run(filepath.Base(vector), func(t *testing.T) {
+			opts = Options{}
+			_, pages := readHexFile(t, vector)
+			tag := &memoryTag{pages: pages}
+			got := captureStdout(t, func() { readFullTag(tag) })
+			checkGolden(t, vector, got)
+		})
+	}
+}
+
+func TestMemoryMapGolden(t *testing.T) {
+	for _, name := range []string{"ntag216_url", "type2_empty"} {
+		t.Run(name, func(t *testing.T) {
+			opts = Options{Map: true}
+			defer func() { opts = Options{} }()
+			vector := filepath.Join("testdata", "tags", name+".hex")
+			_, pages := readHexFile(t, vector)
+			got := captureStdout(t, func() { readFullTag(&memoryTag{pages: pages}) })
+			checkGolden(t, strings.TrimSuffix(vector, ".hex")+".map.hex", got)
+		})
+	}
+}
+
+func TestDecodeNDEFRecordsCorpus(t *testing.T) {
+	tests := []struct {
+		vector string
+		kinds  []string
+		ok     bool
+	}{
+		{"uri_https", []string{"uri"}, true},
+		{"multi_record", []string{"uri", "text"}, true},
+		{"smart_poster", []string{"smartposter"}, true},
+		{"long_record", []string{"uri"}, true},
+		{"chunked", []string{"text", "unchanged", "unchanged"}, true},
+		{"image_media", []string{"media"}, true},
+		{"empty_record", []string{"empty", "uri"}, true},
+		{"empty_record_malformed", []string{"empty", "text"}, true},
+		{"record_ids", []string{"uri", "external"}, true},
+		{"app_launch", []string{"uri", "external"}, true},
+		{"handover_select", []string{"wellknown", "media", "media"}, true},
+		{"unknown_reserved_tnf", []string{"unknown", "reserved", "unknown"}, true},
+		{"payload_length_overflow", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.vector, func(t *testing.T) {
+			data, _ := readHexFile(t, filepath.Join("testdata", "ndef", tt.vector+".hex"))
+			// Skip the NDEF TLV header (type + 1-byte length) to get the message
+			records, err := decodeNDEFRecords(data[2 : 2+int(data[1])])
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+			var kinds []string
+			for _, rec := range records {
+				kinds = append(kinds, rec.Kind())
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.kinds, ",") {
+				t.Errorf("kinds = %v, want %v", kinds, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestRecordIDs(t *testing.T) {
+	data, _ := readHexFile(t, filepath.Join("testdata", "ndef", "record_ids.hex"))
+	records, err := decodeNDEFRecords(data[2 : 2+int(data[1])])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]byte{[]byte("0"), {0x00, 0xFF, 0x80}}
+	for i, rec := range records {
+		if !rec.IL || !bytes.Equal(rec.ID, want[i]) {
+			t.Errorf("record %d: IL=%v ID % X, want ID % X", i+1, rec.IL, rec.ID, want[i])
+		}
+	}
+	if got, want := formatRecordID([]byte{0x00, 0xFF, 0x80}), "00 FF 80 (binary, 3 bytes)"; got != want {
+		t.Errorf("formatRecordID = %q, want %q", got, want)
+	}
+}
+
+func TestReadFirstURI(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	got, err := ReadFirstURI(&memoryTag{pages: pages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://dnd.qrand.me/r/04A1B2C3D4E5F6"; got != want {
+		t.Errorf("ReadFirstURI = %q, want %q", got, want)
+	}
+
+	if _, _, err := ReadFirstText(&memoryTag{pages: pages}); !errors.Is(err, errRecordNotFound) {
+		t.Errorf("ReadFirstText on a URI-only tag: err = %v, want errRecordNotFound", err)
+	}
+}
+
+func TestReadPages(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	tag := &memoryTag{pages: pages, rollover: true}
+
+	got, err := ReadPages(tag, 0x04, 0x09)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Join(pages[4:10], nil); !bytes.Equal(got, want) {
+		t.Errorf("ReadPages(04, 09) = % X, want % X", got, want)
+	}
+
+	// Past the end of memory: the pages before the boundary, then a typed error,
+	// not the page 0-1 data the 16-byte read at last-1 rolls over to
+	last := byte(len(pages) - 1)
+	got, err = ReadPages(tag, last-1, last+2)
+	var perr *PageReadError
+	if !errors.As(err, &perr) || perr.Page != last+1 {
+		t.Fatalf("err = %v, want *PageReadError at page %02X", err, last+1)
+	}
+	if want := bytes.Join(pages[last-1:], nil); !bytes.Equal(got, want) {
+		t.Errorf("partial read = % X, want % X", got, want)
+	}
+}
+
+func TestNDEFTreeGolden(t *testing.T) {
+	for _, name := range []string{"app_launch", "empty_record_malformed", "handover_select", "multi_record", "record_ids", "smart_poster", "smart_poster_truncated", "unknown_reserved_tnf"} {
+		t.Run(name, func(t *testing.T) {
+			opts = Options{Tree: true}
+			defer func() { opts = Options{} }()
+			vector := filepath.Join("testdata", "ndef", name+".hex")
+			data, _ := readHexFile(t, vector)
+			got := captureStdout(t, func() { analyzeNDEFStructure(data, 4) })
+			checkGolden(t, strings.TrimSuffix(vector, ".hex")+".tree.hex", got)
+		})
+	}
+}
+
+func TestParsePageDump(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+
+	// Pages in reverse order, every other one in the "Page PP:" form, and the
+	// first two pages on one line
+	var dump strings.Builder
+	dump.WriteString("# NTAG216 in page-addressed form\n")
+	for p := len(pages) - 1; p >= 2; p-- {
+		if p%2 == 0 {
+			fmt.Fprintf(&dump, "Page %02X: % X  // page %d\n", p, pages[p], p)
+		} else {
+			fmt.Fprintf(&dump, "%02X: % X\n", p, pages[p])
+		}
+	}
+	fmt.Fprintf(&dump, "00: % X % X\n", pages[0], pages[1])
+
+	got, err := parsePageDump(dump.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(pages) {
+		t.Fatalf("got %d pages, want %d", len(got), len(pages))
+	}
+	for p := range pages {
+		if !bytes.Equal(got[p], pages[p]) {
+			t.Errorf("page %02X = % X, want % X", p, got[p], pages[p])
+		}
+	}
+
+	for _, bad := range []string{
+		"03 0F D1 01",          // No page address
+		"04: 03 0F D1",         // Partial page
+		"Page 4G: 00 00 00 00", // Bad page number
+		"04: 00 00 00 00\n04: 00 00 00 01",
+	} {
+		if _, err := parsePageDump(bad); err == nil {
+			t.Errorf("parsePageDump(%q) succeeded", bad)
+		}
+	}
+
+	// Missing pages read back as errors
+	gap := &memoryTag{pages: [][]byte{pages[0], pages[1], pages[2], pages[3], nil, pages[5]}}
+	if _, err := readPage(gap, 0x04); err == nil {
+		t.Error("read of a missing page succeeded")
+	}
+}
+
+func TestParseHexDump(t *testing.T) {
+	// Several pages on the "00:" line and the lines out of order
+	d, err := parseHexDump("04: 03 00 FE 00\n00: 04 A1 B2 9F C3 D4 E5 F6 B0 48 00 00 E1 10 6D 00\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.pages) != 5 || d.firstPage != 0 || !bytes.Equal(d.pages[1], []byte{0xC3, 0xD4, 0xE5, 0xF6}) || !bytes.Equal(d.pages[4], []byte{0x03, 0x00, 0xFE, 0x00}) {
+		t.Errorf("pages = % X, first page %d", d.pages, d.firstPage)
+	}
+	if len(d.data) != 20 || d.data[16] != 0x03 {
+		t.Errorf("data = % X, want pages 00-04 in page order", d.data)
+	}
+
+	for _, bad := range []string{
+		"00: 04 A1",                        // Short page
+		"00: 04 A1 B2 9F\n02: B0 48 00 00", // Gap at page 01
+	} {
+		if _, err := parseHexDump(bad); err == nil {
+			t.Errorf("parseHexDump(%q) succeeded", bad)
+		}
+	}
+
+	// A dump of page 00 alone reads as a tag without UID, not a panic
+	if _, err := getUID(&memoryTag{pages: [][]byte{{0x04, 0xA1, 0xB2, 0x9F}}}); err == nil {
+		t.Error("UID read from a dump without page 01")
+	}
+}
+
+func TestMarkdownReportGolden(t *testing.T) {
+	for _, name := range []string{"ntag216_url", "type2_empty"} {
+		t.Run(name, func(t *testing.T) {
+			vector := filepath.Join("testdata", "tags", name+".hex")
+			_, pages := readHexFile(t, vector)
+			got, err := markdownReport(&memoryTag{pages: pages})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, strings.TrimSuffix(vector, ".hex")+".md.hex", got)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ntagConfigLabels names the pages from the dynamic lock page up, in order
+var ntagConfigLabels = []string{"Dynamic Lock", "CFG0", "CFG1", "PWD", "PACK"}
+
+// printNTAGConfigPages dumps the dynamic lock, configuration, PWD and PACK
+// pages that follow the user memory of an NTAG21x
+func printNTAGConfigPages(card transceiver, tagType string, maxPage byte) {
+	layout, ok := lockLayoutFor(tagType)
+	if !ok || layout.dynLockPage == 0 {
+		return
+	}
+	fmt.Printf("\n=== NTAG CONFIGURATION PAGES ===\n")
+
+	auth0 := -1 // Unknown until CFG0 is read
+	for i, label := range ntagConfigLabels {
+		page := layout.dynLockPage + i
+		if page > int(maxPage) {
+			break
+		}
+		data, err := readPage(card, byte(page))
+		if err != nil {
+			fmt.Printf("Page %02X: ❌ Error: %v (%s)\n", page, err, label)
+			continue
+		}
+		fmt.Printf("Page %02X: % X (%s)\n", page, data, label)
+		if len(data) < 4 {
+			continue
+		}
+
+		switch label {
+		case "CFG0":
+			auth0 = int(data[3])
+		case "PWD":
+			printPWDPage(data, auth0, maxPage)
+		case "PACK":
+			printPACKPage(data, auth0 >= 0 && auth0 <= int(maxPage))
+		}
+	}
+}
+
+// printPWDPage explains what the PWD page read returned. Genuine NTAG21x
+// never reveal the password and always answer 00 00 00 00.
+func printPWDPage(data []byte, auth0 int, maxPage byte) {
+	switch {
+	case !bytes.Equal(data[:4], []byte{0, 0, 0, 0}):
+		fmt.Printf("    ⚠️  Password readable: %02X%02X%02X%02X (genuine NTAGs hide it, likely a clone)\n", data[0], data[1], data[2], data[3])
+	case auth0 >= 0 && auth0 <= int(maxPage):
+		fmt.Printf("    🔒 Reads as zeros: password protection is active from page %02X and hides the password\n", auth0)
+	default:
+		fmt.Printf("    Reads as zeros: the password is never readable (factory default FF FF FF FF)\n")
+	}
+}
+
+// printPACKPage shows the 16-bit password acknowledge returned by PWD_AUTH.
+// Bytes 2-3 are RFU.
+func printPACKPage(data []byte, protected bool) {
+	pack := fmt.Sprintf("%02X %02X", data[0], data[1])
+	switch {
+	case data[0] != 0 || data[1] != 0:
+		fmt.Printf("    PACK: %s (readable)\n", pack)
+	case protected:
+		fmt.Printf("    PACK: %s, hidden while password protection is active\n", pack)
+	default:
+		fmt.Printf("    PACK: %s (genuine NTAGs return zeros here, the real value is only sent after PWD_AUTH)\n", pack)
+	}
+}
+
+// mirrorConfNames are the MIRROR_CONF values of CFG0 byte 0, bits 7-6
+var mirrorConfNames = [4]string{"no ASCII mirror", "UID mirror", "NFC counter mirror", "UID and NFC counter mirror"}
+
+// decodeCFG0 explains the MIRROR byte and MIRROR_PAGE of CFG0 bit by bit
+// (NTAG213/215/216 datasheet 8.5.7); AUTH0 in byte 3 is left to the caller
+func decodeCFG0(cfg []byte) []string {
+	mirror := cfg[0]
+	conf := mirror >> 6
+	lines := []string{
+		fmt.Sprintf("MIRROR: %02X", mirror),
+		fmt.Sprintf("  MIRROR_CONF: %d (%s)", conf, mirrorConfNames[conf]),
+		fmt.Sprintf("  MIRROR_BYTE: %d (mirror starts at byte %d of MIRROR_PAGE)", mirror>>4&0x03, mirror>>4&0x03),
+	}
+	if mirror&0x04 != 0 {
+		lines = append(lines, "  STRG_MOD_EN: 1 (strong modulation enabled, the factory default)")
+	} else {
+		lines = append(lines, "  STRG_MOD_EN: 0 (strong modulation disabled)")
+	}
+	if rfui := mirror & 0x0B; rfui != 0 {
+		lines = append(lines, fmt.Sprintf("  ⚠️  RFU bits set: %02X", rfui))
+	}
+	lines = append(lines, fmt.Sprintf("RFUI: %02X", cfg[1]))
+
+	switch {
+	case conf == 0:
+		lines = append(lines, fmt.Sprintf("MIRROR_PAGE: %02X (unused, no mirror configured)", cfg[2]))
+	case cfg[2] < 0x04:
+		lines = append(lines, fmt.Sprintf("MIRROR_PAGE: %02X (mirror disabled, page must be 04 or above)", cfg[2]))
+	default:
+		lines = append(lines, fmt.Sprintf("MIRROR_PAGE: %02X (mirror starts at page %02X)", cfg[2], cfg[2]))
+	}
+	return lines
+}
+
+// decodeCFG1 explains the ACCESS byte of CFG1 bit by bit (NTAG213/215/216
+// datasheet 8.5.7); bytes 1-3 are RFU
+func decodeCFG1(cfg []byte) []string {
+	access := cfg[0]
+	lines := []string{fmt.Sprintf("ACCESS: %02X", access)}
+	if access&0x80 != 0 {
+		lines = append(lines, "  PROT: 1 (password required for reads and writes from AUTH0)")
+	} else {
+		lines = append(lines, "  PROT: 0 (password required for writes only from AUTH0)")
+	}
+	if access&0x40 != 0 {
+		lines = append(lines, "  CFGLCK: 1 (configuration pages permanently locked)")
+	} else {
+		lines = append(lines, "  CFGLCK: 0 (configuration pages writable)")
+	}
+	if access&0x10 != 0 {
+		lines = append(lines, "  NFC_CNT_EN: 1 (NFC counter enabled)")
+	} else {
+		lines = append(lines, "  NFC_CNT_EN: 0 (NFC counter disabled)")
+	}
+	if access&0x08 != 0 {
+		lines = append(lines, "  NFC_CNT_PWD_PROT: 1 (READ_CNT needs the password)")
+	} else {
+		lines = append(lines, "  NFC_CNT_PWD_PROT: 0 (READ_CNT open to anyone)")
+	}
+	if access&0x20 != 0 {
+		lines = append(lines, "  ⚠️  RFU bit 5 set")
+	}
+	if authLim := access & 0x07; authLim == 0 {
+		lines = append(lines, "  AUTHLIM: 0 (unlimited password attempts)")
+	} else {
+		lines = append(lines, fmt.Sprintf("  AUTHLIM: %d (tag locks PWD_AUTH after %d failed attempts)", authLim, authLim))
+	}
+	return lines
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCFG(t *testing.T) {
+	// UID and counter mirror from byte 2 of page 0x10, strong modulation off
+	got := decodeCFG0([]byte{0xE0, 0x00, 0x10, 0xFF})
+	want := []string{
+		"MIRROR: E0",
+		"  MIRROR_CONF: 3 (UID and NFC counter mirror)",
+		"  MIRROR_BYTE: 2 (mirror starts at byte 2 of MIRROR_PAGE)",
+		"  STRG_MOD_EN: 0 (strong modulation disabled)",
+		"RFUI: 00",
+		"MIRROR_PAGE: 10 (mirror starts at page 10)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CFG0:\n got %q\nwant %q", got, want)
+	}
+
+	// PROT, counter enabled and password protected, 3 attempts
+	got = decodeCFG1([]byte{0x9B, 0x05, 0x00, 0x00})
+	want = []string{
+		"ACCESS: 9B",
+		"  PROT: 1 (password required for reads and writes from AUTH0)",
+		"  CFGLCK: 0 (configuration pages writable)",
+		"  NFC_CNT_EN: 1 (NFC counter enabled)",
+		"  NFC_CNT_PWD_PROT: 1 (READ_CNT needs the password)",
+		"  AUTHLIM: 3 (tag locks PWD_AUTH after 3 failed attempts)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("CFG1:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	uid := []byte{0x04, 0xA1, 0xB2, 0xC3, 0xD4, 0xE5, 0xF6}
+	if got, want := expectedMirror(3, uid, 0x1F), "04A1B2C3D4E5F6x00001F"; got != want {
+		t.Errorf("UID + counter: got %q, want %q", got, want)
+	}
+	if !mirrorMatches([]byte("00002A"), expectedMirror(2, uid, -1)) {
+		t.Error("unknown counter should match any 6 hex digits")
+	}
+	if mirrorMatches([]byte("04a1b2c3d4e5f6"), expectedMirror(1, uid, -1)) {
+		t.Error("lowercase hex should not match the UID mirror")
+	}
+}
+
+func TestNTAGConfigPageOffsets(t *testing.T) {
+	passThroughOK = false
+	for _, tt := range []struct {
+		tagType string
+		pages   int // Pages 0x00 to PACK (NTAG213/215/216 datasheet 8.5)
+	}{
+		{"NTAG213", 0x2D},
+		{"NTAG215", 0x87},
+		{"NTAG216", 0xE7},
+	} {
+		t.Run(tt.tagType, func(t *testing.T) {
+			pages := make([][]byte, tt.pages)
+			for i := range pages {
+				pages[i] = []byte{0x04, 0x00, 0x00, 0x00}
+			}
+			tag := &memoryTag{pages: pages}
+			if got := identifyTagType(tag); got != tt.tagType {
+				t.Fatalf("identifyTagType = %s", got)
+			}
+			maxPage := maxPageFor(tt.tagType)
+			if int(maxPage) != tt.pages-1 || int(maxPage) != lockLayouts[tt.tagType].dynLockPage+len(ntagConfigLabels)-1 {
+				t.Errorf("maxPageFor = %02X, want the PACK page %02X", maxPage, tt.pages-1)
+			}
+			out := captureStdout(t, func() { printNTAGConfigPages(tag, tt.tagType, maxPage) })
+			if strings.Contains(out, "Error") || !strings.Contains(out, fmt.Sprintf("Page %02X: 04 00 00 00 (PACK)", tt.pages-1)) {
+				t.Errorf("config pages:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NTAG I2C type names, told apart by GET_VERSION (the UID and page probing
+// can't distinguish them from NTAG21x)
+const (
+	ntagI2C1K     = "NTAG I2C 1K"
+	ntagI2C2K     = "NTAG I2C 2K"
+	ntagI2CPlus1K = "NTAG I2C plus 1K"
+	ntagI2CPlus2K = "NTAG I2C plus 2K"
+)
+
+// isNTAG21x reports whether tagType is one of NTAG213/215/216
+func isNTAG21x(tagType string) bool {
+	return strings.HasPrefix(tagType, "NTAG21")
+}
+
+// isNTAGI2C reports whether tagType is an NTAG I2C variant
+func isNTAGI2C(tagType string) bool {
+	return strings.HasPrefix(tagType, "NTAG I2C")
+}
+
+// ntagI2CType maps a GET_VERSION response to an NTAG I2C type name, or "" for other tags.
+// Product type 04 subtype 05 is NTAG I2C; minor version 02 marks the plus variant.
+func ntagI2CType(v []byte) string {
+	if len(v) != 8 || v[1] != 0x04 || v[2] != 0x04 || v[3] != 0x05 {
+		return ""
+	}
+	plus := v[5] == 0x02
+	switch {
+	case v[6] == 0x13 && plus:
+		return ntagI2CPlus1K
+	case v[6] == 0x13:
+		return ntagI2C1K
+	case v[6] == 0x15 && plus:
+		return ntagI2CPlus2K
+	case v[6] == 0x15:
+		return ntagI2C2K
+	}
+	return ""
+}
+
+// memRegion is a labelled page range in one sector of a sector-based memory map
+type memRegion struct {
+	sector      byte
+	first, last byte
+	label       string
+}
+
+// ntagI2CLayout returns the memory map of an NTAG I2C tag. The 1K fits in
+// sector 0; the 2K continues its user memory in sector 1 and keeps its lock
+// and configuration pages there. Session registers live in sector 3.
+func ntagI2CLayout(tagType string) []memRegion {
+	if tagType == ntagI2C2K || tagType == ntagI2CPlus2K {
+		return []memRegion{
+			{0, 0x00, 0x03, "header (UID, static lock, CC)"},
+			{0, 0x04, 0xFF, "user memory"},
+			{1, 0x00, 0xDF, "user memory (continued)"},
+			{1, 0xE0, 0xE0, "dynamic lock bytes"},
+			{1, 0xE8, 0xE9, "configuration registers"},
+			{3, 0xF8, 0xF9, "session registers"},
+		}
+	}
+	return []memRegion{
+		{0, 0x00, 0x03, "header (UID, static lock, CC)"},
+		{0, 0x04, 0xE1, "user memory"},
+		{0, 0xE2, 0xE2, "dynamic lock bytes"},
+		{0, 0xE8, 0xE9, "configuration registers"},
+		{3, 0xF8, 0xF9, "session registers"},
+	}
+}
+
+// ntagI2CMaxPage returns the last page of sector 0 that readFullTag dumps
+func ntagI2CMaxPage(tagType string) byte {
+	if tagType == ntagI2C2K || tagType == ntagI2CPlus2K {
+		return 0xFF
+	}
+	return 0xE9
+}
+
+// selectSector switches the tag to another memory sector with the two-part
+// SECTOR_SELECT command. The tag acknowledges part 2 passively by staying
+// silent, which the reader reports as a timeout.
+func selectSector(card transceiver, sector byte) error {
+	if _, err := passThrough(card, []byte{0xC2, 0xFF}); err != nil {
+		return fmt.Errorf("SECTOR_SELECT part 1: %w", err)
+	}
+	if _, err := passThrough(card, []byte{sector, 0x00, 0x00, 0x00}); err != nil && !errors.Is(err, errTagTimeout) {
+		return fmt.Errorf("SECTOR_SELECT part 2: %w", err)
+	}
+	return nil
+}
+
+// printNTAGI2CMemoryMap prints the NTAG I2C memory map and dumps the regions
+// outside sector 0, switching sectors and returning to sector 0 afterwards
+func printNTAGI2CMemoryMap(card transceiver, tagType string) {
+	fmt.Printf("\n=== NTAG I2C MEMORY MAP ===\n")
+	for _, r := range ntagI2CLayout(tagType) {
+		fmt.Printf("Sector %d, pages %02X-%02X: %s\n", r.sector, r.first, r.last, r.label)
+	}
+
+	if !passThroughOK {
+		fmt.Printf("⚠️  Sectors other than 0 need SECTOR_SELECT, which this reader can't pass through\n")
+		return
+	}
+
+	current := byte(0)
+	for _, r := range ntagI2CLayout(tagType) {
+		if r.sector == 0 {
+			continue // Already dumped by the main read loop
+		}
+		if r.sector != current {
+			if err := selectSector(card, r.sector); err != nil {
+				fmt.Printf("❌ Could not select sector %d: %v\n", r.sector, err)
+				break
+			}
+			current = r.sector
+		}
+		fmt.Printf("\nSector %d, %s:\n", r.sector, r.label)
+		for p := int(r.first); p <= int(r.last); p++ {
+			data, err := readPage(card, byte(p))
+			if err != nil {
+				fmt.Printf("  Page %02X: ❌ Error: %v\n", p, err)
+				break
+			}
+			fmt.Printf("  Page %02X: % X\n", p, data)
+		}
+	}
+
+	if current != 0 {
+		if err := selectSector(card, 0); err != nil {
+			fmt.Printf("⚠️  Could not return to sector 0: %v\n", err)
+		}
+	}
+}
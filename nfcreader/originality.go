@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// nxpNTAG21xKey is NXP's originality signature public key for NTAG21x
+// (secp128r1, uncompressed point)
+const nxpNTAG21xKey = "04494E1A386D3D3CFE3DC10E5DE68A499B1C202DB5B132393E89ED19FE5BE8BC61"
+
+// secp128r1 domain parameters (SEC 2)
+var (
+	secp128r1P  = mustHexInt("FFFFFFFDFFFFFFFFFFFFFFFFFFFFFFFF")
+	secp128r1A  = mustHexInt("FFFFFFFDFFFFFFFFFFFFFFFFFFFFFFFC")
+	secp128r1B  = mustHexInt("E87579C11079F43DD824993C2CEE5ED3")
+	secp128r1Gx = mustHexInt("161FF7528B899B2D0C28607CA52C5B86")
+	secp128r1Gy = mustHexInt("CF5AC8395BAFEB13C02DA292DDED7A83")
+	secp128r1N  = mustHexInt("FFFFFFFE0000000075A30D1B9038A115")
+)
+
+func mustHexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad hex constant " + s)
+	}
+	return n
+}
+
+// ecPoint is an affine point on secp128r1; nil coordinates are the point at infinity
+type ecPoint struct {
+	x, y *big.Int
+}
+
+func (p ecPoint) infinity() bool { return p.x == nil }
+
+// ecAdd adds two points using affine formulas
+func ecAdd(a, b ecPoint) ecPoint {
+	if a.infinity() {
+		return b
+	}
+	if b.infinity() {
+		return a
+	}
+	p := secp128r1P
+	var lambda *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if sum := new(big.Int).Add(a.y, b.y); sum.Mod(sum, p).Sign() == 0 {
+			return ecPoint{} // P + (-P)
+		}
+		// Doubling: (3x² + a) / 2y
+		num := new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3)).Add(num, secp128r1A)
+		den := new(big.Int).Lsh(a.y, 1)
+		lambda = num.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	} else {
+		num := new(big.Int).Sub(b.y, a.y)
+		den := new(big.Int).Sub(b.x, a.x)
+		lambda = num.Mul(num, den.ModInverse(den.Mod(den, p), p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, p)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, lambda).Sub(y, a.y).Mod(y, p)
+	return ecPoint{x, y}
+}
+
+// ecMul computes k·P by double-and-add
+func ecMul(k *big.Int, pt ecPoint) ecPoint {
+	var r ecPoint
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = ecAdd(r, r)
+		if k.Bit(i) == 1 {
+			r = ecAdd(r, pt)
+		}
+	}
+	return r
+}
+
+// onCurve reports whether pt satisfies y² = x³ + ax + b
+func onCurve(pt ecPoint) bool {
+	p := secp128r1P
+	lhs := new(big.Int).Mul(pt.y, pt.y)
+	lhs.Mod(lhs, p)
+	rhs := new(big.Int).Mul(pt.x, pt.x)
+	rhs.Mul(rhs, pt.x).Add(rhs, new(big.Int).Mul(secp128r1A, pt.x)).Add(rhs, secp128r1B).Mod(rhs, p)
+	return lhs.Cmp(rhs) == 0
+}
+
+// parseSigKey parses a secp128r1 public key given as hex, either
+// uncompressed (04 || X || Y) or compressed (02/03 || X)
+func parseSigKey(s string) (ecPoint, error) {
+	raw, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		return ecPoint{}, err
+	}
+	var pt ecPoint
+	switch {
+	case len(raw) == 33 && raw[0] == 0x04:
+		pt = ecPoint{new(big.Int).SetBytes(raw[1:17]), new(big.Int).SetBytes(raw[17:])}
+	case len(raw) == 17 && (raw[0] == 0x02 || raw[0] == 0x03):
+		// y = sqrt(x³ + ax + b); p ≡ 3 (mod 4) so sqrt(v) = v^((p+1)/4)
+		p := secp128r1P
+		x := new(big.Int).SetBytes(raw[1:])
+		v := new(big.Int).Mul(x, x)
+		v.Mul(v, x).Add(v, new(big.Int).Mul(secp128r1A, x)).Add(v, secp128r1B).Mod(v, p)
+		exp := new(big.Int).Add(p, big.NewInt(1))
+		y := new(big.Int).Exp(v, exp.Rsh(exp, 2), p)
+		if y.Bit(0) != uint(raw[0]&1) {
+			y.Sub(p, y)
+		}
+		pt = ecPoint{x, y}
+	default:
+		return ecPoint{}, errors.New("want a 33-byte uncompressed (04...) or 17-byte compressed (02/03...) secp128r1 key")
+	}
+	if !onCurve(pt) {
+		return ecPoint{}, errors.New("key is not a point on secp128r1")
+	}
+	return pt, nil
+}
+
+// verifyOriginality checks an NXP originality signature (r || s, 16 bytes
+// each) over the UID. NXP signs the UID itself, without hashing it first.
+func verifyOriginality(uid, sig []byte, key ecPoint) bool {
+	if len(sig) != 32 {
+		return false
+	}
+	n := secp128r1N
+	r := new(big.Int).SetBytes(sig[:16])
+	s := new(big.Int).SetBytes(sig[16:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	e := new(big.Int).SetBytes(uid)
+	w := new(big.Int).ModInverse(s, n)
+	u1 := new(big.Int).Mul(e, w)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, n)
+	pt := ecAdd(ecMul(u1, ecPoint{secp128r1Gx, secp128r1Gy}), ecMul(u2, key))
+	if pt.infinity() {
+		return false
+	}
+	return new(big.Int).Mod(pt.x, n).Cmp(r) == 0
+}
+
+// readSignature sends READ_SIG (3C 00) and returns the 32-byte originality signature
+func readSignature(card transceiver) ([]byte, error) {
+	sig, err := passThrough(card, []byte{0x3C, 0x00})
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != 32 {
+		return nil, fmt.Errorf("READ_SIG returned %d bytes, want 32", len(sig))
+	}
+	return sig, nil
+}
+
+// printOriginality reads the originality signature, for -verify-sig, and
+// verifies it over the UID against NXP's key or the one given with -sig-key
+func printOriginality(card transceiver, uid []byte) {
+	fmt.Printf("\n=== ORIGINALITY SIGNATURE ===\n")
+	sig, err := readSignature(card)
+	if err != nil {
+		fmt.Printf("❌ READ_SIG failed: %v\n", err)
+		if tagNAKed(err) {
+			reselectTag(card)
+		}
+		return
+	}
+	fmt.Printf("Signature: % X\n", sig)
+
+	keyHex := nxpNTAG21xKey
+	if opts.SigKey != "" {
+		keyHex = opts.SigKey
+	}
+	key, err := parseSigKey(keyHex)
+	if err != nil {
+		fmt.Printf("❌ Invalid public key: %v\n", err)
+		return
+	}
+	if verifyOriginality(uid, sig, key) {
+		if opts.SigKey == "" {
+			fmt.Printf("✅ Originality: VERIFIED (genuine NXP)\n")
+		} else {
+			fmt.Printf("✅ Originality: VERIFIED (against supplied key)\n")
+		}
+	} else {
+		fmt.Printf("❌ Originality: FAILED (signature does not match UID and key)\n")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// signForTest produces an NXP-style signature (no hashing) with private key d and nonce k
+func signForTest(uid []byte, d, k *big.Int) []byte {
+	n := secp128r1N
+	r := new(big.Int).Mod(ecMul(k, ecPoint{secp128r1Gx, secp128r1Gy}).x, n)
+	s := new(big.Int).Mul(r, d)
+	s.Add(s, new(big.Int).SetBytes(uid)).Mul(s, new(big.Int).ModInverse(k, n)).Mod(s, n)
+	return append(r.FillBytes(make([]byte, 16)), s.FillBytes(make([]byte, 16))...)
+}
+
+func TestVerifyOriginality(t *testing.T) {
+	if _, err := parseSigKey(nxpNTAG21xKey); err != nil {
+		t.Fatalf("NXP key: %v", err)
+	}
+
+	d := mustHexInt("0123456789ABCDEF0123456789ABCDEF")
+	key := ecMul(d, ecPoint{secp128r1Gx, secp128r1Gy})
+	uid := []byte{0x04, 0xA1, 0xB2, 0xC3, 0xD4, 0xE5, 0xF6}
+	sig := signForTest(uid, d, mustHexInt("0F1E2D3C4B5A69788796A5B4C3D2E1F0"))
+
+	if !verifyOriginality(uid, sig, key) {
+		t.Error("valid signature rejected")
+	}
+	other := append([]byte{}, uid...)
+	other[6] ^= 0x01
+	if verifyOriginality(other, sig, key) {
+		t.Error("signature accepted for a different UID")
+	}
+	nxp, _ := parseSigKey(nxpNTAG21xKey)
+	if verifyOriginality(uid, sig, nxp) {
+		t.Error("signature accepted against the wrong key")
+	}
+
+	// Genuine NTAG21x UID/signature pairs (as used by the Proxmark3 key recovery self-test)
+	for _, v := range []struct{ uid, sig string }{
+		{"04E10CDA993C80", "8B76052EE42F5567BEB53238B3E3F9950707C0DCC956B5C5EFCFDB709B2D82B3"},
+		{"04DB0BDA993C80", "6048EFD9417CD10F6B7F1818D471A7FE5B46868D2EABDC6307A1E0AAE139D8D0"},
+	} {
+		uid, _ := hex.DecodeString(v.uid)
+		sig, _ := hex.DecodeString(v.sig)
+		if !verifyOriginality(uid, sig, nxp) {
+			t.Errorf("genuine NXP signature for %s rejected", v.uid)
+		}
+		uid[6] ^= 0x01
+		if verifyOriginality(uid, sig, nxp) {
+			t.Errorf("NXP signature for %s accepted for another UID", v.uid)
+		}
+	}
+
+	// The compressed form of the key must decode to the same point
+	compressed := append([]byte{0x02 | byte(key.y.Bit(0))}, key.x.FillBytes(make([]byte, 16))...)
+	got, err := parseSigKey(hex.EncodeToString(compressed))
+	if err != nil || got.y.Cmp(key.y) != 0 {
+		t.Errorf("compressed key: got %v, err %v", got, err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// errTagTimeout is returned by passThrough when the tag did not answer
+var errTagTimeout = errors.New("tag did not respond")
+
+// errTagRejected is returned by passThrough when the PN53x reports another
+// error status for the tag command, typically a NAK
+var errTagRejected = errors.New("tag command failed")
+
+// passThroughCache remembers per reader name whether raw tag commands can be
+// sent through it, so the probe runs only on the first connection
+var passThroughCache = map[string]bool{}
+
+// passThroughOK is set for the current connection from passThroughCache and
+// gates features that need native tag commands (GET_VERSION and similar)
+var passThroughOK bool
+
+// tagVersion is the GET_VERSION answer of the tag on the reader, nil when
+// the tag did not answer it (plain Ultralight, clones) or pass-through is off.
+// It describes the tag, so it is probed for every tag, while passThroughOK
+// comes from the per-reader cache.
+var tagVersion []byte
+
+// passThrough sends a native tag command through the reader's PN53x
+// InCommunicateThru pseudo-APDU (FF 00 00 00 Lc D4 42 <cmd>) and returns the
+// tag's answer. Readers that reject InCommunicateThru get the command again
+// through InDataExchange.
+func passThrough(card transceiver, cmd []byte) ([]byte, error) {
+	resp, err := pn53xExchange(card, []byte{0xD4, 0x42}, cmd)
+	if err == nil || errors.Is(err, errTagTimeout) {
+		return resp, err
+	}
+	if resp, dxErr := inDataExchange(card, cmd); dxErr == nil {
+		return resp, nil
+	}
+	return nil, err
+}
+
+// inDataExchange sends a native tag command to target 1 with the PN53x
+// InDataExchange command (FF 00 00 00 Lc D4 40 01 <cmd>), the form ACR122U
+// readers use for GET_VERSION, FAST_READ and the like, and returns the tag's
+// answer without the D5 41 <status> framing
+func inDataExchange(card transceiver, cmd []byte) ([]byte, error) {
+	return pn53xExchange(card, []byte{0xD4, 0x40, 0x01}, cmd)
+}
+
+// pn53xExchange sends the PN53x command pn followed by the tag command cmd in
+// a direct transmit pseudo-APDU, checks the D5 <code+1> response header and
+// the status byte, and returns the data after the status
+func pn53xExchange(card transceiver, pn, cmd []byte) ([]byte, error) {
+	apdu := append([]byte{0xFF, 0x00, 0x00, 0x00, byte(len(pn) + len(cmd))}, pn...)
+	resp, err := transmit(card, append(apdu, cmd...))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 3 || resp[0] != 0xD5 || resp[1] != pn[1]+1 {
+		return nil, fmt.Errorf("unexpected pass-through response: % X", resp)
+	}
+	switch resp[2] & 0x3F { // Bit 6 (MI) only flags more data to come
+	case 0x00:
+		return resp[3:], nil
+	case 0x01: // PN53x status 01: timeout
+		return nil, errTagTimeout
+	default:
+		return nil, fmt.Errorf("%w, PN53x status %02X", errTagRejected, resp[2])
+	}
+}
+
+// tagNAKed reports whether a pass-through error came from the tag rather than
+// the reader: the PN53x framing was fine but the tag timed out or refused
+func tagNAKed(err error) bool {
+	return errors.Is(err, errTagTimeout) || errors.Is(err, errTagRejected)
+}
+
+// reselectTag re-activates the tag after it NAKed a native command. NTAG and
+// Ultralight drop to IDLE on a NAK and ignore every command until they are
+// selected again, which reconnecting with a card reset does.
+func reselectTag(card transceiver) {
+	resetter, ok := card.(fieldResetter)
+	if !ok {
+		return
+	}
+	err := resetter.Reconnect(scard.ShareExclusive, scard.ProtocolAny, scard.ResetCard)
+	if errors.Is(err, scard.ErrSharingViolation) {
+		err = resetter.Reconnect(scard.ShareShared, scard.ProtocolAny, scard.ResetCard)
+	}
+	if err != nil {
+		logger.Debugf("Re-selecting the tag after a NAK failed: %v", describePCSCError(err))
+	}
+}
+
+// errVersionLength is returned by getVersion for an answer that isn't 8 bytes
+var errVersionLength = errors.New("GET_VERSION answer is not 8 bytes")
+
+// getVersion sends the NTAG/Ultralight GET_VERSION command (0x60)
+func getVersion(card transceiver) ([]byte, error) {
+	resp, err := passThrough(card, []byte{0x60})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) != 8 {
+		return nil, fmt.Errorf("%w: %d bytes", errVersionLength, len(resp))
+	}
+	return resp, nil
+}
+
+// checkPassThrough sends a benign GET_VERSION to the tag on a new connection.
+// Whether the reader passes native commands through is judged by the PN53x
+// framing of the answer, not by whether the tag supports GET_VERSION: a
+// D5 43/D5 41 reply with an error status still proves pass-through works.
+// That reader result is cached per reader; the tag's own answer is returned
+// as its version, nil when it has none. A tag that NAKed is re-selected.
+func checkPassThrough(card transceiver, reader string) (readerOK bool, version []byte) {
+	ok, cached := passThroughCache[reader]
+	if cached && !ok {
+		return false, nil
+	}
+	v, err := getVersion(card)
+	if tagNAKed(err) {
+		reselectTag(card)
+	}
+	if !cached {
+		// A wrong-length answer still came back with good framing
+		ok = err == nil || tagNAKed(err) || errors.Is(err, errVersionLength)
+		passThroughCache[reader] = ok
+		if ok {
+			logger.Infof("🔌 Reader supports tag pass-through (native commands enabled)")
+		} else {
+			logger.Warnf("🔌 Reader does not support tag pass-through (%v)", err)
+			logger.Warnf("   GET_VERSION and other native tag commands are disabled; using FF-family APDUs only")
+		}
+	}
+	if !ok || err != nil {
+		if ok {
+			logger.Debugf("Tag did not answer GET_VERSION: %v", err)
+		}
+		return ok, nil
+	}
+	return true, v
+}
+
+// printVersionInfo decodes the GET_VERSION response of the tag on the reader
+func printVersionInfo() {
+	fmt.Printf("\n=== GET_VERSION ===\n")
+	v := tagVersion
+	if v == nil {
+		fmt.Printf("⚠️  Tag did not answer GET_VERSION (plain Ultralight or not an NXP tag)\n")
+		return
+	}
+	fmt.Printf("Response: % X\n", v)
+	fmt.Printf("  Vendor: %02X", v[1])
+	if v[1] == 0x04 {
+		fmt.Printf(" (NXP)")
+	}
+	fmt.Printf("\n  Product type: %02X, subtype: %02X\n", v[2], v[3])
+	fmt.Printf("  Version: %d.%d\n", v[4], v[5])
+	// Storage size byte: bits 7-1 give n, bit 0 set means between 2^n and 2^(n+1)
+	n := int(v[6] >> 1)
+	if v[6]&0x01 != 0 {
+		fmt.Printf("  Storage size: %02X (between %d and %d bytes)\n", v[6], 1<<n, 1<<(n+1))
+	} else {
+		fmt.Printf("  Storage size: %02X (%d bytes)\n", v[6], 1<<n)
+	}
+	if v[2] == 0x04 {
+		switch v[6] {
+		case 0x0F:
+			fmt.Printf("  Product: NTAG213\n")
+		case 0x11:
+			fmt.Printf("  Product: NTAG215\n")
+		case 0x13:
+			fmt.Printf("  Product: NTAG216\n")
+		}
+	}
+}
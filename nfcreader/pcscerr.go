@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// pcscHints maps common PC/SC error codes to a plain explanation with a remediation hint
+var pcscHints = map[scard.Error]string{
+	scard.ErrNoService:          "PC/SC service is not running (start pcscd on Linux or the Smart Card service on Windows)",
+	scard.ErrServiceStopped:     "PC/SC service stopped (restart pcscd or the Smart Card service)",
+	scard.ErrNoReadersAvailable: "no NFC reader connected (plug in the reader and check its driver)",
+	scard.ErrUnknownReader:      "reader not found (it may have been unplugged)",
+	scard.ErrReaderUnavailable:  "reader unavailable (it may have been unplugged or is resetting)",
+	scard.ErrNoSmartcard:        "no tag on the reader (place a tag and try again)",
+	scard.ErrRemovedCard:        "tag was removed during the operation (hold it steady on the reader)",
+	scard.ErrResetCard:          "tag was reset by another application (try again)",
+	scard.ErrUnresponsiveCard:   "tag does not respond (reposition it or try another tag)",
+	scard.ErrUnpoweredCard:      "tag is not powered (reposition it on the reader)",
+	scard.ErrUnsupportedCard:    "tag type is not supported by the reader",
+	scard.ErrSharingViolation:   "tag is in use by another application that holds it exclusively (close other NFC tools or stop nfc-uid-service, then try again)",
+	scard.ErrTimeout:            "timed out waiting for the reader",
+	scard.ErrCommError:          "communication error with the reader (check the USB connection)",
+	scard.ErrCommDataLost:       "communication with the reader was interrupted (check the USB connection)",
+	scard.ErrNotReady:           "reader is not ready (wait a moment and try again)",
+}
+
+// describePCSCError prefixes a PC/SC error with a human-readable explanation
+// and remediation hint, keeping the original error wrapped for errors.Is/As
+func describePCSCError(err error) error {
+	var code scard.Error
+	if !errors.As(err, &code) {
+		return err
+	}
+	hint, ok := pcscHints[code]
+	if !ok {
+		return err
+	}
+	return fmt.Errorf("%s: %w", hint, err)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// phoneURI is a decoded tel:, sms: or smsto: URI
+type phoneURI struct {
+	Scheme string // "tel", "sms" or "smsto"
+	Raw    string // Number as written in the URI
+	Number string // Number with visual separators removed
+	Body   string // SMS message from ?body=, unescaped
+	Err    error  // Why the number is not a valid dialable number
+}
+
+// phoneVisualSeparators are the RFC 3966 visual separators plus spaces
+const phoneVisualSeparators = " -.()/"
+
+// parsePhoneURI splits a tel:/sms:/smsto: URI into its number and, for SMS,
+// the message body. ok is false for other schemes.
+func parsePhoneURI(uri string) (phoneURI, bool) {
+	scheme, rest, found := strings.Cut(uri, ":")
+	scheme = strings.ToLower(scheme)
+	if !found || (scheme != "tel" && scheme != "sms" && scheme != "smsto") {
+		return phoneURI{}, false
+	}
+	p := phoneURI{Scheme: scheme}
+
+	if scheme != "tel" {
+		var query string
+		rest, query, _ = strings.Cut(rest, "?")
+		if values, err := url.ParseQuery(query); err == nil {
+			p.Body = values.Get("body")
+		} else {
+			p.Err = fmt.Errorf("bad query %q: %v", query, err)
+		}
+		if scheme == "smsto" && p.Body == "" {
+			// smsto:<number>:<message> is the common Android form
+			if number, msg, ok := strings.Cut(rest, ":"); ok {
+				rest, p.Body = number, msg
+			}
+		}
+	}
+	rest, _, _ = strings.Cut(rest, ";") // tel: parameters such as ;ext=
+	if unescaped, err := url.PathUnescape(rest); err == nil {
+		rest = unescaped
+	}
+	p.Raw = rest
+
+	var b strings.Builder
+	for _, c := range rest {
+		if !strings.ContainsRune(phoneVisualSeparators, c) {
+			b.WriteRune(c)
+		}
+	}
+	p.Number = b.String()
+	if p.Err == nil {
+		p.Err = validatePhoneNumber(p.Number)
+	}
+	return p, true
+}
+
+// validatePhoneNumber checks for an optional leading + followed by 3-15
+// digits (E.164 allows at most 15); * and # are allowed for service codes
+func validatePhoneNumber(number string) error {
+	digits := strings.TrimPrefix(number, "+")
+	if digits == "" {
+		return fmt.Errorf("no number")
+	}
+	count := 0
+	for _, c := range digits {
+		switch {
+		case c >= '0' && c <= '9':
+			count++
+		case c == '*' || c == '#':
+		default:
+			return fmt.Errorf("invalid character %q in number", c)
+		}
+	}
+	if count < 3 || count > 15 {
+		return fmt.Errorf("%d digits, expected 3-15", count)
+	}
+	return nil
+}
+
+// printPhoneURI shows the normalized number (and SMS body) of a tel:/sms: URI
+func printPhoneURI(p phoneURI) {
+	label := "📞 Phone"
+	if p.Scheme != "tel" {
+		label = "💬 SMS to"
+	}
+	fmt.Printf("        %s: %s", label, p.Number)
+	if p.Raw != p.Number {
+		fmt.Printf(" (written as %q)", p.Raw)
+	}
+	fmt.Printf("\n")
+	if p.Scheme != "tel" {
+		if p.Body != "" {
+			fmt.Printf("        Message: %q\n", p.Body)
+		} else {
+			fmt.Printf("        Message: (none)\n")
+		}
+	}
+	if p.Err != nil {
+		fmt.Printf("        ⚠️  Invalid phone number: %v\n", p.Err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParsePhoneURI(t *testing.T) {
+	tests := []struct {
+		uri          string
+		number, body string
+		valid        bool
+	}{
+		{"tel:+1 (555) 123-4567", "+15551234567", "", true},
+		{"tel:+31.20.555.0100;ext=12", "+31205550100", "", true},
+		{"tel:%2B44%2020%207946%200000", "+442079460000", "", true},
+		{"tel:*#06#", "*#06#", "", false},
+		{"sms:+15551234567?body=Hello%20there", "+15551234567", "Hello there", true},
+		{"sms:555-0100", "5550100", "", true},
+		{"smsto:+15551234567:On my way", "+15551234567", "On my way", true},
+		{"tel:call-me", "callme", "", false},
+	}
+	for _, tt := range tests {
+		p, ok := parsePhoneURI(tt.uri)
+		if !ok {
+			t.Errorf("%s: not recognized", tt.uri)
+			continue
+		}
+		if p.Number != tt.number || p.Body != tt.body || (p.Err == nil) != tt.valid {
+			t.Errorf("%s: got number %q body %q err %v", tt.uri, p.Number, p.Body, p.Err)
+		}
+	}
+
+	if _, ok := parsePhoneURI("https://example.com"); ok {
+		t.Error("https URI recognized as a phone URI")
+	}
+}
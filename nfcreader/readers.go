@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebfe/scard"
+)
+
+// readerCardStatus describes whether a card is present on each reader
+func readerCardStatus(pcsc *scard.Context, readers []string) []string {
+	rs := make([]scard.ReaderState, len(readers))
+	for i, r := range readers {
+		rs[i] = scard.ReaderState{Reader: r, CurrentState: scard.StateUnaware}
+	}
+	status := make([]string, len(readers))
+	if err := pcsc.GetStatusChange(rs, 0); err != nil && err != scard.ErrTimeout {
+		for i := range status {
+			status[i] = "status unknown"
+		}
+		return status
+	}
+	for i, r := range rs {
+		switch {
+		case r.EventState&scard.StatePresent != 0:
+			status[i] = "card present"
+		case r.EventState&(scard.StateUnavailable|scard.StateUnknown) != 0:
+			status[i] = "unavailable"
+		default:
+			status[i] = "empty"
+		}
+	}
+	return status
+}
+
+// printReaderList prints each attached reader with its index and card status
+// on stdout, for -list-readers, and returns the exit code: 0 when at least
+// one reader is attached, 1 otherwise
+func printReaderList(pcsc *scard.Context) int {
+	readers, err := pcsc.ListReaders()
+	if err != nil && err != scard.ErrNoReadersAvailable {
+		logger.Errorf("pcsc ListReaders: %v", describePCSCError(err))
+		return 1
+	}
+	if len(readers) == 0 {
+		logger.Errorf("no PC/SC readers found")
+		return 1
+	}
+	status := readerCardStatus(pcsc, readers)
+	for i, r := range readers {
+		fmt.Printf("[%d] %s (%s)\n", i, r, status[i])
+	}
+	return 0
+}
+
+// pickReader lists the readers with their card status on stderr and reads a choice from in
+func pickReader(pcsc *scard.Context, readers []string, in io.Reader) (string, error) {
+	status := readerCardStatus(pcsc, readers)
+	fmt.Fprintf(os.Stderr, "📱 Available readers:\n")
+	for i, r := range readers {
+		fmt.Fprintf(os.Stderr, "   [%d] %s (%s)\n", i, r, status[i])
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(os.Stderr, "Select reader [0-%d]: ", len(readers)-1)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil || n < 0 || n >= len(readers) {
+			fmt.Fprintf(os.Stderr, "❌ Invalid choice, enter a number between 0 and %d\n", len(readers)-1)
+			continue
+		}
+		return readers[n], nil
+	}
+}
+
+// readerPollInterval is how often -wait-for-reader checks for a new reader
+const readerPollInterval = time.Second
+
+// listReaders returns the attached readers. With wait set it polls until one
+// appears, ctx is cancelled or timeout (0 = no limit) passes, for services
+// that start before the reader is enumerated.
+func listReaders(ctx context.Context, pcsc *scard.Context, wait bool, timeout time.Duration) ([]string, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	logged := false
+	for {
+		readers, err := pcsc.ListReaders()
+		if err == scard.ErrNoReadersAvailable && wait {
+			err = nil // pcsc-lite reports "no readers" as an error
+		}
+		if err != nil || len(readers) > 0 || !wait {
+			return readers, err
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, fmt.Errorf("no PC/SC reader appeared within %v", timeout)
+		}
+		if !logged {
+			logger.Infof("⏳ Waiting for a PC/SC reader to be connected...")
+			logged = true
+		}
+		if err := sleepContext(ctx, readerPollInterval); err != nil {
+			return nil, err
+		}
+	}
+}
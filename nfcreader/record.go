@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ndefRecord is one decoded NDEF record with its header flags and raw fields
+type ndefRecord struct {
+	Index   int  // 1-based position within the message
+	MB      bool // Message Begin
+	ME      bool // Message End
+	CF      bool // Chunk Flag
+	SR      bool // Short Record
+	IL      bool // ID Length present
+	TNF     byte // Type Name Format
+	Type    []byte
+	ID      []byte
+	Payload []byte
+	Size    int // Encoded length of the whole record in bytes
+}
+
+// recordKinds lists the names returned by ndefRecord.Kind
+var recordKinds = []string{
+	"empty", "uri", "text", "smartposter", "wellknown", "media",
+	"absolute-uri", "external", "unknown", "unchanged", "reserved",
+}
+
+// decodeNDEFRecords splits an NDEF message into records. On malformed input it
+// returns the records decoded so far together with an error describing the problem.
+func decodeNDEFRecords(data []byte) ([]ndefRecord, error) {
+	var records []ndefRecord
+	offset := 0
+
+	for offset < len(data) {
+		start := offset
+		header := data[offset]
+		rec := ndefRecord{
+			Index: len(records) + 1,
+			MB:    header&0x80 != 0,
+			ME:    header&0x40 != 0,
+			CF:    header&0x20 != 0,
+			SR:    header&0x10 != 0,
+			IL:    header&0x08 != 0,
+			TNF:   header & 0x07,
+		}
+		offset++
+
+		if offset >= len(data) {
+			return records, fmt.Errorf("record %d: missing type length", rec.Index)
+		}
+		typeLength := int(data[offset])
+		offset++
+
+		var payloadLength int
+		if rec.SR {
+			if offset >= len(data) {
+				return records, fmt.Errorf("record %d: missing payload length", rec.Index)
+			}
+			payloadLength = int(data[offset])
+			offset++
+		} else {
+			if offset+4 > len(data) {
+				return records, fmt.Errorf("record %d: missing payload length", rec.Index)
+			}
+			payloadLength = int(data[offset])<<24 | int(data[offset+1])<<16 |
+				int(data[offset+2])<<8 | int(data[offset+3])
+			offset += 4
+		}
+
+		idLength := 0
+		if rec.IL {
+			if offset >= len(data) {
+				return records, fmt.Errorf("record %d: missing ID length", rec.Index)
+			}
+			idLength = int(data[offset])
+			offset++
+		}
+
+		if offset+typeLength > len(data) {
+			return records, fmt.Errorf("record %d: type length (%d) exceeds remaining data", rec.Index, typeLength)
+		}
+		rec.Type = data[offset : offset+typeLength]
+		offset += typeLength
+
+		if offset+idLength > len(data) {
+			return records, fmt.Errorf("record %d: ID length (%d) exceeds remaining data", rec.Index, idLength)
+		}
+		rec.ID = data[offset : offset+idLength]
+		offset += idLength
+
+		if payloadLength < 0 || offset+payloadLength > len(data) {
+			return records, fmt.Errorf("record %d: payload length (%d) exceeds remaining data (%d bytes)",
+				rec.Index, payloadLength, len(data)-offset)
+		}
+		rec.Payload = data[offset : offset+payloadLength]
+		offset += payloadLength
+		rec.Size = offset - start
+
+		records = append(records, rec)
+		if rec.ME {
+			return records, nil
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("empty NDEF message")
+	}
+	return records, errors.New("message ended without ME flag")
+}
+
+// Kind returns a short lowercase name for the record type, used for
+// file names and filtering
+func (r ndefRecord) Kind() string {
+	switch r.TNF {
+	case 0x00:
+		return "empty"
+	case 0x01:
+		switch string(r.Type) {
+		case "U":
+			return "uri"
+		case "T":
+			return "text"
+		case "Sp":
+			return "smartposter"
+		default:
+			return "wellknown"
+		}
+	case 0x02:
+		return "media"
+	case 0x03:
+		return "absolute-uri"
+	case 0x04:
+		return "external"
+	case 0x06:
+		return "unchanged"
+	case 0x07:
+		return "reserved"
+	default:
+		return "unknown"
+	}
+}
+
+// tnfHasType reports whether records of a TNF carry a type field: only
+// Well-known, Media, Absolute URI and External types do
+func tnfHasType(tnf byte) bool {
+	return tnf >= 0x01 && tnf <= 0x04
+}
+
+// recordTNFProblem checks the field lengths a record's TNF allows (NDEF
+// spec 3.3): Empty records have no type, ID or payload, Unknown, Unchanged
+// and Reserved records have no type, and the others need one
+func recordTNFProblem(tnf byte, typeLength, idLength, payloadLength int) string {
+	switch {
+	case tnf == 0x00:
+		if typeLength == 0 && idLength == 0 && payloadLength == 0 {
+			return ""
+		}
+		return fmt.Sprintf("empty record (TNF 0) must have zero lengths, has type %d, ID %d, payload %d",
+			typeLength, idLength, payloadLength)
+	case tnfHasType(tnf):
+		if typeLength == 0 {
+			return fmt.Sprintf("%s record (TNF %d) must have a type, type length is 0", getTNFDescription(tnf), tnf)
+		}
+	case typeLength != 0:
+		return fmt.Sprintf("%s record (TNF %d) must have type length 0, has %d", getTNFDescription(tnf), tnf, typeLength)
+	}
+	return ""
+}
+
+// aarType is the external type of an Android Application Record
+const aarType = "android.com:pkg"
+
+// playStoreURL returns the Google Play page of an Android package, where an
+// AAR sends phones that don't have the app
+func playStoreURL(pkg string) string {
+	return "https://play.google.com/store/apps/details?id=" + url.QueryEscape(pkg)
+}
+
+// formatRecordID renders a record ID for display: printable IDs as a quoted
+// string with their bytes, anything else as hex only
+func formatRecordID(id []byte) string {
+	for _, b := range id {
+		if b < 0x20 || b > 0x7E {
+			return fmt.Sprintf("% X (binary, %d bytes)", id, len(id))
+		}
+	}
+	return fmt.Sprintf("%q (% X)", id, id)
+}
+
+// parseRecordKinds parses a comma separated list of record kinds into a set
+func parseRecordKinds(list string) (map[string]bool, error) {
+	kinds := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, k := range recordKinds {
+			if k == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown record type %q (use: %s)", name, strings.Join(recordKinds, ", "))
+		}
+		kinds[name] = true
+	}
+	if len(kinds) == 0 {
+		return nil, errors.New("no record types given")
+	}
+	return kinds, nil
+}
+
+// fileExtension guesses a file extension for the record's payload from its TNF and type
+func (r ndefRecord) fileExtension() string {
+	switch r.Kind() {
+	case "uri", "text", "absolute-uri":
+		return r.Kind() + ".txt"
+	case "media":
+		if isImageType(string(r.Type)) {
+			return mediaExtension(string(r.Type))
+		}
+		if strings.HasPrefix(strings.ToLower(string(r.Type)), "text/") {
+			return "media.txt"
+		}
+		return "media.bin"
+	default:
+		return r.Kind() + ".bin"
+	}
+}
+
+// extractRecords writes each record's raw payload to dir/record-N.<ext>
+func extractRecords(data []byte, dir string) {
+	records, err := decodeNDEFRecords(data)
+	if err != nil {
+		fmt.Printf("  ⚠️  Extract: %v\n", err)
+	}
+	for _, rec := range records {
+		name := fmt.Sprintf("record-%d.%s", rec.Index, rec.fileExtension())
+		path, err := savePayload(dir, name, rec.Payload)
+		if err != nil {
+			fmt.Printf("  ❌ Failed to extract record %d: %v\n", rec.Index, err)
+			continue
+		}
+		fmt.Printf("  💾 Extracted record %d (%s, %d bytes) to %s\n", rec.Index, rec.Kind(), len(rec.Payload), path)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// readPageSnapshot reads pages 0..maxPage one by one, leaving nil for pages that fail
+func readPageSnapshot(card transceiver, maxPage byte) [][]byte {
+	pages := make([][]byte, int(maxPage)+1)
+	for page := range pages {
+		if data, err := readPage(card, byte(page)); err == nil {
+			pages[page] = data
+		}
+	}
+	return pages
+}
+
+// checkReadConsistency reads the full memory twice and flags every page whose
+// contents differ between the reads (or that only one read returned) as unreliable
+func checkReadConsistency(card transceiver, maxPage byte) {
+	fmt.Printf("\n=== DOUBLE READ CONSISTENCY CHECK ===\n")
+
+	first := readPageSnapshot(card, maxPage)
+	second := readPageSnapshot(card, maxPage)
+
+	var unreliable []string
+	unreadable := 0
+	for page := range first {
+		a, b := first[page], second[page]
+		switch {
+		case a == nil && b == nil:
+			unreadable++
+		case a == nil || b == nil:
+			fmt.Printf("Page %02X: ⚠️  read only once (1st: % X / 2nd: % X)\n", page, a, b)
+			unreliable = append(unreliable, fmt.Sprintf("%02X", page))
+		case !bytes.Equal(a, b):
+			fmt.Printf("Page %02X: ⚠️  mismatch (1st: % X / 2nd: % X)\n", page, a, b)
+			unreliable = append(unreliable, fmt.Sprintf("%02X", page))
+		}
+	}
+
+	if unreadable > 0 {
+		fmt.Printf("(%d page(s) unreadable in both reads, not compared)\n", unreadable)
+	}
+	if len(unreliable) == 0 {
+		fmt.Printf("✅ All readable pages matched across both reads\n")
+		return
+	}
+	fmt.Printf("❌ %d unreliable page(s): %s\n", len(unreliable), strings.Join(unreliable, ", "))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// smartPosterAction returns the meaning of a Smart Poster "act" record code
+func smartPosterAction(code byte) string {
+	switch code {
+	case 0x00:
+		return "Do (perform the action: open, call, send)"
+	case 0x01:
+		return "Save (store for later)"
+	case 0x02:
+		return "Open (open for editing)"
+	default:
+		return fmt.Sprintf("Unknown action 0x%02X", code)
+	}
+}
+
+// parseSmartPosterPayload decodes the nested NDEF message of a Smart Poster
+// ("Sp") record: URI, Title, Action ("act"), Size ("s"), Type ("t") and icons
+func parseSmartPosterPayload(payload []byte) {
+	fmt.Printf("        📰 Smart Poster\n")
+
+	records, err := decodeNDEFRecords(payload)
+	for _, rec := range records {
+		switch {
+		case rec.TNF == 0x01 && string(rec.Type) == "U":
+			parseURIPayload(rec.Payload)
+		case rec.TNF == 0x01 && string(rec.Type) == "T":
+			fmt.Printf("        🏷️  Title record\n")
+			parseTextPayload(rec.Payload)
+		case rec.TNF == 0x01 && string(rec.Type) == "act":
+			if len(rec.Payload) != 1 {
+				fmt.Printf("        ❌ Action record must be 1 byte, got %d: % X\n", len(rec.Payload), rec.Payload)
+				continue
+			}
+			fmt.Printf("        ▶️  Action: %s\n", smartPosterAction(rec.Payload[0]))
+		case rec.TNF == 0x01 && string(rec.Type) == "s":
+			if len(rec.Payload) != 4 {
+				fmt.Printf("        ❌ Size record must be 4 bytes, got %d: % X\n", len(rec.Payload), rec.Payload)
+				continue
+			}
+			fmt.Printf("        📏 Size: %d bytes (referenced object)\n", binary.BigEndian.Uint32(rec.Payload))
+		case rec.TNF == 0x01 && string(rec.Type) == "t":
+			fmt.Printf("        📄 Type: %s (referenced object MIME type)\n", string(rec.Payload))
+		case rec.TNF == 0x02 && isImageType(string(rec.Type)):
+			fmt.Printf("        Icon:\n")
+			summarizeImagePayload(string(rec.Type), rec.Payload, rec.Index)
+		default:
+			fmt.Printf("        🔍 Sub-record %d: TNF %d (%s), type %q, %d byte payload\n",
+				rec.Index, rec.TNF, getTNFDescription(rec.TNF), rec.Type, len(rec.Payload))
+		}
+	}
+	if err != nil {
+		fmt.Printf("        ❌ Smart Poster: %v\n", err)
+	}
+}
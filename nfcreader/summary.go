@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"time"
+)
+
+// tagSummary is the outcome of one full read, one row of -csv-out
+type tagSummary struct {
+	Time      time.Time
+	UID       string // Hex, upper case
+	TagType   string
+	Capacity  int    // Usable NDEF bytes from the CC (or the type's standard size), 0 if unknown
+	FirstURI  string // First URI record, "" if none
+	FirstText string // First Text record, "" if none
+	Verdict   ndefVerdict
+	Records   []ndefRecord // Records of the NDEF message, as far as they decoded
+}
+
+// csvHeader names the columns written by csvRow
+var csvHeader = []string{"time", "uid", "tag_type", "capacity", "first_uri", "first_text", "verdict"}
+
+// verdictName returns a short machine-readable name for the NDEF verdict
+func verdictName(v ndefVerdict) string {
+	switch v {
+	case ndefEmpty:
+		return "empty"
+	case ndefPresent:
+		return "present"
+	default:
+		return "missing"
+	}
+}
+
+// addMessage fills the first URI and text from an NDEF message
+func (s *tagSummary) addMessage(msg []byte) {
+	records, _ := decodeNDEFRecords(msg) // Keep what decoded before an error
+	s.Records = records
+	for _, rec := range records {
+		switch rec.Kind() {
+		case "uri":
+			if s.FirstURI == "" && len(rec.Payload) > 0 && rec.Payload[0] <= 0x23 {
+				s.FirstURI = getURIPrefix(rec.Payload[0]) + string(rec.Payload[1:])
+			}
+		case "text":
+			if text, _, err := decodeTextPayload(rec.Payload); s.FirstText == "" && err == nil {
+				s.FirstText = text
+			}
+		}
+	}
+}
+
+// csvRow returns the summary as the columns of csvHeader
+func (s tagSummary) csvRow() []string {
+	return []string{
+		s.Time.Format(time.RFC3339),
+		s.UID,
+		s.TagType,
+		strconv.Itoa(s.Capacity),
+		s.FirstURI,
+		s.FirstText,
+		verdictName(s.Verdict),
+	}
+}
+
+// appendCSV appends the summary as a row to path, writing the header row
+// first when the file is new or empty
+func appendCSV(path string, s tagSummary) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w := csv.NewWriter(file)
+	if info.Size() == 0 {
+		w.Write(csvHeader)
+	}
+	w.Write(s.csvRow())
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTagSummaryCSV(t *testing.T) {
+	opts = Options{}
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	var summary tagSummary
+	var ok bool
+	captureStdout(t, func() { summary, ok = readFullTag(&memoryTag{pages: pages}) })
+	if !ok {
+		t.Fatal("readFullTag failed")
+	}
+
+	path := filepath.Join(t.TempDir(), "tags.csv")
+	for i := 0; i < 2; i++ {
+		if err := appendCSV(path, summary); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("want header plus 2 rows, got:\n%s", data)
+	}
+	_, row, _ := strings.Cut(lines[1], ",") // Drop the timestamp
+	if want := "04A1B2C3D4E5F6,NTAG216,872,https://dnd.qrand.me/r/04A1B2C3D4E5F6,,present"; row != want {
+		t.Errorf("row = %q, want %q", row, want)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// tailDebounce is how long a tag must stay off the reader before -tail logs
+// the same UID again, so a tag that briefly drops out of the field logs once
+const tailDebounce = 2 * time.Second
+
+// tailLog writes the -tail audit log, one line per tag presence
+type tailLog struct {
+	path    string
+	lastUID string    // UID of the last logged tag
+	removed time.Time // When the last logged tag left the reader
+}
+
+// tailLine formats one log line: RFC 3339 time, UID and tag type, tab separated
+func tailLine(t time.Time, uidHex, tagType string) string {
+	return fmt.Sprintf("%s\t%s\t%s\n", t.Format(time.RFC3339), uidHex, tagType)
+}
+
+// identifyTag returns the tag type without the full analysis, checking for
+// a Type 4 NDEF application when no Type 2 pages answer
+func identifyTag(card transceiver) string {
+	tagType := identifyTagType(card)
+	if tagType == "unknown" && selectNDEFApplication(card) == nil {
+		return "Type 4"
+	}
+	return tagType
+}
+
+// record logs the tag on the reader unless it is the last logged tag coming
+// back within tailDebounce
+func (t *tailLog) record(card transceiver) error {
+	uid, err := getUID(card)
+	if err != nil {
+		return fmt.Errorf("read UID: %w", err)
+	}
+	uidHex := strings.ToUpper(hex.EncodeToString(uid))
+	now := time.Now()
+	if uidHex == t.lastUID && now.Sub(t.removed) < tailDebounce {
+		logger.Debugf("UID %s back within %s, not logged again", uidHex, tailDebounce)
+		return nil
+	}
+
+	line := tailLine(now, uidHex, identifyTag(card))
+	if err := appendLine(t.path, line); err != nil {
+		return err
+	}
+	t.lastUID = uidHex
+	logger.Infof("📝 %s", strings.TrimSpace(line))
+	return nil
+}
+
+// markRemoved notes that the tag left the reader
+func (t *tailLog) markRemoved() {
+	t.removed = time.Now()
+}
+
+// appendLine appends line to path. The file is opened for every line, so
+// after an external rotation (rename, or copy and truncate) the next line
+// goes to the new file.
+func appendLine(path, line string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailLog(t *testing.T) {
+	_, pages := readHexFile(t, filepath.Join("testdata", "tags", "ntag216_url.hex"))
+	card := &memoryTag{pages: pages}
+	tail := &tailLog{path: filepath.Join(t.TempDir(), "audit.log")}
+
+	if err := tail.record(card); err != nil {
+		t.Fatal(err)
+	}
+	tail.markRemoved()
+	if err := tail.record(card); err != nil { // Back within tailDebounce: not logged
+		t.Fatal(err)
+	}
+	tail.removed = time.Now().Add(-tailDebounce)
+	if err := tail.record(card); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(tail.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got:\n%s", data)
+	}
+	_, rest, _ := strings.Cut(lines[0], "\t") // Drop the timestamp
+	if want := "04A1B2C3D4E5F6\tNTAG216"; rest != want {
+		t.Errorf("line = %q, want %q", rest, want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// pageTiming is the latency of one page read, including any alternative reads.
+// A page served from an earlier block or FAST_READ answer cost no transmit of
+// its own; the transmit's latency is on the page that started it.
+type pageTiming struct {
+	page   int
+	took   time.Duration
+	alt    bool // Needed readPageAlternative
+	ok     bool
+	cached bool
+}
+
+// readTimer collects per-page latencies for -timing. A nil *readTimer
+// records nothing, so callers don't need to check the flag.
+type readTimer struct {
+	start time.Time
+	pages []pageTiming
+}
+
+// newReadTimer returns a running timer, or nil when -timing is off
+func newReadTimer() *readTimer {
+	if !opts.Timing {
+		return nil
+	}
+	return &readTimer{start: time.Now()}
+}
+
+// record stores the latency of a page read started at start
+func (t *readTimer) record(page int, start time.Time, alt, ok bool) {
+	if t == nil {
+		return
+	}
+	t.pages = append(t.pages, pageTiming{page: page, took: time.Since(start), alt: alt, ok: ok})
+}
+
+// recordCached notes a page that came from an earlier multi-page read
+func (t *readTimer) recordCached(page int) {
+	if t == nil {
+		return
+	}
+	t.pages = append(t.pages, pageTiming{page: page, ok: true, cached: true})
+}
+
+// print reports each page's latency, flags pages well above the median and
+// prints the total analysis time
+func (t *readTimer) print() {
+	if t == nil {
+		return
+	}
+	fmt.Printf("\n=== READ TIMING ===\n")
+	var sorted []time.Duration
+	for _, p := range t.pages {
+		if !p.cached {
+			sorted = append(sorted, p.took)
+		}
+	}
+	if len(sorted) > 0 {
+		// Cached pages would drag the median to zero and flag every transmit
+		slices.Sort(sorted)
+		median := sorted[len(sorted)/2]
+
+		var total time.Duration
+		for _, p := range t.pages {
+			if p.cached {
+				fmt.Printf("Page %02d: %10s (from the previous read)\n", p.page, "-")
+				continue
+			}
+			total += p.took
+			note := ""
+			switch {
+			case !p.ok:
+				note = " ❌ failed"
+			case p.alt:
+				note = " ⚠️  alternative read"
+			}
+			if p.took > 2*median && p.took-median > time.Millisecond {
+				note += " 🐢 slow"
+			}
+			fmt.Printf("Page %02d: %10s%s\n", p.page, p.took.Round(time.Microsecond), note)
+		}
+		fmt.Printf("Pages read: %d in %d transmits, median %s per transmit, total %s\n", len(t.pages),
+			len(sorted), median.Round(time.Microsecond), total.Round(time.Microsecond))
+	}
+	fmt.Printf("⏱️  Analysis took %s\n", time.Since(t.start).Round(time.Millisecond))
+}
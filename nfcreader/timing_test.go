@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTimerCachedPages(t *testing.T) {
+	timer := &readTimer{start: time.Now()}
+	for block := 0; block < 3; block++ {
+		timer.record(block*4, time.Now().Add(-4*time.Millisecond), false, true)
+		for p := block*4 + 1; p < block*4+4; p++ {
+			timer.recordCached(p)
+		}
+	}
+	out := captureStdout(t, timer.print)
+	if strings.Contains(out, "slow") {
+		t.Errorf("block reads flagged slow against cached pages:\n%s", out)
+	}
+	if !strings.Contains(out, "Pages read: 12 in 3 transmits") {
+		t.Errorf("summary does not count transmits:\n%s", out)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import "fmt"
+
+// TLV types of the Type 2 data area (NFC Forum Type 2 Tag spec 2.3)
+const (
+	tlvNull          = 0x00
+	tlvLockControl   = 0x01
+	tlvMemoryControl = 0x02
+	tlvNDEF          = 0x03
+	tlvProprietary   = 0xFD
+	tlvTerminator    = 0xFE
+)
+
+// tlvLength reads the length field of the TLV at offset: one byte, or
+// FF followed by a 2-byte big-endian length. header is the size of type
+// plus length field; ok is false when the length field is cut off.
+func tlvLength(data []byte, offset int) (length, header int, ok bool) {
+	if offset+1 >= len(data) {
+		return 0, 0, false
+	}
+	if data[offset+1] != 0xFF {
+		return int(data[offset+1]), 2, true
+	}
+	if offset+3 >= len(data) {
+		return 0, 0, false
+	}
+	return int(data[offset+2])<<8 | int(data[offset+3]), 4, true
+}
+
+// tlvName returns the name shown for a TLV type
+func tlvName(tlvType byte) string {
+	switch tlvType {
+	case tlvLockControl:
+		return "Lock Control"
+	case tlvMemoryControl:
+		return "Memory Control"
+	case tlvNDEF:
+		return "NDEF Message"
+	case tlvProprietary:
+		return "Proprietary TLV"
+	default:
+		return "Unknown TLV, skipped by length"
+	}
+}
+
+// tlvAreaComplete reports whether data holds the whole TLV structure up to
+// its Terminator TLV, walking the TLVs by their lengths so a 0xFE byte
+// inside a value doesn't end the read early
+func tlvAreaComplete(data []byte) bool {
+	offset := 0
+	for offset < len(data) {
+		switch data[offset] {
+		case tlvNull:
+			offset++
+			continue
+		case tlvTerminator:
+			return true
+		case 0xFF:
+			return true // Not a TLV type: nothing parseable follows
+		}
+		length, header, ok := tlvLength(data, offset)
+		if !ok {
+			return false
+		}
+		offset += header + length
+	}
+	return false
+}
+
+// printControlTLV decodes a Lock or Memory Control TLV value: the position
+// of the reserved area in tag memory (page address and byte offset, in pages
+// of 2^BytesPerPage bytes), its size, and for locks the bytes each bit locks
+func printControlTLV(tlvType byte, value []byte) {
+	if len(value) != 3 {
+		fmt.Printf("  ⚠️  Control TLV value should be 3 bytes, got %d\n", len(value))
+		return
+	}
+	pageAddr, byteOffset := int(value[0]>>4), int(value[0]&0x0F)
+	bytesPerPage := 1 << (value[2] & 0x0F)
+	addr := pageAddr*bytesPerPage + byteOffset
+	size := int(value[1]) // 0 means 256
+	if size == 0 {
+		size = 256
+	}
+	if tlvType == tlvLockControl {
+		fmt.Printf("  Dynamic lock bits: %d bits at memory byte %d (page %02X, byte %d)\n", size, addr, addr/4, addr%4)
+		fmt.Printf("  Bytes locked per bit: %d\n", 1<<(value[2]>>4))
+		return
+	}
+	fmt.Printf("  Reserved memory: %d bytes at memory byte %d (page %02X, byte %d)\n", size, addr, addr/4, addr%4)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestTLVAreaComplete(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"terminator", []byte{0x03, 0x00, 0xFE, 0x00}, true},
+		{"FE inside a control TLV value", []byte{0x02, 0x03, 0xFE, 0x08, 0x02, 0x03}, false},
+		{"FE inside an NDEF value", []byte{0x03, 0x03, 0xD1, 0xFE, 0x00}, false},
+		{"3-byte length", []byte{0x03, 0xFF, 0x00, 0x01, 0xFE, 0xFE}, true},
+		{"length cut off", []byte{0x03, 0xFF, 0x00}, false},
+		{"blank", []byte{0x00, 0x00, 0x00, 0x00}, false},
+	}
+	for _, tt := range tests {
+		if got := tlvAreaComplete(tt.data); got != tt.want {
+			t.Errorf("%s: tlvAreaComplete(% X) = %v, want %v", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ebfe/scard"
+)
+
+// scriptedCard answers each Transmit with the next canned response
+type scriptedCard struct {
+	responses [][]byte
+	sent      [][]byte
+}
+
+func (s *scriptedCard) Transmit(cmd []byte) ([]byte, error) {
+	s.sent = append(s.sent, cmd)
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+func TestTransmitGetResponse(t *testing.T) {
+	card := &scriptedCard{responses: [][]byte{
+		{0x01, 0x02, 0x61, 0x02},
+		{0x03, 0x04, 0x90, 0x00},
+	}}
+	data, err := transmit(card, []byte{0x00, 0xB0, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("data % X, want 01 02 03 04", data)
+	}
+	if len(card.sent) != 2 || !bytes.Equal(card.sent[1], []byte{0x00, 0xC0, 0x00, 0x00, 0x02}) {
+		t.Errorf("sent % X, want a GET RESPONSE for 2 bytes", card.sent)
+	}
+}
+
+func TestTransmitSWAccept(t *testing.T) {
+	card := &scriptedCard{responses: [][]byte{{0xAA, 0x91, 0xAF}, {0x91, 0xAF}}}
+	data, sw, err := transmitSW(card, []byte{0x90, 0x60, 0x00, 0x00, 0x00}, 0x9100, 0x91AF)
+	if err != nil || sw != 0x91AF || !bytes.Equal(data, []byte{0xAA}) {
+		t.Errorf("got % X, SW %04X, %v", data, sw, err)
+	}
+	if _, err := transmit(card, []byte{0x90, 0xAF, 0x00, 0x00, 0x00}); err == nil {
+		t.Error("91AF accepted by plain transmit")
+	}
+}
+
+func TestPassThroughInDataExchangeFallback(t *testing.T) {
+	card := &scriptedCard{responses: [][]byte{
+		{0x6A, 0x81}, // InCommunicateThru not supported
+		{0xD5, 0x41, 0x00, 0x00, 0x04, 0x04, 0x90, 0x00}, // InDataExchange answer
+	}}
+	data, err := passThrough(card, []byte{0x60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte{0x00, 0x04, 0x04}) {
+		t.Errorf("data % X, want 00 04 04", data)
+	}
+	if want := []byte{0xFF, 0x00, 0x00, 0x00, 0x04, 0xD4, 0x40, 0x01, 0x60}; !bytes.Equal(card.sent[1], want) {
+		t.Errorf("fallback sent % X, want % X", card.sent[1], want)
+	}
+
+	// A tag timeout is the tag's answer, not a reader problem: no fallback
+	card = &scriptedCard{responses: [][]byte{{0xD5, 0x43, 0x01, 0x90, 0x00}}}
+	if _, err := passThrough(card, []byte{0x60}); err != errTagTimeout {
+		t.Errorf("err = %v, want errTagTimeout", err)
+	}
+}
+
+// reselectCard is a scriptedCard that counts re-selects
+type reselectCard struct {
+	scriptedCard
+	reconnects int
+}
+
+func (r *reselectCard) Reconnect(scard.ShareMode, scard.Protocol, scard.Disposition) error {
+	r.reconnects++
+	return nil
+}
+
+func TestCheckPassThrough(t *testing.T) {
+	passThroughCache = map[string]bool{}
+	defer func() { passThroughCache = map[string]bool{} }()
+
+	// A plain Ultralight NAKs GET_VERSION: the reader still passed it through
+	card := &reselectCard{scriptedCard: scriptedCard{responses: [][]byte{{0xD5, 0x43, 0x01, 0x90, 0x00}}}}
+	ok, version := checkPassThrough(card, "ACR122U")
+	if !ok || version != nil || card.reconnects != 1 {
+		t.Errorf("NAK: ok %v, version % X, %d reconnects; want true, none, 1", ok, version, card.reconnects)
+	}
+
+	// An NTAG on the same reader answers; the cached reader result is kept
+	v := []byte{0x00, 0x04, 0x04, 0x02, 0x01, 0x00, 0x13, 0x03}
+	card = &reselectCard{scriptedCard: scriptedCard{responses: [][]byte{append(append([]byte{0xD5, 0x43, 0x00}, v...), 0x90, 0x00)}}}
+	ok, version = checkPassThrough(card, "ACR122U")
+	if !ok || !bytes.Equal(version, v) || card.reconnects != 0 {
+		t.Errorf("NTAG: ok %v, version % X, %d reconnects", ok, version, card.reconnects)
+	}
+
+	// A reader that rejects both pseudo-APDUs has no pass-through, and is not asked again
+	card = &reselectCard{scriptedCard: scriptedCard{responses: [][]byte{{0x6A, 0x81}, {0x6A, 0x81}}}}
+	if ok, _ := checkPassThrough(card, "Other"); ok {
+		t.Error("reader without pass-through classified as capable")
+	}
+	if ok, _ := checkPassThrough(card, "Other"); ok || len(card.sent) != 2 {
+		t.Errorf("cached reader sent %d APDUs, want none after the first probe", len(card.sent)-2)
+	}
+}
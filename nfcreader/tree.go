@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// maxTreeDepth stops runaway recursion on nested messages that contain themselves
+const maxTreeDepth = 4
+
+// nestedMessageOffset returns where a record's payload holds a nested NDEF
+// message: Smart Poster at 0, handover records after their version byte
+func nestedMessageOffset(rec ndefRecord) (int, bool) {
+	if rec.TNF != 0x01 {
+		return 0, false
+	}
+	if string(rec.Type) == "Sp" {
+		return 0, true
+	}
+	if isHandoverType(string(rec.Type)) {
+		return 1, len(rec.Payload) > 1
+	}
+	return 0, false
+}
+
+// recordTitle is the one-line label of a record in the tree
+func recordTitle(rec ndefRecord) string {
+	name := rec.Kind()
+	switch {
+	case rec.TNF == 0x01 && string(rec.Type) == "Sp":
+		name = "Smart Poster"
+	case rec.TNF == 0x01 && strings.HasPrefix(string(rec.Type), "H") && len(rec.Type) == 2:
+		name = "Handover " + string(rec.Type)
+	case rec.TNF == 0x01:
+		name = fmt.Sprintf("%s %q", name, rec.Type)
+	case rec.TNF == 0x02, rec.TNF == 0x04:
+		name = fmt.Sprintf("%s %s", name, rec.Type)
+	}
+	return fmt.Sprintf("[%d] %s (TNF %d %s, %d byte payload)", rec.Index, name, rec.TNF, getTNFDescription(rec.TNF), len(rec.Payload))
+}
+
+// recordFlags lists the header flags that are set
+func recordFlags(rec ndefRecord) string {
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{{rec.MB, "MB"}, {rec.ME, "ME"}, {rec.CF, "CF"}, {rec.SR, "SR"}, {rec.IL, "IL"}} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// recordDetails returns the decoded content lines of a record
+func recordDetails(rec ndefRecord) []string {
+	details := []string{"Flags: " + recordFlags(rec)}
+	if len(rec.ID) > 0 {
+		details = append(details, "ID: "+formatRecordID(rec.ID))
+	}
+	switch {
+	case rec.TNF == 0x00:
+		details = append(details, "Empty record")
+	case rec.TNF == 0x01 && string(rec.Type) == "U" && len(rec.Payload) > 0:
+		details = append(details, "URI: "+getURIPrefix(rec.Payload[0])+string(rec.Payload[1:]))
+	case rec.TNF == 0x01 && string(rec.Type) == "T":
+		if text, lang, err := decodeTextPayload(rec.Payload); err == nil {
+			details = append(details, fmt.Sprintf("Text (%s): %q", lang, text))
+		} else {
+			details = append(details, "❌ "+err.Error())
+		}
+	case rec.TNF == 0x01 && string(rec.Type) == "act" && len(rec.Payload) == 1:
+		details = append(details, "Action: "+smartPosterAction(rec.Payload[0]))
+	case rec.TNF == 0x01 && string(rec.Type) == "s" && len(rec.Payload) == 4:
+		details = append(details, fmt.Sprintf("Size: %d bytes", binary.BigEndian.Uint32(rec.Payload)))
+	case rec.TNF == 0x01 && string(rec.Type) == "t":
+		details = append(details, "Type: "+string(rec.Payload))
+	case rec.TNF == 0x01 && string(rec.Type) == "ac":
+		if ac, err := parseAlternativeCarrier(rec.Payload); err == nil {
+			details = append(details, fmt.Sprintf("Carrier power state: %s (%d), carrier data reference %s",
+				carrierPowerStates[ac.PowerState], ac.PowerState, formatRecordID(ac.DataRef)))
+		} else {
+			details = append(details, "❌ "+err.Error())
+		}
+	case rec.TNF == 0x03:
+		details = append(details, "URI: "+string(rec.Type))
+	case rec.TNF == 0x04 && string(rec.Type) == aarType:
+		details = append(details, "Android app: "+string(rec.Payload), "Play Store: "+playStoreURL(string(rec.Payload)))
+	}
+	if off, ok := nestedMessageOffset(rec); ok && off == 1 {
+		details = append(details, fmt.Sprintf("Version: %d.%d", rec.Payload[0]>>4, rec.Payload[0]&0x0F))
+	}
+	if _, nested := nestedMessageOffset(rec); !nested && len(details) == 1 && len(rec.Payload) > 0 {
+		shown := rec.Payload
+		if len(shown) > 16 {
+			shown = shown[:16]
+		}
+		line := fmt.Sprintf("Payload: % X", shown)
+		if len(shown) < len(rec.Payload) {
+			line += " ..."
+		}
+		details = append(details, line)
+	}
+	if rec.TNF == 0x07 {
+		details = append(details, "Reserved TNF, handled as Unknown")
+	}
+	if problem := recordTNFProblem(rec.TNF, len(rec.Type), len(rec.ID), len(rec.Payload)); problem != "" {
+		details = append(details, "⚠️  Malformed: "+problem)
+	}
+	return details
+}
+
+// printRecordTree prints records as tree branches under prefix, descending
+// into Smart Poster and handover payloads
+func printRecordTree(records []ndefRecord, prefix string, depth int) {
+	for i, rec := range records {
+		last := i == len(records)-1
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Printf("%s%s%s\n", prefix, branch, recordTitle(rec))
+
+		details := recordDetails(rec)
+		if rec.TNF == 0x01 && isHandoverType(string(rec.Type)) {
+			details = append(details, handoverCarrierLines(rec, records)...)
+		}
+		off, nested := nestedMessageOffset(rec)
+		for j, line := range details {
+			leaf := "├── "
+			if j == len(details)-1 && !nested {
+				leaf = "└── "
+			}
+			fmt.Printf("%s%s%s%s\n", prefix, indent, leaf, line)
+		}
+		if !nested {
+			continue
+		}
+		sub, err := decodeNDEFRecords(rec.Payload[off:])
+		fmt.Printf("%s%s└── Nested message (%d record(s))\n", prefix, indent, len(sub))
+		if depth+1 >= maxTreeDepth {
+			fmt.Printf("%s%s    └── (nesting too deep, not shown)\n", prefix, indent)
+			continue
+		}
+		printRecordTree(sub, prefix+indent+"    ", depth+1)
+		if err != nil {
+			fmt.Printf("%s%s    ❌ %v\n", prefix, indent, err)
+		}
+	}
+}
+
+// printNDEFTree prints an NDEF message as an indented tree (-tree)
+func printNDEFTree(data []byte) {
+	records, err := decodeNDEFRecords(data)
+	shown := records
+	if opts.OnlyTypes != nil {
+		shown = nil
+		for _, rec := range records {
+			if opts.OnlyTypes[rec.Kind()] {
+				shown = append(shown, rec)
+			}
+		}
+	}
+
+	fmt.Printf("  NDEF Message (%d record(s), %d bytes)\n", len(records), len(data))
+	printRecordTree(shown, "  ", 0)
+	if hidden := len(records) - len(shown); hidden > 0 {
+		fmt.Printf("  (%d of %d record(s) hidden by -only-types)\n", hidden, len(records))
+	}
+	if err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+	}
+}
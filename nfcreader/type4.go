@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ndefAppAID is the NFC Forum Type 4 Tag NDEF application (mapping version 2.0)
+var ndefAppAID = []byte{0xD2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01}
+
+// type4CCFileID is the fixed file identifier of the Type 4 capability container
+const type4CCFileID = 0xE103
+
+// type4CC is a decoded Type 4 capability container with its NDEF File Control TLV
+type type4CC struct {
+	CCLen       int  // Size of the CC file
+	Version     byte // Mapping version, major in the high nibble
+	MLe         int  // Maximum R-APDU data size (READ BINARY)
+	MLc         int  // Maximum C-APDU data size (UPDATE BINARY)
+	FileID      uint16
+	MaxNDEFSize int  // Maximum NDEF file size, including the 2-byte NLEN
+	ReadAccess  byte // 00 = read allowed
+	WriteAccess byte // 00 = write allowed, FF = read-only
+}
+
+// parseType4CC decodes the CC file: CCLEN(2) version(1) MLe(2) MLc(2) and the
+// NDEF File Control TLV 04 06 fileID(2) maxSize(2) read(1) write(1)
+func parseType4CC(data []byte) (type4CC, error) {
+	var cc type4CC
+	if len(data) < 15 {
+		return cc, fmt.Errorf("CC file is %d bytes, need at least 15", len(data))
+	}
+	cc.CCLen = int(data[0])<<8 | int(data[1])
+	cc.Version = data[2]
+	cc.MLe = int(data[3])<<8 | int(data[4])
+	cc.MLc = int(data[5])<<8 | int(data[6])
+	if data[7] != 0x04 || data[8] != 0x06 {
+		return cc, fmt.Errorf("expected NDEF File Control TLV 04 06, got %02X %02X", data[7], data[8])
+	}
+	cc.FileID = uint16(data[9])<<8 | uint16(data[10])
+	cc.MaxNDEFSize = int(data[11])<<8 | int(data[12])
+	cc.ReadAccess = data[13]
+	cc.WriteAccess = data[14]
+	return cc, nil
+}
+
+// accessLabel describes a Type 4 read/write access condition byte
+func accessLabel(b byte) string {
+	switch {
+	case b == 0x00:
+		return "granted"
+	case b == 0xFF:
+		return "denied"
+	case b >= 0x80 && b <= 0xFE:
+		return "proprietary"
+	default:
+		return "RFU"
+	}
+}
+
+// printType4CC prints the CC fields the way the Type 2 CC page is decoded
+func printType4CC(cc type4CC) {
+	fmt.Printf("\n=== CAPABILITY CONTAINER FILE (E103) ===\n")
+	fmt.Printf("CCLEN: %04X (%d bytes)\n", cc.CCLen, cc.CCLen)
+	fmt.Printf("Mapping Version: %d.%d\n", cc.Version>>4, cc.Version&0x0F)
+	fmt.Printf("MLe: %04X (max %d bytes per READ BINARY)\n", cc.MLe, cc.MLe)
+	fmt.Printf("MLc: %04X (max %d bytes per UPDATE BINARY)\n", cc.MLc, cc.MLc)
+	fmt.Printf("NDEF File Control TLV:\n")
+	fmt.Printf("    File ID: %04X\n", cc.FileID)
+	fmt.Printf("    Max NDEF Size: %04X (%d bytes)\n", cc.MaxNDEFSize, cc.MaxNDEFSize)
+	fmt.Printf("    Read Access: %02X (%s)\n", cc.ReadAccess, accessLabel(cc.ReadAccess))
+	fmt.Printf("    Write Access: %02X (%s)\n", cc.WriteAccess, accessLabel(cc.WriteAccess))
+	if cc.Version>>4 != 2 && cc.Version>>4 != 3 {
+		fmt.Printf("    ⚠️  Unsupported mapping version\n")
+	}
+	if cc.MLe < 0x0F {
+		fmt.Printf("    ⚠️  MLe below the 15-byte minimum\n")
+	}
+}
+
+// selectNDEFApplication selects the NDEF application by AID
+func selectNDEFApplication(card transceiver) error {
+	apdu := append([]byte{0x00, 0xA4, 0x04, 0x00, byte(len(ndefAppAID))}, ndefAppAID...)
+	_, err := transmit(card, append(apdu, 0x00))
+	return err
+}
+
+// selectFile selects an elementary file by identifier within the current application
+func selectFile(card transceiver, id uint16) error {
+	_, err := transmit(card, []byte{0x00, 0xA4, 0x00, 0x0C, 0x02, byte(id >> 8), byte(id)})
+	return err
+}
+
+// readBinary reads n bytes of the selected file at offset
+func readBinary(card transceiver, offset int, n int) ([]byte, error) {
+	return transmit(card, []byte{0x00, 0xB0, byte(offset >> 8), byte(offset), byte(n)})
+}
+
+// readType4CC selects the NDEF application and reads and decodes its CC file
+func readType4CC(card transceiver) (type4CC, error) {
+	if err := selectNDEFApplication(card); err != nil {
+		return type4CC{}, fmt.Errorf("select NDEF application: %w", err)
+	}
+	if err := selectFile(card, type4CCFileID); err != nil {
+		return type4CC{}, fmt.Errorf("select CC file: %w", err)
+	}
+	data, err := readBinary(card, 0, 0x0F)
+	if err != nil {
+		return type4CC{}, fmt.Errorf("read CC file: %w", err)
+	}
+	return parseType4CC(data)
+}
+
+// readType4NDEF reads the NDEF file named in the CC, honouring MLe
+func readType4NDEF(card transceiver, cc type4CC) ([]byte, error) {
+	if err := selectFile(card, cc.FileID); err != nil {
+		return nil, fmt.Errorf("select NDEF file: %w", err)
+	}
+	nlen, err := readBinary(card, 0, 2)
+	if err != nil || len(nlen) < 2 {
+		return nil, fmt.Errorf("read NLEN: %v", err)
+	}
+	n := int(nlen[0])<<8 | int(nlen[1])
+	if n+2 > cc.MaxNDEFSize {
+		return nil, fmt.Errorf("NLEN %d exceeds the CC's max NDEF size %d", n, cc.MaxNDEFSize)
+	}
+	chunk := min(cc.MLe, 0xFF)
+	if chunk <= 0 {
+		return nil, errors.New("CC reports MLe of 0")
+	}
+	var msg []byte
+	for len(msg) < n {
+		data, err := readBinary(card, 2+len(msg), min(chunk, n-len(msg)))
+		if err != nil {
+			return msg, fmt.Errorf("read NDEF file at %d: %w", 2+len(msg), err)
+		}
+		if len(data) == 0 {
+			return msg, errors.New("empty READ BINARY response")
+		}
+		msg = append(msg, data...)
+	}
+	return msg[:n], nil
+}
+
+// analyzeType4Tag reports a Type 4 tag's CC file and NDEF message. It returns
+// false when the tag has no NDEF application so the caller can carry on.
+func analyzeType4Tag(card transceiver) (ndefVerdict, bool) {
+	cc, err := readType4CC(card)
+	if err != nil {
+		logger.Debugf("Type 4 probe: %v", err)
+		return ndefMissing, false
+	}
+	fmt.Printf("📋 Tag Type: Type 4 (ISO-DEP)\n")
+	printType4CC(cc)
+
+	fmt.Printf("\n=== NDEF FILE (%04X) ===\n", cc.FileID)
+	msg, err := readType4NDEF(card, cc)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return ndefMissing, true
+	}
+	if len(msg) == 0 {
+		fmt.Printf("NLEN: 0000\n")
+		return ndefEmpty, true
+	}
+	fmt.Printf("NLEN: %04X (%d bytes)\n", len(msg), len(msg))
+	parseNDEFMessage(msg)
+	return ndefPresent, true
+}
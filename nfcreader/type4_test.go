@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestParseType4CC(t *testing.T) {
+	// Typical NTAG 424 / DESFire EV2 NDEF CC: 15 bytes, v2.0, MLe 00FF, MLc 00FF,
+	// NDEF file E104 of 0x0100 bytes, read granted, write granted
+	cc, err := parseType4CC([]byte{0x00, 0x0F, 0x20, 0x00, 0xFF, 0x00, 0xFF,
+		0x04, 0x06, 0xE1, 0x04, 0x01, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := type4CC{CCLen: 15, Version: 0x20, MLe: 0xFF, MLc: 0xFF,
+		FileID: 0xE104, MaxNDEFSize: 0x100, ReadAccess: 0x00, WriteAccess: 0x00}
+	if cc != want {
+		t.Errorf("got %+v, want %+v", cc, want)
+	}
+
+	if _, err := parseType4CC([]byte{0x00, 0x0F, 0x20}); err == nil {
+		t.Error("short CC file: expected an error")
+	}
+	if _, err := parseType4CC([]byte{0x00, 0x0F, 0x20, 0x00, 0xFF, 0x00, 0xFF,
+		0x05, 0x06, 0xE1, 0x04, 0x01, 0x00, 0x00, 0x00}); err == nil {
+		t.Error("proprietary file control TLV: expected an error")
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// ultralightTagType is the tag type name for plain MIFARE Ultralight (16 pages)
+const ultralightTagType = "MIFARE Ultralight"
+
+// printOTPPage decodes page 3 of a plain Ultralight as its one-time
+// programmable area: 32 bits that can only ever be set, never cleared
+func printOTPPage(data []byte) {
+	var pattern []string
+	set := 0
+	for _, b := range data {
+		pattern = append(pattern, fmt.Sprintf("%08b", b))
+		set += bits.OnesCount8(b)
+	}
+	fmt.Printf("\n    OTP bits: %s", strings.Join(pattern, " "))
+	fmt.Printf("\n    OTP usage: %d/%d bits set, %d remaining (write-once, bits can only be set)", set, len(data)*8, len(data)*8-set)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// CapacityExceededError reports an NDEF message that does not fit the tag's
+// data area, so callers can pick a larger tag instead of failing mid-write
+type CapacityExceededError struct {
+	TagType   string
+	Needed    int // Bytes the NDEF Message TLV needs
+	Available int // Bytes in the data area
+}
+
+func (e *CapacityExceededError) Error() string {
+	return fmt.Sprintf("NDEF message needs %d bytes but the %s data area holds %d", e.Needed, e.TagType, e.Available)
+}
+
+// TagCapacity detects the tag type and returns the data area size in bytes
+// the tag will have once formatted: -cc-size, the type's factory size, or
+// for a Type2-compatible (non-NXP) tag the size in an existing CC, else the
+// legacy size. It errors when page 0 cannot be read to identify the tag
+func TagCapacity(card *scard.Card) (string, int, error) {
+	tagType := detectTagType(card)
+	if tagType == "unknown" {
+		return tagType, 0, fmt.Errorf("cannot identify the tag")
+	}
+	if opts.CCDataSize > 0 {
+		return tagType, opts.CCDataSize / 8 * 8, nil
+	}
+	if size, ok := ccDataSizes[tagType]; ok {
+		return tagType, int(size) * 8, nil
+	}
+	if cc, err := readPage(card, 0x03); err == nil && len(cc) >= 4 && cc[0] == 0xE1 && cc[2] != 0 {
+		return tagType, int(cc[2]) * 8, nil
+	}
+	return tagType, legacyCCDataSize * 8, nil
+}
+
+// checkCapacity returns a *CapacityExceededError when the NDEF Message TLV
+// for ndef (header and value; the terminator is optional on a full tag)
+// does not fit capacity bytes
+func checkCapacity(tagType string, capacity int, ndef []byte) error {
+	header := 2
+	if len(ndef) >= 0xFF {
+		header = 4
+	}
+	if needed := header + len(ndef); needed > capacity {
+		return &CapacityExceededError{TagType: tagType, Needed: needed, Available: capacity}
+	}
+	return nil
+}
+
+// WriteURITag formats the tag and writes a single URI record, refusing up
+// front with a *CapacityExceededError when the record does not fit
+func WriteURITag(card *scard.Card, uri string) error {
+	ndef := buildURIRecord(uri)
+	tagType, capacity, err := TagCapacity(card)
+	if err != nil {
+		return err
+	}
+	if err := checkCapacity(tagType, capacity, ndef); err != nil {
+		return err
+	}
+	if err := formatType2Tag(card); err != nil {
+		return fmt.Errorf("format: %w", err)
+	}
+	return writeNDEFToType2(card, ndef)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// batchRow is one line of a serialization CSV: a sequence number and the URL to write
+type batchRow struct {
+	Serial string
+	URL    string // May contain {uid}
+}
+
+// csvBatch hands out CSV rows one tag at a time. A row is only consumed
+// once its tag was written, so a failed tag is retried with the same row.
+type csvBatch struct {
+	rows []batchRow
+	next int
+	out  *csv.Writer
+	file *os.File
+}
+
+// loadCSVBatch reads "serial,url" rows from path. A first row whose URL
+// column reads "url" is treated as a header and skipped.
+func loadCSVBatch(path string) (*csvBatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &csvBatch{}
+	for i, rec := range records {
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: want serial,url but got %d column(s)", i+1, len(rec))
+		}
+		if i == 0 && strings.EqualFold(strings.TrimSpace(rec[1]), "url") {
+			continue
+		}
+		b.rows = append(b.rows, batchRow{Serial: strings.TrimSpace(rec[0]), URL: strings.TrimSpace(rec[1])})
+	}
+	if len(b.rows) == 0 {
+		return nil, errors.New("no rows to write")
+	}
+	return b, nil
+}
+
+// openOutput appends written rows to path as serial,url,uid,written_at,
+// writing the header when the file is new
+func (b *csvBatch) openOutput(path string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	b.file, b.out = f, csv.NewWriter(f)
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		b.out.Write([]string{"serial", "url", "uid", "written_at"})
+		b.out.Flush()
+	}
+	return b.out.Error()
+}
+
+// current returns the row for the next tag, false once every row is written
+func (b *csvBatch) current() (batchRow, bool) {
+	if b.next >= len(b.rows) {
+		return batchRow{}, false
+	}
+	return b.rows[b.next], true
+}
+
+// remaining returns how many rows are still to be written
+func (b *csvBatch) remaining() int {
+	return len(b.rows) - b.next
+}
+
+// advance records the tag written with the current row and moves to the next row
+func (b *csvBatch) advance(uid string) error {
+	row := b.rows[b.next]
+	b.next++
+	if b.out == nil {
+		return nil
+	}
+	url := strings.ReplaceAll(row.URL, "{uid}", uid)
+	b.out.Write([]string{row.Serial, url, uid, time.Now().Format(time.RFC3339)})
+	b.out.Flush()
+	return b.out.Error()
+}
+
+// close flushes and closes the output CSV, if any
+func (b *csvBatch) close() {
+	if b.file != nil {
+		b.out.Flush()
+		b.file.Close()
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// ccDataSizes is the data area size byte (in 8-byte units) of the CC for each
+// known tag type, as programmed by NXP at the factory
+var ccDataSizes = map[string]byte{
+	"MIFARE Ultralight":   0x06, // 48 bytes
+	"MIFARE Ultralight C": 0x12, // 144 bytes
+	"NTAG213":             0x12, // 144 bytes
+	"NTAG215":             0x3E, // 496 bytes
+	"NTAG216":             0x6D, // 872 bytes
+}
+
+// legacyCCDataSize is the size the writer used for every tag before the type
+// was detected; it is kept for tags whose capacity is unknown
+const legacyCCDataSize = 0x3F
+
+// detectTagType tells NTAG21x sizes and Ultralight apart by probing the page
+// just past each type's last page; other tags are "Type2-compatible". An
+// Ultralight C (pages 0x00-0x2F) reads past the NTAG213 end, so page 0x30 is
+// probed before it can be taken for an NTAG215 and get a 496-byte OTP CC.
+func detectTagType(card *scard.Card) string {
+	page0, err := readPage(card, 0x00)
+	if err != nil || len(page0) < 1 {
+		return "unknown"
+	}
+	if page0[0] != 0x04 { // NXP
+		return "Type2-compatible"
+	}
+	if _, err := readPage(card, 0x10); err != nil {
+		return "MIFARE Ultralight" // Pages 0x00-0x0F
+	}
+	if _, err := readPage(card, 0x2D); err != nil {
+		return "NTAG213" // Pages 0x00-0x2C
+	}
+	if _, err := readPage(card, 0x30); err != nil {
+		return "MIFARE Ultralight C" // Pages 0x00-0x2F
+	}
+	if _, err := readPage(card, 0x87); err != nil {
+		return "NTAG215" // Pages 0x00-0x86
+	}
+	return "NTAG216" // Pages 0x00-0xE6
+}
+
+// buildCC returns the capability container for a tag type: magic E1, mapping
+// version 1.0, the type's data area size and read/write access. Unknown types
+// get the legacy 504-byte size; use buildCCForSize when the size is known.
+func buildCC(tagType string) []byte {
+	size, ok := ccDataSizes[tagType]
+	if !ok {
+		size = legacyCCDataSize
+	}
+	return []byte{0xE1, 0x10, size, 0x00}
+}
+
+// buildCCForSize returns a read/write capability container declaring a data
+// area of dataBytes, rounded down to the CC's 8-byte granularity
+func buildCCForSize(dataBytes int) ([]byte, error) {
+	if dataBytes < 8 || dataBytes > 0xFF*8 {
+		return nil, fmt.Errorf("data area of %d bytes does not fit a Type 2 CC (8-%d)", dataBytes, 0xFF*8)
+	}
+	return []byte{0xE1, 0x10, byte(dataBytes / 8), 0x00}, nil
+}
+
+// writeCC writes page 3 with the CC for the presented tag and the given
+// access byte. The CC page is one-time programmable on NTAG, so bits can only
+// be set: a CC that already matches is left alone and one that can't be
+// reached is reported.
+func writeCC(card *scard.Card, access byte) error {
+	tagType := detectTagType(card)
+	var cc []byte
+	if opts.CCDataSize > 0 {
+		var err error
+		if cc, err = buildCCForSize(opts.CCDataSize); err != nil {
+			return err
+		}
+	} else {
+		if _, known := ccDataSizes[tagType]; !known {
+			logger.Warnf("Capacity of %s tag unknown, declaring %d bytes (set -cc-size)", tagType, legacyCCDataSize*8)
+		}
+		cc = buildCC(tagType)
+	}
+	cc[3] = access
+	logger.Debugf("Tag type %s, CC % X", tagType, cc)
+
+	if cur, err := readPage(card, 0x03); err == nil && len(cur) >= 4 {
+		if bytes.Equal(cur[:4], cc) {
+			return nil
+		}
+		if tagType != "Type2-compatible" && cur[0] == 0xE1 {
+			for i := range cc {
+				if cur[i]&^cc[i] != 0 {
+					logger.Warnf("CC is already % X and OTP bits can't be cleared, keeping it (wanted % X)", cur[:4], cc)
+					return nil
+				}
+			}
+		}
+	}
+	return writePageAlternative(card, 0x03, cc)
+}
+
+// setCCReadOnly sets the write access nibble of the CC (page 3 byte 3) to
+// 0xF, "no write access", and reads the CC back to confirm. The lock bytes
+// are left alone, so the memory stays writable for tools that ignore the CC;
+// on NTAG the CC is OTP, so the nibble itself can never be cleared again.
+func setCCReadOnly(card *scard.Card) error {
+	cur, err := readPage(card, 0x03)
+	if err != nil {
+		return fmt.Errorf("read CC: %w", err)
+	}
+	if len(cur) < 4 || cur[0] != 0xE1 {
+		return fmt.Errorf("tag has no NDEF capability container (page 3: % X)", cur)
+	}
+	cc := append([]byte(nil), cur[:4]...)
+	cc[3] |= 0x0F
+	if cc[3] != cur[3] {
+		if err := writePageAlternative(card, 0x03, cc); err != nil {
+			return fmt.Errorf("write CC: %w", err)
+		}
+	}
+
+	got, err := readPage(card, 0x03)
+	if err != nil {
+		return fmt.Errorf("read CC back: %w", err)
+	}
+	if len(got) < 4 || got[3]&0x0F != 0x0F {
+		return fmt.Errorf("CC reads back % X, write access is not 0xF", got)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildCC(t *testing.T) {
+	tests := []struct {
+		tagType string
+		want    []byte
+	}{
+		{"MIFARE Ultralight", []byte{0xE1, 0x10, 0x06, 0x00}},
+		{"MIFARE Ultralight C", []byte{0xE1, 0x10, 0x12, 0x00}},
+		{"NTAG213", []byte{0xE1, 0x10, 0x12, 0x00}},
+		{"NTAG215", []byte{0xE1, 0x10, 0x3E, 0x00}},
+		{"NTAG216", []byte{0xE1, 0x10, 0x6D, 0x00}},
+		{"Type2-compatible", []byte{0xE1, 0x10, legacyCCDataSize, 0x00}},
+	}
+	for _, tt := range tests {
+		if got := buildCC(tt.tagType); !bytes.Equal(got, tt.want) {
+			t.Errorf("buildCC(%q) = % X, want % X", tt.tagType, got, tt.want)
+		}
+	}
+}
+
+func TestBuildCCForSize(t *testing.T) {
+	tests := []struct {
+		bytes int
+		want  []byte // nil: rejected
+	}{
+		{48, []byte{0xE1, 0x10, 0x06, 0x00}},
+		{500, []byte{0xE1, 0x10, 0x3E, 0x00}}, // Rounded down to 496
+		{8, []byte{0xE1, 0x10, 0x01, 0x00}},
+		{0xFF * 8, []byte{0xE1, 0x10, 0xFF, 0x00}},
+		{7, nil},
+		{0xFF*8 + 8, nil},
+	}
+	for _, tt := range tests {
+		got, err := buildCCForSize(tt.bytes)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("buildCCForSize(%d) = % X, want an error", tt.bytes, got)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, tt.want) {
+			t.Errorf("buildCCForSize(%d) = % X, %v; want % X", tt.bytes, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ebfe/scard"
+)
+
+// errNoNDEF is returned when the data area holds no NDEF Message TLV
+var errNoNDEF = errors.New("no NDEF message TLV found")
+
+// readPage reads one 4-byte page from a Type 2 tag using FF B0 00 <page> 04
+func readPage(card *scard.Card, page byte) ([]byte, error) {
+	return transmit(card, []byte{0xFF, 0xB0, 0x00, page, 0x04})
+}
+
+// readCapabilityContainer reads page 3 and checks for the NDEF magic number
+func readCapabilityContainer(card *scard.Card) ([]byte, error) {
+	cc, err := readPage(card, 0x03)
+	if err != nil {
+		return nil, fmt.Errorf("read capability container: %w", err)
+	}
+	if len(cc) < 4 || cc[0] != 0xE1 {
+		return nil, fmt.Errorf("tag is not NDEF formatted (CC: % X)", cc)
+	}
+	return cc, nil
+}
+
+// readNDEFMessage walks the TLVs of the data area (starting at page 4) and
+// returns the value of the first NDEF Message TLV and the data area offset
+// it starts at, after any Lock/Memory Control TLVs. Pages are read lazily so
+// only the bytes up to the end of the message are transferred.
+func readNDEFMessage(card *scard.Card, dataSize int) ([]byte, int, error) {
+	return findNDEFTLV(func(page byte) ([]byte, error) { return readPage(card, page) }, dataSize)
+}
+
+// findNDEFTLV walks the data area TLVs through readPage, which returns the
+// 4 bytes of a page, and returns the value of the first NDEF Message TLV and
+// its offset. Without one, the offset is that of the Terminator TLV, where a
+// new message would go, along with errNoNDEF.
+func findNDEFTLV(readPage func(page byte) ([]byte, error), dataSize int) ([]byte, int, error) {
+	var area []byte
+	need := func(n int) error {
+		for len(area) < n {
+			if len(area) >= dataSize {
+				return errors.New("TLV runs past the end of the data area")
+			}
+			page := byte(0x04 + len(area)/4)
+			data, err := readPage(page)
+			if err != nil {
+				return fmt.Errorf("read page %d: %w", page, err)
+			}
+			area = append(area, data...)
+		}
+		return nil
+	}
+
+	offset := 0
+	for {
+		if err := need(offset + 1); err != nil {
+			return nil, 0, err
+		}
+		tlvType := area[offset]
+		switch tlvType {
+		case 0x00: // NULL TLV
+			offset++
+			continue
+		case 0xFE: // Terminator
+			return nil, offset, errNoNDEF
+		}
+
+		if err := need(offset + 2); err != nil {
+			return nil, 0, err
+		}
+		length, header := int(area[offset+1]), 2
+		if length == 0xFF {
+			if err := need(offset + 4); err != nil {
+				return nil, 0, err
+			}
+			length, header = int(area[offset+2])<<8|int(area[offset+3]), 4
+		}
+		if err := need(offset + header + length); err != nil {
+			return nil, 0, err
+		}
+		if tlvType == 0x03 {
+			return area[offset+header : offset+header+length], offset, nil
+		}
+		offset += header + length // Skip Lock/Memory Control and proprietary TLVs
+	}
+}
+
+// checkRewritable runs checkWritable over the pages writeNDEFAfterControlTLVs
+// writes for ndef: the page the control TLVs end in, when they end mid-page,
+// through the last page of the NDEF TLV
+func checkRewritable(card *scard.Card, ndef []byte, start int) error {
+	area, err := wrapNDEFTLV(ndef)
+	if err != nil {
+		return err
+	}
+	first := start / 4 * 4
+	return checkWritable(card, detectTagType(card), first, controlAreaSize(start)+len(area)-first)
+}
+
+// selectRecord resolves a selector (1-based index or record kind such as
+// "uri" or "text") to a position in records
+func selectRecord(records []ndefRecord, selector string) (int, error) {
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n < 1 || n > len(records) {
+			return 0, fmt.Errorf("record %d out of range (message has %d records)", n, len(records))
+		}
+		return n - 1, nil
+	}
+	for i, rec := range records {
+		if rec.Kind() == selector {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no %q record in message", selector)
+}
+
+// editRecord replaces one record of the tag's existing NDEF message and
+// writes the message back, preserving the order of the other records.
+// MB/ME flags are recomputed by the encoder.
+func editRecord(card *scard.Card, selector string, replacement ndefRecord) error {
+	cc, err := readCapabilityContainer(card)
+	if err != nil {
+		return err
+	}
+	msg, start, err := readNDEFMessage(card, int(cc[2])*8)
+	if err != nil {
+		return fmt.Errorf("read existing NDEF: %w", err)
+	}
+	records, err := decodeNDEFRecords(msg)
+	if err != nil {
+		return fmt.Errorf("parse existing NDEF: %w", err)
+	}
+	for _, rec := range records {
+		if rec.CF {
+			return errors.New("chunked records are not supported by the editor")
+		}
+	}
+
+	idx, err := selectRecord(records, selector)
+	if err != nil {
+		return err
+	}
+	records[idx] = replacement
+
+	ndef := encodeNDEFMessage(records)
+	if err := checkFitsAfterControlTLVs(ndef, start, int(cc[2])*8); err != nil {
+		return fmt.Errorf("edited message: %w", err)
+	}
+	if err := checkRewritable(card, ndef, start); err != nil {
+		return err
+	}
+	return writeNDEFAfterControlTLVs(card, ndef, start)
+}
+
+// appendRecord adds a record to the end of the tag's existing NDEF message.
+// The encoder clears ME on the previous last record and sets it on the new
+// one. A formatted tag without a message gets a single-record message.
+func appendRecord(card *scard.Card, record ndefRecord) error {
+	cc, err := readCapabilityContainer(card)
+	if err != nil {
+		return err
+	}
+	dataSize := int(cc[2]) * 8
+	var records []ndefRecord
+	msg, start, err := readNDEFMessage(card, dataSize)
+	switch {
+	case errors.Is(err, errNoNDEF):
+		// Formatted but no NDEF TLV yet
+	case err != nil:
+		return fmt.Errorf("read existing NDEF: %w", err)
+	case len(msg) > 0:
+		if records, err = decodeNDEFRecords(msg); err != nil {
+			return fmt.Errorf("parse existing NDEF: %w", err)
+		}
+	}
+	for _, rec := range records {
+		if rec.CF {
+			return errors.New("cannot append to a message with chunked records")
+		}
+	}
+
+	records = append(records, record)
+	ndef := encodeNDEFMessage(records)
+	if err := checkFitsAfterControlTLVs(ndef, start, dataSize); err != nil {
+		return fmt.Errorf("appended message: %w", err)
+	}
+	if err := checkRewritable(card, ndef, start); err != nil {
+		return err
+	}
+	return writeNDEFAfterControlTLVs(card, ndef, start)
+}
+
+// checkFitsAfterControlTLVs checks that the NDEF TLV for ndef, with the
+// 3-byte length form past 254 bytes and the terminator, fits in a data area
+// of dataSize bytes after the control TLVs in its first start bytes
+func checkFitsAfterControlTLVs(ndef []byte, start, dataSize int) error {
+	area, err := wrapNDEFTLV(ndef)
+	if err != nil {
+		return err
+	}
+	if needed := controlAreaSize(start) + len(area); needed > dataSize {
+		return fmt.Errorf("needs %d bytes but the data area holds %d", needed, dataSize)
+	}
+	return nil
+}
+
+// controlAreaSize is the data area space the control TLVs in the first start
+// bytes take once padded to a whole page
+func controlAreaSize(start int) int {
+	return int(ndefStartPage(start)-0x04) * 4
+}
+
+// writeNDEFAfterControlTLVs writes the message after the Lock/Memory Control
+// TLVs in the first start bytes of the data area. The rest of the page they
+// end in is filled with NULL TLVs, and the NDEF TLV starts on the next page.
+func writeNDEFAfterControlTLVs(card *scard.Card, ndef []byte, start int) error {
+	if start%4 != 0 {
+		page := byte(0x04 + start/4)
+		data, err := readPage(card, page)
+		if err != nil || len(data) < 4 {
+			return fmt.Errorf("read page %d: %v", page, err)
+		}
+		padded := append(append([]byte(nil), data[:start%4]...), make([]byte, 4-start%4)...)
+		if err := writePageAlternative(card, page, padded); err != nil {
+			return fmt.Errorf("write page %d: %w", page, err)
+		}
+	}
+	return writeNDEFToType2At(card, ndef, ndefStartPage(start))
+}
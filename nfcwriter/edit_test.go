@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestCheckFitsAfterControlTLVs(t *testing.T) {
+	tests := []struct {
+		ndef, start, dataSize int
+		ok                    bool
+	}{
+		{45, 0, 48, true},    // 2 + 45 + FE = 48
+		{46, 0, 48, false},   // Terminator doesn't fit
+		{41, 4, 48, true},    // Control TLVs fill page 4: 4 + 2 + 41 + FE
+		{42, 4, 48, false},   // Control TLVs fill page 4
+		{37, 5, 48, true},    // 5 bytes of control TLVs pad to 2 pages
+		{254, 0, 256, false}, // Short form, but 2 + 254 + FE is 257
+		{255, 0, 264, true},  // 4 + 255 + FE = 260
+		{255, 0, 258, false}, // Old len+3 check let this through
+		{490, 0, 496, true},  // NTAG215: 4 + 490 + FE = 495
+		{492, 0, 496, false}, // 497
+	}
+	for _, tt := range tests {
+		err := checkFitsAfterControlTLVs(make([]byte, tt.ndef), tt.start, tt.dataSize)
+		if (err == nil) != tt.ok {
+			t.Errorf("%d bytes after %d in %d: err = %v, want ok %v", tt.ndef, tt.start, tt.dataSize, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ebfe/scard"
+)
+
+// lockLayout describes where a Type 2 tag's dynamic lock bits apply
+type lockLayout struct {
+	lastUserPage int // Last user data page
+	dynLockPage  int // Page holding the dynamic lock bytes, 0 if the tag has none
+	pagesPerBit  int // Pages covered by each dynamic lock bit
+}
+
+// lockLayouts maps tag types to their lock layout (NTAG213/215/216 datasheet 8.5)
+var lockLayouts = map[string]lockLayout{
+	"NTAG213":           {lastUserPage: 0x27, dynLockPage: 0x28, pagesPerBit: 2},
+	"NTAG215":           {lastUserPage: 0x81, dynLockPage: 0x82, pagesPerBit: 16},
+	"NTAG216":           {lastUserPage: 0xE1, dynLockPage: 0xE2, pagesPerBit: 16},
+	"MIFARE Ultralight": {lastUserPage: 0x0F},
+	// Ultralight C lock bits at page 0x28 cover uneven page groups, not decoded
+	"MIFARE Ultralight C": {lastUserPage: 0x27},
+}
+
+// staticLockedPages decodes the static lock bytes (page 2 bytes 2-3): lock0
+// bit 3 locks the CC (page 3), bits 4-7 pages 4-7, lock1 bits 0-7 pages 8-15
+func staticLockedPages(lock0, lock1 byte) []int {
+	var pages []int
+	for bit := 3; bit < 8; bit++ {
+		if lock0&(1<<bit) != 0 {
+			pages = append(pages, bit)
+		}
+	}
+	for bit := 0; bit < 8; bit++ {
+		if lock1&(1<<bit) != 0 {
+			pages = append(pages, 8+bit)
+		}
+	}
+	return pages
+}
+
+// dynamicLockedPages decodes dynamic lock bytes 0-1, each bit locking
+// pagesPerBit pages from page 16 up to the last user page
+func dynamicLockedPages(l lockLayout, dyn []byte) []int {
+	var pages []int
+	if l.dynLockPage == 0 || len(dyn) < 2 {
+		return nil
+	}
+	for bit := 0; bit < 16; bit++ {
+		first := 16 + bit*l.pagesPerBit
+		if first > l.lastUserPage {
+			break
+		}
+		if dyn[bit/8]&(1<<(bit%8)) == 0 {
+			continue
+		}
+		for p := first; p < first+l.pagesPerBit && p <= l.lastUserPage; p++ {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+// writtenPages returns the first and last page a write of dataBytes from data
+// area byte start touches
+func writtenPages(start, dataBytes int) (first, last int) {
+	return 4 + start/4, 4 + (start+dataBytes+3)/4 - 1
+}
+
+// checkWritable reads the CC, the lock bytes and, on NTAG21x, AUTH0 and
+// refuses a write of dataBytes from data area byte start (page 4 is byte 0)
+// that would hit a read-only CC, a locked page or password protected memory,
+// before anything is written
+func checkWritable(card *scard.Card, tagType string, start, dataBytes int) error {
+	firstPage, lastPage := writtenPages(start, dataBytes)
+
+	cc, err := readPage(card, 0x03)
+	if err != nil {
+		return fmt.Errorf("read CC: %w", err)
+	}
+	if len(cc) >= 4 && cc[0] == 0xE1 && cc[3]&0x0F != 0 {
+		return fmt.Errorf("tag is read-only: CC write access is 0x%X (CC % X), cannot write", cc[3]&0x0F, cc[:4])
+	}
+
+	var locked []int
+	if pg2, err := readPage(card, 0x02); err == nil && len(pg2) >= 4 {
+		for _, p := range staticLockedPages(pg2[2], pg2[3]) {
+			// A locked CC only matters when it isn't an NDEF CC already
+			if p >= 4 || len(cc) < 4 || cc[0] != 0xE1 {
+				locked = append(locked, p)
+			}
+		}
+	}
+	layout, known := lockLayouts[tagType]
+	if known && layout.dynLockPage != 0 {
+		if dyn, err := readPage(card, byte(layout.dynLockPage)); err == nil {
+			locked = append(locked, dynamicLockedPages(layout, dyn)...)
+		}
+	}
+	var hit []string
+	for _, p := range locked {
+		if p == 3 || p >= firstPage && p <= lastPage {
+			hit = append(hit, fmt.Sprintf("%02X", p))
+		}
+	}
+	if len(hit) > 0 {
+		return fmt.Errorf("tag is locked: page(s) %s needed for this write are permanently locked, cannot write", strings.Join(hit, ", "))
+	}
+
+	if known && layout.dynLockPage != 0 {
+		if cfg0, err := readPage(card, byte(layout.dynLockPage+1)); err == nil && len(cfg0) >= 4 {
+			if auth0 := int(cfg0[3]); auth0 <= lastPage {
+				return fmt.Errorf("tag is password protected from page %02X (AUTH0), cannot write without the password", auth0)
+			}
+		}
+	}
+	return nil
+}
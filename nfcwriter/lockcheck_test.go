@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStaticLockedPages(t *testing.T) {
+	tests := []struct {
+		lock0, lock1 byte
+		want         []int
+	}{
+		{0x00, 0x00, nil},
+		{0x07, 0x00, nil}, // Block-locking bits only
+		{0x08, 0x00, []int{3}},
+		{0xF0, 0x00, []int{4, 5, 6, 7}},
+		{0x00, 0x81, []int{8, 15}},
+		{0xFF, 0xFF, []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+	}
+	for _, tt := range tests {
+		if got := staticLockedPages(tt.lock0, tt.lock1); !slices.Equal(got, tt.want) {
+			t.Errorf("staticLockedPages(%02X, %02X) = %v, want %v", tt.lock0, tt.lock1, got, tt.want)
+		}
+	}
+}
+
+func TestDynamicLockedPages(t *testing.T) {
+	tests := []struct {
+		tagType string
+		dyn     []byte
+		want    []int
+	}{
+		{"NTAG213", []byte{0x00, 0x00}, nil},
+		{"NTAG213", []byte{0x01, 0x00}, []int{16, 17}},
+		{"NTAG213", []byte{0x00, 0x08}, []int{38, 39}}, // Bit 11, the last one in range
+		{"NTAG213", []byte{0x00, 0x10}, nil},           // Bit 12 starts past page 0x27
+		{"NTAG215", []byte{0x02, 0x00}, []int{32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47}},
+		{"NTAG216", []byte{0x00, 0x10}, []int{0xD0, 0xD1, 0xD2, 0xD3, 0xD4, 0xD5, 0xD6, 0xD7, 0xD8, 0xD9, 0xDA, 0xDB, 0xDC, 0xDD, 0xDE, 0xDF}},
+		{"NTAG216", []byte{0x00, 0x20}, []int{0xE0, 0xE1}}, // Cut off at the last user page
+		{"MIFARE Ultralight", []byte{0xFF, 0xFF}, nil},     // No dynamic lock bytes
+		{"NTAG213", []byte{0xFF}, nil},                     // Short read
+	}
+	for _, tt := range tests {
+		if got := dynamicLockedPages(lockLayouts[tt.tagType], tt.dyn); !slices.Equal(got, tt.want) {
+			t.Errorf("dynamicLockedPages(%s, % X) = %v, want %v", tt.tagType, tt.dyn, got, tt.want)
+		}
+	}
+}
+
+func TestWrittenPages(t *testing.T) {
+	tests := []struct {
+		start, dataBytes int
+		first, last      int
+	}{
+		{0, 4, 4, 4},
+		{0, 48, 4, 15},
+		{0, 49, 4, 16},
+		{8, 12, 6, 8}, // -edit after control TLVs filling pages 4-5
+		{4, 16, 5, 8}, // Starts on page 5
+		{5, 16, 5, 9}, // Unaligned start runs one page further
+	}
+	for _, tt := range tests {
+		if first, last := writtenPages(tt.start, tt.dataBytes); first != tt.first || last != tt.last {
+			t.Errorf("writtenPages(%d, %d) = %02X-%02X, want %02X-%02X", tt.start, tt.dataBytes, first, last, tt.first, tt.last)
+		}
+	}
+}
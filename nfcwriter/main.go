@@ -1,22 +1,66 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/ebfe/scard"
 )
 
+// Options holds the writer settings parsed from the command line
+type Options struct {
+	URL            string        // URL template to write, {uid} is replaced with the tag UID
+	EditRecord     string        // Replace this record (1-based index or type) instead of reformatting
+	External       string        // Write an external type record (domain:type) instead of the URL
+	Text           string        // Write a UTF-8 Text record instead of the URL, {uid} is replaced with the tag UID
+	Lang           string        // Language code of the Text record
+	Payload        []byte        // Payload for the external type record
+	AndroidApp     string        // Follow the URL with an Android Application Record for this package
+	Append         bool          // Append the record to the existing NDEF message instead of reformatting
+	URICode        int           // Force this URI identifier code (-1 = default https:// handling)
+	Validate       bool          // Read the tag back after a full write and check the first record's content
+	SafeWrite      bool          // Read each page back right after writing it and stop on the first mismatch
+	SelfTest       bool          // Round-trip sample messages through the NDEF codec and exit
+	CCDataSize     int           // Declare this many data area bytes in the CC instead of the detected type's size
+	RecordID       []byte        // ID field of the written record (nil = no ID)
+	CCReadOnly     bool          // Mark the data area read-only in the CC after writing (lock bytes untouched)
+	CounterPwdProt string        // "on" or "off": set NFC_CNT_PWD_PROT in CFG1 after writing ("" = leave as is)
+	ListReaders    bool          // Print the attached readers with their card status and exit
+	WaitForReader  bool          // Poll until a reader is connected instead of failing at startup
+	ReaderTimeout  time.Duration // With WaitForReader, give up after this long (0 = wait forever)
+	CSVIn          string        // Serialization CSV (serial,url): write the next row's URL to each tag
+	CSVOut         string        // Append serial,url,uid for each written tag to this CSV
+	UIDAttrib      scard.Attrib  // SCardGetAttrib id to read the UID from when FF CA fails (0 = off)
+	RemovalTimeout time.Duration // Stop waiting for a tag to be removed after this long (0 = wait forever)
+}
+
+// opts is the active configuration, set once in main before any tag is written
+var opts = Options{
+	URL:     "https://dnd.qrand.me/r/{uid}",
+	Lang:    "en",
+	URICode: -1,
+}
+
 // APDU helpers
 func transmit(card *scard.Card, apdu []byte) ([]byte, error) {
+	logger.Debugf("APDU > % X", apdu)
+	start := time.Now()
 	resp, err := card.Transmit(apdu)
+	traceAPDU(apduTrace, apdu, resp, err, time.Since(start))
 	if err != nil {
-		return nil, err
+		return nil, describePCSCError(err)
 	}
+	logger.Debugf("APDU < % X", resp)
 	if len(resp) < 2 {
 		return nil, errors.New("short APDU response")
 	}
@@ -28,9 +72,43 @@ func transmit(card *scard.Card, apdu []byte) ([]byte, error) {
 	return resp[:len(resp)-2], nil
 }
 
-// getUID uses the ACR/PCSC pseudo-APDU FF CA 00 00 00 to fetch UID
+// getUID uses the ACR/PCSC pseudo-APDU FF CA 00 00 00 to fetch UID. Readers
+// without FF CA support can expose the UID as a (vendor) attribute instead,
+// which is read when -uid-attrib is set.
 func getUID(card *scard.Card) ([]byte, error) {
-	return transmit(card, []byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
+	uid, err := transmit(card, []byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
+	if err == nil && len(uid) > 0 {
+		return uid, nil
+	}
+	if err == nil {
+		err = errors.New("reader returned an empty UID")
+	}
+	if opts.UIDAttrib == 0 {
+		return nil, err
+	}
+	uid, aerr := card.GetAttrib(opts.UIDAttrib)
+	if aerr == nil && len(uid) == 0 {
+		aerr = errors.New("empty attribute")
+	}
+	if aerr != nil {
+		return nil, fmt.Errorf("FF CA: %w; GetAttrib 0x%X: %v", err, uint32(opts.UIDAttrib), describePCSCError(aerr))
+	}
+	return uid, nil
+}
+
+// uidSizeLabel describes a UID by its length and ISO 14443-3 cascade level
+// (4 bytes = single size, 7 = double size, 10 = triple size)
+func uidSizeLabel(uid []byte) string {
+	switch len(uid) {
+	case 4:
+		return "4-byte, single size"
+	case 7:
+		return "7-byte, double size"
+	case 10:
+		return "10-byte, triple size"
+	default:
+		return fmt.Sprintf("%d-byte, non-standard size", len(uid))
+	}
 }
 
 // writePage writes one 4-byte page to a Type 2 tag using FF D6 00 <page> 04 + data
@@ -43,47 +121,99 @@ func writePage(card *scard.Card, page byte, data []byte) error {
 	return err
 }
 
+// writePageAlternative tries alternative methods to write a page if the standard method fails
+func writePageAlternative(card *scard.Card, page byte, data []byte) error {
+	// Try standard method first
+	err := writePage(card, page, data)
+	if err == nil || len(data) != 4 {
+		return err
+	}
+
+	// Try Ultralight WRITE (A2) wrapped in a PN53x InDataExchange for target 1
+	_, altErr := inDataExchange(card, append([]byte{0xA2, page}, data...))
+	if altErr == nil {
+		logger.Debugf("page %02X written via InDataExchange", page)
+		return nil
+	}
+
+	return fmt.Errorf("all write methods failed for page %02X: %v; %v", page, err, altErr)
+}
+
 // buildURIRecord builds a single-record NDEF message for a URI using SR
 // URI payload = [identifierCode][uriWithoutPrefix]
-// identifierCode 0x04 = "https://"
+// identifierCode e.g. 0x04 = "https://"
 func buildURIRecord(uri string) []byte {
-	payload := []byte{}
-	// Normalize and choose identifier code 0x04 (https://)
-	trimmed := strings.TrimPrefix(strings.TrimPrefix(uri, "http://"), "https://")
-	payload = append(payload, 0x04)
-	payload = append(payload, []byte(trimmed)...)
+	// Header 0xD1: MB=1, ME=1, SR=1, TNF=0x01 (Well-known), type 'U'
+	return encodeNDEFMessage([]ndefRecord{newURIRecord(uri)})
+}
 
-	// NDEF short record header
-	header := byte(0xD1)  // MB=1, ME=1, SR=1, TNF=0x01 (Well-known)
-	typeLen := byte(0x01) // 'U'
-	payloadLen := byte(len(payload))
-	typ := byte('U')
+// uriPayload builds the URI record payload [identifierCode][uriWithoutPrefix]
+// with the longest prefix from the identifier code table. A URI with a scheme
+// the table lacks (smsto:, whatsapp:, intent:, ...) is stored in full with
+// code 0x00; one without a scheme is taken as a host and gets https:// (0x04).
+func uriPayload(uri string) []byte {
+	code := 0
+	for c, prefix := range uriPrefixes {
+		if c > 0 && strings.HasPrefix(uri, prefix) && len(prefix) > len(uriPrefixes[code]) {
+			code = c
+		}
+	}
+	if code == 0 && !hasURIScheme(uri) {
+		return append([]byte{0x04}, uri...)
+	}
+	return append([]byte{byte(code)}, uri[len(uriPrefixes[code]):]...)
+}
 
-	msg := []byte{header, typeLen, payloadLen, typ}
-	msg = append(msg, payload...)
-	return msg
+// hasURIScheme reports whether uri starts with a scheme and a colon (RFC 3986
+// 3.1: a letter, then letters, digits, "+", "-" or "."). A dotted name
+// followed by a port number, as in "example.com:8080/x", is a host, not a
+// scheme.
+func hasURIScheme(uri string) bool {
+	scheme, rest, found := strings.Cut(uri, ":")
+	if !found || scheme == "" || !(scheme[0] >= 'a' && scheme[0] <= 'z' || scheme[0] >= 'A' && scheme[0] <= 'Z') {
+		return false
+	}
+	for _, c := range scheme {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	if strings.Contains(scheme, ".") {
+		port := strings.TrimLeft(rest, "0123456789")
+		if len(port) < len(rest) && (port == "" || strings.ContainsAny(port[:1], "/?#")) {
+			return false
+		}
+	}
+	return true
 }
 
 // formatType2Tag formats an NFC card according to NFC Forum Type 2 data format
 // This initializes the capability container and prepares the tag for NDEF writing
 func formatType2Tag(card *scard.Card) error {
+	return formatType2TagWithAccess(card, 0x00)
+}
+
+// formatType2TagWithAccess formats the tag like formatType2Tag with the given
+// CC access byte, e.g. 0x0F (no write access) so phones see the tag as
+// read-only as soon as it is provisioned. The lock bytes stay unlocked, and
+// on NTAG the CC is OTP, so a set access nibble can't be cleared later.
+func formatType2TagWithAccess(card *scard.Card, access byte) error {
 	// Page 0: Manufacturer data (UID) - read-only, don't modify
 	// Page 1: Reserved for manufacturer - don't modify
 
 	// Page 2: Lock bytes (bytes 2-3 of page 2)
 	// Setting to 0x00, 0x00 means no blocks are locked
 	lockBytes := []byte{0x00, 0x00, 0x00, 0x00}
-	if err := writePage(card, 0x02, lockBytes); err != nil {
+	if err := writePageAlternative(card, 0x02, lockBytes); err != nil {
 		return fmt.Errorf("write lock bytes: %w", err)
 	}
 
 	// Page 3: Capability Container (CC)
 	// Byte 0: Magic number (0xE1) - indicates NDEF capability
 	// Byte 1: Version (0x10) - version 1.0
-	// Byte 2: Data size (0x3F) - 504 bytes available (0x3F * 8 = 504)
-	// Byte 3: Access conditions (0x00) - read/write allowed
-	cc := []byte{0xE1, 0x10, 0x3F, 0x00}
-	if err := writePage(card, 0x03, cc); err != nil {
+	// Byte 2: Data size in 8-byte units, from the detected tag type (see buildCC)
+	// Byte 3: Access conditions (0x00 read/write, 0x0F read-only)
+	if err := writeCC(card, access); err != nil {
 		return fmt.Errorf("write capability container: %w", err)
 	}
 
@@ -91,144 +221,765 @@ func formatType2Tag(card *scard.Card) error {
 	// Initialize with NULL TLV (0x00) and then terminator TLV (0xFE)
 	// This ensures the tag is properly formatted but empty
 	clearData := []byte{0x00, 0x00, 0x00, 0xFE}
-	if err := writePage(card, 0x04, clearData); err != nil {
+	if err := writePageAlternative(card, 0x04, clearData); err != nil {
 		return fmt.Errorf("write initial NDEF area: %w", err)
 	}
 
 	return nil
 }
 
-// writeNDEFToType2 writes TLV (0x03, len, ndef...) and terminator 0xFE starting at page 4
-func writeNDEFToType2(card *scard.Card, ndef []byte) error {
-	if len(ndef) > 254 {
-		return fmt.Errorf("NDEF too large for single-byte TLV length: %d", len(ndef))
+// verifyPage reads a page straight back and compares it with what was written,
+// catching a tag that is drifting out of the field before more pages are written
+func verifyPage(card *scard.Card, page byte, want []byte) error {
+	got, err := readPage(card, page)
+	if err != nil {
+		return fmt.Errorf("read back: %w", err)
+	}
+	if len(got) < len(want) || !bytes.Equal(got[:len(want)], want) {
+		return fmt.Errorf("read back % X, wrote % X", got, want)
+	}
+	return nil
+}
+
+// encodeTLV encodes one data area TLV. NULL (0x00) and Terminator (0xFE)
+// are a bare type byte; other values get a 1-byte length up to 254 bytes,
+// or FF and a 2-byte big-endian length up to 65534 bytes.
+func encodeTLV(tag byte, value []byte) ([]byte, error) {
+	switch {
+	case tag == 0x00 || tag == 0xFE:
+		if len(value) > 0 {
+			return nil, fmt.Errorf("TLV %02X has no value, got %d bytes", tag, len(value))
+		}
+		return []byte{tag}, nil
+	case len(value) < 0xFF:
+		return append([]byte{tag, byte(len(value))}, value...), nil
+	case len(value) <= 0xFFFE:
+		return append([]byte{tag, 0xFF, byte(len(value) >> 8), byte(len(value))}, value...), nil
+	}
+	return nil, fmt.Errorf("TLV value too large: %d bytes (max 65534)", len(value))
+}
+
+// wrapNDEFTLV builds the data area bytes for a message: the NDEF Message TLV
+// and a Terminator TLV, padded with 0x00 to a whole number of pages
+func wrapNDEFTLV(ndef []byte) ([]byte, error) {
+	tlv, err := encodeTLV(0x03, ndef)
+	if err != nil {
+		return nil, err
 	}
-	tlv := []byte{0x03, byte(len(ndef))}
-	tlv = append(tlv, ndef...)
-	tlv = append(tlv, 0xFE)
+	terminator, _ := encodeTLV(0xFE, nil)
+	tlv = append(tlv, terminator...)
 
-	// Write starting at page 4, 4 bytes per page
-	page := byte(0x04)
 	// Ensure data length is multiple of 4 by padding 0x00
 	pad := (4 - (len(tlv) % 4)) % 4
 	if pad > 0 {
 		tlv = append(tlv, make([]byte, pad)...)
 	}
+	return tlv, nil
+}
+
+// ndefStartPage returns the page the NDEF Message TLV starts on when
+// control TLVs (Lock Control, Memory Control) fill the first controlLen
+// bytes of the data area, rounded up to a whole page
+func ndefStartPage(controlLen int) byte {
+	return byte(0x04 + (controlLen+3)/4)
+}
+
+// writeNDEFToType2 writes the NDEF Message TLV and terminator starting at page 4
+func writeNDEFToType2(card *scard.Card, ndef []byte) error {
+	return writeNDEFToType2At(card, ndef, 0x04)
+}
+
+// writeNDEFToType2At writes the NDEF Message TLV and terminator starting at
+// startPage, leaving the control TLVs on the pages before it alone
+func writeNDEFToType2At(card *scard.Card, ndef []byte, startPage byte) error {
+	tlv, err := wrapNDEFTLV(ndef)
+	if err != nil {
+		return err
+	}
+
+	// Write starting at startPage, 4 bytes per page
+	page := startPage
 	for i := 0; i < len(tlv); i += 4 {
-		if err := writePage(card, page, tlv[i:i+4]); err != nil {
+		if err := writePageAlternative(card, page, tlv[i:i+4]); err != nil {
 			return fmt.Errorf("write page %d: %w", page, err)
 		}
+		if opts.SafeWrite {
+			if err := verifyPage(card, page, tlv[i:i+4]); err != nil {
+				return fmt.Errorf("safe write stopped at page %d: %w", page, err)
+			}
+		}
 		page++
 	}
 	return nil
 }
 
 func main() {
-	log.SetFlags(0)
+	// Parse command line arguments
+	urlSet, langSet := false, false
+	for i := 1; i < len(os.Args); i++ {
+		switch os.Args[i] {
+		case "-url":
+			if i+1 < len(os.Args) {
+				opts.URL = os.Args[i+1]
+				urlSet = true
+				i++ // Skip next argument as it's the URL
+			}
+		case "-text":
+			if i+1 < len(os.Args) {
+				opts.Text = os.Args[i+1]
+				i++
+			}
+		case "-lang":
+			if i+1 < len(os.Args) {
+				opts.Lang = os.Args[i+1]
+				langSet = true
+				i++
+			}
+		case "-edit":
+			if i+1 < len(os.Args) {
+				opts.EditRecord = os.Args[i+1]
+				i++
+			}
+		case "-append":
+			opts.Append = true
+		case "-validate":
+			opts.Validate = true
+		case "-safe-write":
+			opts.SafeWrite = true
+		case "-debug":
+			logger.SetLevel(levelDebug)
+		case "-trace":
+			apduTrace = os.Stderr
+		case "-quiet":
+			logger.SetLevel(levelWarn)
+		case "-log-level":
+			if i+1 < len(os.Args) {
+				level, err := parseLogLevel(os.Args[i+1])
+				if err != nil {
+					logger.Fatalf("invalid -log-level: %v", err)
+				}
+				logger.SetLevel(level)
+				i++
+			}
+		case "-uid-attrib":
+			if i+1 < len(os.Args) {
+				id, err := strconv.ParseUint(os.Args[i+1], 0, 32)
+				if err != nil {
+					logger.Fatalf("invalid -uid-attrib: %s", os.Args[i+1])
+				}
+				opts.UIDAttrib = scard.Attrib(id)
+				i++
+			}
+		case "-csv":
+			if i+1 < len(os.Args) {
+				opts.CSVIn = os.Args[i+1]
+				i++
+			}
+		case "-csv-out":
+			if i+1 < len(os.Args) {
+				opts.CSVOut = os.Args[i+1]
+				i++
+			}
+		case "-uri-code":
+			if i+1 < len(os.Args) {
+				code, err := strconv.ParseUint(os.Args[i+1], 0, 8)
+				if err != nil {
+					logger.Fatalf("invalid -uri-code: %s", os.Args[i+1])
+				}
+				opts.URICode = int(code)
+				i++
+			}
+		case "-external":
+			if i+1 < len(os.Args) {
+				opts.External = os.Args[i+1]
+				i++
+			}
+		case "-android-app":
+			if i+1 < len(os.Args) {
+				opts.AndroidApp = os.Args[i+1]
+				i++
+			}
+		case "-payload":
+			if i+1 < len(os.Args) {
+				opts.Payload = []byte(os.Args[i+1])
+				i++
+			}
+		case "-payload-hex":
+			if i+1 < len(os.Args) {
+				payload, err := hex.DecodeString(strings.ReplaceAll(os.Args[i+1], " ", ""))
+				if err != nil {
+					logger.Fatalf("invalid -payload-hex: %v", err)
+				}
+				opts.Payload = payload
+				i++
+			}
+		case "-id":
+			if i+1 < len(os.Args) {
+				opts.RecordID = []byte(os.Args[i+1])
+				i++
+			}
+		case "-id-hex":
+			if i+1 < len(os.Args) {
+				id, err := hex.DecodeString(strings.ReplaceAll(os.Args[i+1], " ", ""))
+				if err != nil {
+					logger.Fatalf("invalid -id-hex: %v", err)
+				}
+				opts.RecordID = id
+				i++
+			}
+		case "-cc-read-only":
+			opts.CCReadOnly = true
+		case "-list-readers":
+			opts.ListReaders = true
+		case "-counter-pwd-prot":
+			if i+1 < len(os.Args) {
+				opts.CounterPwdProt = os.Args[i+1]
+				if opts.CounterPwdProt != "on" && opts.CounterPwdProt != "off" {
+					logger.Fatalf("invalid -counter-pwd-prot: %s (use on or off)", opts.CounterPwdProt)
+				}
+				i++
+			}
+		case "-removal-timeout":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d < 0 {
+					logger.Fatalf("invalid -removal-timeout: %s", os.Args[i+1])
+				}
+				opts.RemovalTimeout = d
+				i++
+			}
+		case "-wait-for-reader":
+			opts.WaitForReader = true
+			// Optional timeout, e.g. 30s
+			if i+1 < len(os.Args) {
+				if d, err := time.ParseDuration(os.Args[i+1]); err == nil {
+					opts.ReaderTimeout = d
+					i++
+				}
+			}
+		case "-selftest":
+			opts.SelfTest = true
+		case "-cc-size":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil {
+					logger.Fatalf("invalid -cc-size: %s", os.Args[i+1])
+				}
+				if _, err := buildCCForSize(n); err != nil {
+					logger.Fatalf("invalid -cc-size: %v", err)
+				}
+				opts.CCDataSize = n
+				i++
+			}
+		}
+	}
+
+	// Offline codec check: no reader needed
+	if opts.SelfTest {
+		if !runSelfTest() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Cancel blocking waits on Ctrl+C / SIGTERM so the PC/SC context is released cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Validate a forced URI identifier code against the URL template up front
+	if opts.URICode >= 0 && opts.External == "" {
+		if _, err := buildURIRecordWithCode(strings.ReplaceAll(opts.URL, "{uid}", "00"), byte(opts.URICode)); err != nil {
+			logger.Fatalf("invalid -uri-code: %v", err)
+		}
+	}
+
+	// Validate the record ID up front rather than on the first tag
+	if _, err := (ndefRecord{}).withID(opts.RecordID); err != nil {
+		logger.Fatalf("invalid record ID: %v", err)
+	}
+
+	// Validate the external type up front rather than on the first tag
+	if opts.External != "" {
+		if _, err := buildExternalRecord(opts.External, opts.Payload); err != nil {
+			logger.Fatalf("invalid -external: %v", err)
+		}
+	}
+
+	// Validate the Text record up front; it replaces the URL record
+	if opts.Text != "" {
+		if urlSet || opts.External != "" || opts.AndroidApp != "" || opts.URICode >= 0 || opts.CSVIn != "" {
+			logger.Fatalf("-text writes a Text record and cannot be combined with -url, -external, -android-app, -uri-code or -csv")
+		}
+		if !utf8.ValidString(opts.Text) {
+			logger.Fatalf("invalid -text: not valid UTF-8")
+		}
+		if _, err := newTextRecord(opts.Lang, opts.Text); err != nil {
+			logger.Fatalf("invalid -lang: %v", err)
+		}
+		for _, c := range opts.Lang {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				logger.Fatalf("invalid -lang: %q is not a language tag such as en or pt-BR", opts.Lang)
+			}
+		}
+	} else if langSet {
+		logger.Fatalf("-lang requires -text")
+	}
+
+	// Validate the app launch pair up front; it replaces the whole message
+	if opts.AndroidApp != "" {
+		if opts.External != "" || opts.EditRecord != "" || opts.Append {
+			logger.Fatalf("-android-app writes a URI + AAR message and cannot be combined with -external, -edit or -append")
+		}
+		if _, err := buildAppLaunchMessage(strings.ReplaceAll(opts.URL, "{uid}", "00"), opts.AndroidApp); err != nil {
+			logger.Fatalf("invalid -android-app: %v", err)
+		}
+	}
+
+	if n := strings.Count(opts.URL, uidMirrorToken); n > 1 {
+		logger.Fatalf("-url has %d %s placeholders, the tag has one UID mirror", n, uidMirrorToken)
+	} else if n == 1 && (opts.EditRecord != "" || opts.Append || opts.External != "") {
+		logger.Fatalf("%s needs a full URL write and cannot be combined with -edit, -append or -external", uidMirrorToken)
+	}
+	if opts.CCReadOnly && (opts.EditRecord != "" || opts.Append) {
+		logger.Fatalf("-cc-read-only applies to full writes and cannot be combined with -edit or -append")
+	}
+	if opts.CounterPwdProt != "" && (opts.EditRecord != "" || opts.Append) {
+		logger.Fatalf("-counter-pwd-prot applies to full writes and cannot be combined with -edit or -append")
+	}
+	if opts.Validate && (opts.EditRecord != "" || opts.Append) {
+		logger.Fatalf("-validate checks the first record of a full write and cannot be combined with -edit or -append")
+	}
+
+	// Serialization run: one CSV row per tag
+	var batch *csvBatch
+	if opts.CSVIn != "" {
+		if opts.External != "" {
+			logger.Fatalf("-csv writes URLs and cannot be combined with -external")
+		}
+		b, err := loadCSVBatch(opts.CSVIn)
+		if err != nil {
+			logger.Fatalf("load -csv %s: %v", opts.CSVIn, err)
+		}
+		if opts.CSVOut != "" {
+			if err := b.openOutput(opts.CSVOut); err != nil {
+				logger.Fatalf("open -csv-out %s: %v", opts.CSVOut, err)
+			}
+		}
+		defer b.close()
+		batch = b
+		logger.Infof("Loaded %d rows from %s", batch.remaining(), opts.CSVIn)
+	} else if opts.CSVOut != "" {
+		logger.Fatalf("-csv-out requires -csv")
+	}
 
 	// Establish PC/SC context
-	ctx, err := scard.EstablishContext()
+	pcsc, err := scard.EstablishContext()
 	if err != nil {
-		log.Fatalf("pcsc EstablishContext: %v", err)
+		logger.Fatalf("pcsc EstablishContext: %v", describePCSCError(err))
+	}
+	defer pcsc.Release()
+
+	if opts.ListReaders {
+		code := printReaderList(pcsc)
+		pcsc.Release()
+		os.Exit(code)
 	}
-	defer ctx.Release()
 
 	// Ensure a reader is available
-	readers, err := ctx.ListReaders()
+	readers, err := listReaders(ctx, pcsc, opts.WaitForReader, opts.ReaderTimeout)
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
-		log.Fatalf("pcsc ListReaders: %v", err)
+		logger.Fatalf("pcsc ListReaders: %v", describePCSCError(err))
 	}
 	if len(readers) == 0 {
-		log.Fatalf("no PC/SC readers found")
+		logger.Fatalf("no PC/SC readers found")
 	}
 	reader := readers[0]
-	log.Printf("Using reader: %s", reader)
+	logger.Infof("Using reader: %s", reader)
+
+	// Tag still on the reader when -removal-timeout expired, skipped until removed
+	var leftBehind string
 
-	// Loop forever: wait for insertion, process, then wait for removal
+	// Loop until cancelled: wait for insertion, process, then wait for removal
 	for {
 		// Wait until a card is present
-		waitForCardPresent(ctx, reader)
+		if err := waitForCardPresent(ctx, pcsc, reader); err != nil {
+			return
+		}
 
 		// Try connecting (retry briefly on transient errors)
-		var card *scard.Card
-		for i := 0; i < 10; i++ {
-			card, err = ctx.Connect(reader, scard.ShareExclusive, scard.ProtocolAny)
-			if err == nil {
-				break
-			}
-			time.Sleep(100 * time.Millisecond)
-		}
+		card, err := connectCard(pcsc, reader)
 		if err != nil {
-			log.Printf("connect failed: %v", err)
-			waitForCardRemoval(ctx, reader)
+			logger.Errorf("connect failed: %v", describePCSCError(err))
+			if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); err != nil && !errors.Is(err, errRemovalTimeout) {
+				return
+			}
 			continue
 		}
+		if leftBehind != "" {
+			if uid, err := getUID(card); err == nil && strings.ToUpper(hex.EncodeToString(uid)) == leftBehind {
+				card.Disconnect(scard.LeaveCard)
+				if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); err != nil && !errors.Is(err, errRemovalTimeout) {
+					return
+				}
+				continue
+			}
+			leftBehind = ""
+		}
 
-		// Process the tag
-		func() {
+		// Process the tag, taking the URL from the next CSV row in a batch run
+		urlTemplate := opts.URL
+		var row batchRow
+		if batch != nil {
+			row, _ = batch.current()
+			urlTemplate = row.URL
+			logger.Infof("Row %s: %s", row.Serial, row.URL)
+		}
+		uid, ok := func() (string, bool) {
 			defer card.Disconnect(scard.LeaveCard)
+			return processTag(card, urlTemplate)
+		}()
 
-			// Get UID
-			uid, err := getUID(card)
-			if err != nil {
-				log.Printf("get UID: %v", err)
-				return
+		// Only a successful write consumes the row; a failed tag retries it
+		if batch != nil {
+			if !ok {
+				logger.Warnf("Row %s not written, present another tag to retry", row.Serial)
+			} else {
+				if err := batch.advance(uid); err != nil {
+					logger.Warnf("record row %s in -csv-out: %v", row.Serial, err)
+				}
+				if batch.remaining() == 0 {
+					logger.Infof("All rows written, stopping")
+					return
+				}
+				logger.Infof("%d rows remaining", batch.remaining())
 			}
-			uidHex := strings.ToUpper(hex.EncodeToString(uid))
-			log.Printf("Tag UID: %s", uidHex)
+		}
 
-			// Format the card as NFC Forum Type 2 format
-			log.Printf("Formatting tag as NFC Forum Type 2...")
-			if err := formatType2Tag(card); err != nil {
-				log.Printf("format Type 2 tag failed: %v", err)
-				return
+		// Wait until the card is removed before processing the next one. A
+		// failed tag left on the reader is retried, a written one is skipped.
+		if err := waitForCardRemoval(ctx, pcsc, reader, opts.RemovalTimeout); errors.Is(err, errRemovalTimeout) {
+			// Warned once here; the skip above waits quietly until it is removed
+			if ok {
+				logger.Warnf("tag %s still on the reader after %v, moving on (it is not written again until removed)", uid, opts.RemovalTimeout)
+				leftBehind = uid
+			} else {
+				logger.Warnf("tag still on the reader after %v, trying it again", opts.RemovalTimeout)
 			}
-			log.Printf("Tag formatted successfully")
+		} else if err != nil {
+			return
+		}
+	}
+}
 
-			// Small delay after formatting as requested
-			time.Sleep(200 * time.Millisecond)
+// processTag writes the configured content to the connected tag, filling
+// {uid} in urlTemplate. It returns the tag UID and whether the write succeeded.
+func processTag(card *scard.Card, urlTemplate string) (string, bool) {
+	// Get UID
+	uid, err := getUID(card)
+	if err != nil {
+		logger.Errorf("get UID: %v", err)
+		return "", false
+	}
+	uidHex := strings.ToUpper(hex.EncodeToString(uid))
+	logger.Infof("Tag UID: %s (%s)", uidHex, uidSizeLabel(uid))
 
-			// Build URL and NDEF
-			fullURL := fmt.Sprintf("https://dnd.qrand.me/r/%s", uidHex)
-			ndef := buildURIRecord(fullURL)
+	// Refuse MIFARE Classic and other non-Type 2 tags before any write
+	if err := checkTagFamily(card); err != nil {
+		logger.Errorf("%v", err)
+		return uidHex, false
+	}
 
-			// Write NDEF directly to memory
-			if err := writeNDEFToType2(card, ndef); err != nil {
-				log.Printf("write NDEF failed: %v", err)
-				return
-			}
-			log.Printf("Wrote URL to tag: %s", fullURL)
-		}()
+	// Content record: an external type record if requested, otherwise the URL
+	fullURL := strings.ReplaceAll(urlTemplate, "{uid}", uidHex)
+	// UID mirror: reserve the placeholder with '0's, the tag renders the UID there
+	placeholder := strings.Repeat("0", len(uidHex))
+	mirror := strings.Contains(fullURL, uidMirrorToken)
+	if mirror {
+		if opts.EditRecord != "" || opts.Append {
+			logger.Errorf("%s needs a full write and cannot be used with -edit or -append", uidMirrorToken)
+			return uidHex, false
+		}
+		fullURL = strings.Replace(fullURL, uidMirrorToken, placeholder, 1)
+	}
+	record, description := newURIRecord(fullURL), "URL "+fullURL
+	if opts.URICode >= 0 {
+		record, _ = newURIRecordWithCode(fullURL, byte(opts.URICode)) // validated in main
+		description = fmt.Sprintf("URL %s (identifier code 0x%02X)", fullURL, opts.URICode)
+	}
+	if opts.Text != "" {
+		text := strings.ReplaceAll(opts.Text, "{uid}", uidHex)
+		record, _ = newTextRecord(opts.Lang, text) // validated in main
+		description = fmt.Sprintf("text %q (%s)", text, opts.Lang)
+	}
+	if opts.External != "" {
+		record, _ = newExternalRecord(opts.External, opts.Payload) // validated in main
+		description = fmt.Sprintf("external record %s (%d byte payload)", opts.External, len(opts.Payload))
+	}
+	if opts.RecordID != nil {
+		record, _ = record.withID(opts.RecordID) // validated in main
+		description += fmt.Sprintf(" with ID % X", opts.RecordID)
+	}
+
+	// Record editor: replace one record and keep the rest of the message
+	if opts.EditRecord != "" {
+		if err := editRecord(card, opts.EditRecord, record); err != nil {
+			logger.Errorf("edit record %s failed: %v", opts.EditRecord, err)
+			return uidHex, false
+		}
+		logger.Infof("Replaced record %s with %s", opts.EditRecord, description)
+		return uidHex, true
+	}
+
+	// Append mode: add the record to the existing message without reformatting
+	if opts.Append {
+		if err := appendRecord(card, record); err != nil {
+			logger.Errorf("append record failed: %v", err)
+			return uidHex, false
+		}
+		logger.Infof("Appended %s", description)
+		return uidHex, true
+	}
+
+	// Cross-platform app tag: the URI record stays first for iOS, the AAR goes last
+	records := []ndefRecord{record}
+	if opts.AndroidApp != "" {
+		aar, _ := newAARRecord(opts.AndroidApp) // validated in main
+		records = append(records, aar)
+		description += " + Android app " + opts.AndroidApp
+	}
+
+	// Refuse up front when the message can't fit, before touching the tag
+	ndef := encodeNDEFMessage(records)
+	tagType, capacity, err := TagCapacity(card)
+	if err != nil {
+		logger.Errorf("capacity check: %v", err)
+		return uidHex, false
+	}
+	if err := checkCapacity(tagType, capacity, ndef); err != nil {
+		logger.Errorf("%v, use a larger tag", err)
+		return uidHex, false
+	}
+	logger.Debugf("%s: %d of %d data area bytes needed", tagType, len(ndef)+2, capacity)
+
+	// Refuse locked and read-only tags before the first write
+	area, err := wrapNDEFTLV(ndef)
+	if err != nil {
+		logger.Errorf("%v", err)
+		return uidHex, false
+	}
+	if err := checkWritable(card, tagType, 0, len(area)); err != nil {
+		logger.Errorf("%v", err)
+		return uidHex, false
+	}
+
+	// Refuse a UID mirror the tag can't take before the placeholder is written
+	mirrorOffset := -1
+	if mirror {
+		if bytes.Count(area, []byte(placeholder)) != 1 {
+			logger.Errorf("UID mirror placeholder %q is not unique in the written data, cannot place the mirror", placeholder)
+			return uidHex, false
+		}
+		mirrorOffset = bytes.Index(area, []byte(placeholder))
+		if err := checkUIDMirror(card, tagType, mirrorOffset, len(placeholder), uidHex); err != nil {
+			logger.Errorf("cannot set the UID mirror: %v", err)
+			return uidHex, false
+		}
+	}
 
-		// Wait until the card is removed before processing the next one
-		waitForCardRemoval(ctx, reader)
+	// Format the card as NFC Forum Type 2 format
+	logger.Infof("Formatting tag as NFC Forum Type 2...")
+	if err := formatType2Tag(card); err != nil {
+		logger.Errorf("format Type 2 tag failed: %v", err)
+		return uidHex, false
+	}
+	logger.Infof("Tag formatted successfully")
+
+	// Small delay after formatting as requested
+	time.Sleep(200 * time.Millisecond)
+
+	// Write NDEF directly to memory
+	if err := writeNDEFToType2(card, ndef); err != nil {
+		logger.Errorf("write NDEF failed: %v", err)
+		return uidHex, false
+	}
+	logger.Infof("Wrote %s to tag", description)
+
+	// Final QC gate: read back and compare what a phone would see
+	if opts.Validate {
+		if err := validateReadBack(card, record); err != nil {
+			logger.Errorf("❌ Read-back validation failed: %v", err)
+			return uidHex, false
+		}
+		logger.Infof("✅ Read-back validation passed: first record is the intended %s", record.Kind())
+	}
+
+	if mirror {
+		if err := setUIDMirror(card, tagType, mirrorOffset, uidHex); err != nil {
+			logger.Errorf("set UID mirror failed: %v", err)
+			return uidHex, false
+		}
+		mirrorPage, mirrorByte, _ := mirrorPlacement(mirrorOffset)
+		logger.Infof("UID mirror set at page %02X byte %d: the tag fills in its UID at read time", mirrorPage, mirrorByte)
+	}
+
+	if opts.CCReadOnly {
+		if err := setCCReadOnly(card); err != nil {
+			logger.Errorf("set CC read-only failed: %v", err)
+			return uidHex, false
+		}
+		logger.Infof("CC write access set to 0xF: phones treat the tag as read-only (memory not locked)")
+	}
+
+	if opts.CounterPwdProt != "" {
+		if err := setCounterPwdProt(card, tagType, opts.CounterPwdProt == "on"); err != nil {
+			logger.Errorf("set NFC_CNT_PWD_PROT failed: %v", err)
+			return uidHex, false
+		}
+		if opts.CounterPwdProt == "on" {
+			logger.Infof("NFC_CNT_PWD_PROT set: reading the NFC counter needs the password")
+		} else {
+			logger.Infof("NFC_CNT_PWD_PROT cleared: anyone can read the NFC counter")
+		}
+	}
+	return uidHex, true
+}
+
+// readerPollInterval is how often -wait-for-reader checks for a new reader
+const readerPollInterval = time.Second
+
+// listReaders returns the attached readers. With wait set it polls until one
+// appears, ctx is cancelled or timeout (0 = no limit) passes, for services
+// that start before the reader is enumerated.
+func listReaders(ctx context.Context, pcsc *scard.Context, wait bool, timeout time.Duration) ([]string, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	logged := false
+	for {
+		readers, err := pcsc.ListReaders()
+		if err == scard.ErrNoReadersAvailable && wait {
+			err = nil // pcsc-lite reports "no readers" as an error
+		}
+		if err != nil || len(readers) > 0 || !wait {
+			return readers, err
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, fmt.Errorf("no PC/SC reader appeared within %v", timeout)
+		}
+		if !logged {
+			logger.Infof("Waiting for a PC/SC reader to be connected...")
+			logged = true
+		}
+		if err := sleepContext(ctx, readerPollInterval); err != nil {
+			return nil, err
+		}
 	}
 }
 
-// waitForCardPresent blocks until the reader reports a present card
-func waitForCardPresent(ctx *scard.Context, reader string) {
+// connectCard connects to the tag on reader, retrying briefly on transient
+// errors. When another application already has the tag open (sharing
+// violation), it connects in shared mode instead of failing.
+func connectCard(pcsc *scard.Context, reader string) (*scard.Card, error) {
+	var card *scard.Card
+	var err error
+	for i := 0; i < 10; i++ {
+		card, err = pcsc.Connect(reader, scard.ShareExclusive, scard.ProtocolAny)
+		if errors.Is(err, scard.ErrSharingViolation) {
+			card, err = pcsc.Connect(reader, scard.ShareShared, scard.ProtocolAny)
+			if err == nil {
+				logger.Warnf("tag is in use by another application, connected in shared mode")
+			}
+		}
+		if err == nil {
+			return card, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil, err
+}
+
+// waitForCardPresent blocks until the reader reports a present card or ctx is cancelled
+func waitForCardPresent(ctx context.Context, pcsc *scard.Context, reader string) error {
 	rs := []scard.ReaderState{{Reader: reader, CurrentState: scard.StateUnaware}}
 	for {
-		_ = ctx.GetStatusChange(rs, time.Second)
+		_ = waitStatusChange(ctx, pcsc, rs, time.Second)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		st := rs[0].EventState
 		rs[0].CurrentState = st
 		if st&scard.StatePresent != 0 {
-			return
+			return nil
+		}
+		if err := sleepContext(ctx, 100*time.Millisecond); err != nil {
+			return err
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 }
 
-// waitForCardRemoval blocks until the reader reports no card present
-func waitForCardRemoval(ctx *scard.Context, reader string) {
+// errRemovalTimeout is returned by waitForCardRemoval when -removal-timeout expires
+var errRemovalTimeout = errors.New("tag not removed in time")
+
+// waitForCardRemoval blocks until the reader reports no card present or ctx
+// is cancelled, or returns errRemovalTimeout after timeout (0 = no limit)
+func waitForCardRemoval(ctx context.Context, pcsc *scard.Context, reader string, timeout time.Duration) error {
 	rs := []scard.ReaderState{{Reader: reader, CurrentState: scard.StateUnaware}}
+	start := time.Now()
 	for {
-		_ = ctx.GetStatusChange(rs, time.Second)
+		if timeout > 0 && time.Since(start) >= timeout {
+			return errRemovalTimeout
+		}
+		_ = waitStatusChange(ctx, pcsc, rs, time.Second)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		st := rs[0].EventState
 		rs[0].CurrentState = st
 		if st&scard.StatePresent == 0 {
-			return
+			return nil
+		}
+		if err := sleepContext(ctx, 150*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
+
+// waitStatusChange calls GetStatusChange and uses the PC/SC cancel mechanism
+// to wake the blocking call early when ctx is cancelled
+func waitStatusChange(ctx context.Context, pcsc *scard.Context, rs []scard.ReaderState, timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			pcsc.Cancel()
+		case <-done:
 		}
-		time.Sleep(150 * time.Millisecond)
+	}()
+
+	if err := pcsc.GetStatusChange(rs, timeout); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return ctx.Err()
+}
+
+// sleepContext pauses for d, returning early with ctx.Err() if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
 }
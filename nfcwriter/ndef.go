@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ndefRecord is one NDEF record. MB, ME and SR are derived when encoding, so
+// only the content fields need to be set when building a record.
+type ndefRecord struct {
+	CF      bool // Chunk Flag (decoded only, chunked messages are not re-encoded)
+	TNF     byte // Type Name Format
+	Type    []byte
+	ID      []byte
+	Payload []byte
+}
+
+// withID returns the record with its ID field set; a non-empty ID sets the IL
+// flag and is encoded after the type. IDs are at most 255 bytes.
+func (r ndefRecord) withID(id []byte) (ndefRecord, error) {
+	if len(id) > 0xFF {
+		return r, fmt.Errorf("record ID is %d bytes, the ID length field holds at most 255", len(id))
+	}
+	r.ID = append([]byte(nil), id...)
+	return r, nil
+}
+
+// newURIRecord builds a well-known URI record with the longest identifier
+// code prefix that matches (see uriPayload)
+func newURIRecord(uri string) ndefRecord {
+	return ndefRecord{TNF: 0x01, Type: []byte("U"), Payload: uriPayload(uri)}
+}
+
+// newTextRecord builds a well-known UTF-8 Text record with an IANA language code
+func newTextRecord(lang, text string) (ndefRecord, error) {
+	if len(lang) == 0 || len(lang) > 0x3F {
+		return ndefRecord{}, fmt.Errorf("language code %q must be 1-63 bytes", lang)
+	}
+	payload := append([]byte{byte(len(lang))}, lang...)
+	return ndefRecord{TNF: 0x01, Type: []byte("T"), Payload: append(payload, text...)}, nil
+}
+
+// uriPrefixes is the NFC Forum URI RTD identifier code table; the index is the code
+var uriPrefixes = []string{
+	"", "http://www.", "https://www.", "http://", "https://",
+	"tel:", "mailto:", "ftp://anonymous:anonymous@", "ftp://ftp.", "ftps://",
+	"sftp://", "smb://", "nfs://", "ftp://", "dav://",
+	"news:", "telnet://", "imap:", "rtsp://", "urn:",
+	"pop:", "sip:", "sips:", "tftp:", "btspp://",
+	"btl2cap://", "btgoep://", "tcpobex://", "irdaobex://", "file://",
+	"urn:epc:id:", "urn:epc:tag:", "urn:epc:pat:", "urn:epc:raw:", "urn:epc:",
+	"urn:nfc:",
+}
+
+// buildURIRecordWithCode builds a single-record NDEF message for a URI using
+// the given identifier code instead of the default one. The URI must start
+// with the code's prefix (any URI is accepted for 0x00, stored verbatim).
+func buildURIRecordWithCode(uri string, code byte) ([]byte, error) {
+	rec, err := newURIRecordWithCode(uri, code)
+	if err != nil {
+		return nil, err
+	}
+	return encodeNDEFMessage([]ndefRecord{rec}), nil
+}
+
+// newURIRecordWithCode builds a well-known URI record with an explicit identifier code
+func newURIRecordWithCode(uri string, code byte) (ndefRecord, error) {
+	if int(code) >= len(uriPrefixes) {
+		return ndefRecord{}, fmt.Errorf("URI identifier code 0x%02X out of range (0x00-0x%02X)", code, len(uriPrefixes)-1)
+	}
+	prefix := uriPrefixes[code]
+	if !strings.HasPrefix(uri, prefix) {
+		return ndefRecord{}, fmt.Errorf("URI %q does not start with %q (code 0x%02X)", uri, prefix, code)
+	}
+	payload := append([]byte{code}, uri[len(prefix):]...)
+
+	// The reader reconstructs prefix+remainder; make sure that gives back the URI
+	if got := uriPrefixes[payload[0]] + string(payload[1:]); got != uri {
+		return ndefRecord{}, fmt.Errorf("URI %q does not round-trip with code 0x%02X (reads back as %q)", uri, code, got)
+	}
+	return ndefRecord{TNF: 0x01, Type: []byte("U"), Payload: payload}, nil
+}
+
+// buildExternalRecord builds a single-record NDEF message holding an NFC
+// Forum external type record (TNF 0x04), e.g. "example.com:myapp". The
+// "urn:nfc:ext:" URI form is accepted and stored without the prefix.
+func buildExternalRecord(domainType string, payload []byte) ([]byte, error) {
+	rec, err := newExternalRecord(domainType, payload)
+	if err != nil {
+		return nil, err
+	}
+	return encodeNDEFMessage([]ndefRecord{rec}), nil
+}
+
+// newExternalRecord validates the domain:type name and builds an external type record
+func newExternalRecord(domainType string, payload []byte) (ndefRecord, error) {
+	name := strings.ToLower(strings.TrimPrefix(domainType, "urn:nfc:ext:"))
+	if err := validateExternalType(name); err != nil {
+		return ndefRecord{}, err
+	}
+	return ndefRecord{TNF: 0x04, Type: []byte(name), Payload: payload}, nil
+}
+
+// newAARRecord builds an Android Application Record: the external type
+// "android.com:pkg" with the application's package name as payload
+func newAARRecord(pkg string) (ndefRecord, error) {
+	if err := validateAndroidPackage(pkg); err != nil {
+		return ndefRecord{}, err
+	}
+	return ndefRecord{TNF: 0x04, Type: []byte("android.com:pkg"), Payload: []byte(pkg)}, nil
+}
+
+// validateAndroidPackage checks a package name has at least two dot-separated
+// segments, each a letter followed by letters, digits or underscores
+func validateAndroidPackage(pkg string) error {
+	segments := strings.Split(pkg, ".")
+	if len(segments) < 2 {
+		return fmt.Errorf("android package %q needs at least two segments, e.g. com.example.app", pkg)
+	}
+	for _, seg := range segments {
+		if seg == "" {
+			return fmt.Errorf("android package %q has an empty segment", pkg)
+		}
+		for i, c := range seg {
+			letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			if !letter && (i == 0 || !(c >= '0' && c <= '9' || c == '_')) {
+				return fmt.Errorf("invalid character %q in android package %q", c, pkg)
+			}
+		}
+	}
+	return nil
+}
+
+// buildAppLaunchMessage builds a message that opens an app on both
+// platforms: the universal link as a URI record first, the AAR last. iOS
+// only acts on the first record and needs an https:// universal link there;
+// Android starts (or offers from Play) the AAR's package wherever the AAR
+// sits, falling back to the URI when the app is not installed.
+func buildAppLaunchMessage(universalLink, androidPackage string) ([]byte, error) {
+	records, err := newAppLaunchRecords(universalLink, androidPackage)
+	if err != nil {
+		return nil, err
+	}
+	return encodeNDEFMessage(records), nil
+}
+
+// newAppLaunchRecords returns the records of buildAppLaunchMessage, in order
+func newAppLaunchRecords(universalLink, androidPackage string) ([]ndefRecord, error) {
+	if !strings.HasPrefix(universalLink, "https://") {
+		return nil, fmt.Errorf("universal link %q must use https:// for iOS", universalLink)
+	}
+	aar, err := newAARRecord(androidPackage)
+	if err != nil {
+		return nil, err
+	}
+	return []ndefRecord{newURIRecord(universalLink), aar}, nil
+}
+
+// validateExternalType checks an external type name is "domain:type" with the
+// characters allowed by the NFC Forum RTD specification
+func validateExternalType(name string) error {
+	domain, typ, ok := strings.Cut(name, ":")
+	if !ok {
+		return fmt.Errorf("external type %q must be of the form domain:type", name)
+	}
+	if domain == "" || typ == "" {
+		return fmt.Errorf("external type %q has an empty domain or type", name)
+	}
+	if len(name) > 255 {
+		return fmt.Errorf("external type is %d bytes, the type field holds at most 255", len(name))
+	}
+	for _, c := range domain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			return fmt.Errorf("invalid character %q in external type domain %q", c, domain)
+		}
+	}
+	for _, c := range typ {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune("()+,-:=@;$_!*'.", c)) {
+			return fmt.Errorf("invalid character %q in external type %q", c, typ)
+		}
+	}
+	return nil
+}
+
+// decodeNDEFRecords splits an NDEF message into records
+func decodeNDEFRecords(data []byte) ([]ndefRecord, error) {
+	var records []ndefRecord
+	offset := 0
+
+	for offset < len(data) {
+		header := data[offset]
+		rec := ndefRecord{CF: header&0x20 != 0, TNF: header & 0x07}
+		sr := header&0x10 != 0
+		il := header&0x08 != 0
+		me := header&0x40 != 0
+		offset++
+
+		if offset >= len(data) {
+			return nil, fmt.Errorf("record %d: missing type length", len(records)+1)
+		}
+		typeLength := int(data[offset])
+		offset++
+
+		var payloadLength int
+		if sr {
+			if offset >= len(data) {
+				return nil, fmt.Errorf("record %d: missing payload length", len(records)+1)
+			}
+			payloadLength = int(data[offset])
+			offset++
+		} else {
+			if offset+4 > len(data) {
+				return nil, fmt.Errorf("record %d: missing payload length", len(records)+1)
+			}
+			payloadLength = int(data[offset])<<24 | int(data[offset+1])<<16 |
+				int(data[offset+2])<<8 | int(data[offset+3])
+			offset += 4
+		}
+
+		idLength := 0
+		if il {
+			if offset >= len(data) {
+				return nil, fmt.Errorf("record %d: missing ID length", len(records)+1)
+			}
+			idLength = int(data[offset])
+			offset++
+		}
+
+		end := offset + typeLength + idLength + payloadLength
+		if payloadLength < 0 || end > len(data) {
+			return nil, fmt.Errorf("record %d: lengths exceed remaining data", len(records)+1)
+		}
+		rec.Type = data[offset : offset+typeLength]
+		offset += typeLength
+		rec.ID = data[offset : offset+idLength]
+		offset += idLength
+		rec.Payload = data[offset:end]
+		offset = end
+
+		records = append(records, rec)
+		if me {
+			return records, nil
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("empty NDEF message")
+	}
+	return nil, errors.New("message ended without ME flag")
+}
+
+// encodeNDEFRecord encodes a record, choosing the short record form when the payload fits in one byte
+func encodeNDEFRecord(rec ndefRecord, mb, me bool) []byte {
+	header := rec.TNF & 0x07
+	if mb {
+		header |= 0x80
+	}
+	if me {
+		header |= 0x40
+	}
+	sr := len(rec.Payload) <= 0xFF
+	if sr {
+		header |= 0x10
+	}
+	if len(rec.ID) > 0 {
+		header |= 0x08
+	}
+
+	out := []byte{header, byte(len(rec.Type))}
+	if sr {
+		out = append(out, byte(len(rec.Payload)))
+	} else {
+		n := len(rec.Payload)
+		out = append(out, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if len(rec.ID) > 0 {
+		out = append(out, byte(len(rec.ID)))
+	}
+	out = append(out, rec.Type...)
+	out = append(out, rec.ID...)
+	out = append(out, rec.Payload...)
+	return out
+}
+
+// encodeNDEFMessage encodes records in order, setting MB on the first and ME on the last
+func encodeNDEFMessage(records []ndefRecord) []byte {
+	var msg []byte
+	for i, rec := range records {
+		msg = append(msg, encodeNDEFRecord(rec, i == 0, i == len(records)-1)...)
+	}
+	return msg
+}
+
+// Kind returns a short lowercase name for the record type, matching the reader's record kinds
+func (r ndefRecord) Kind() string {
+	switch r.TNF {
+	case 0x00:
+		return "empty"
+	case 0x01:
+		switch string(r.Type) {
+		case "U":
+			return "uri"
+		case "T":
+			return "text"
+		case "Sp":
+			return "smartposter"
+		default:
+			return "wellknown"
+		}
+	case 0x02:
+		return "media"
+	case 0x03:
+		return "absolute-uri"
+	case 0x04:
+		return "external"
+	case 0x06:
+		return "unchanged"
+	case 0x07:
+		return "reserved"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// CFG1 ACCESS byte bits (NTAG213/215/216 datasheet 8.5.7)
+const (
+	accessCFGLCK        = 0x40 // Configuration pages permanently locked
+	accessNFCCntEn      = 0x10 // NFC counter enabled
+	accessNFCCntPwdProt = 0x08 // READ_CNT needs PWD_AUTH
+)
+
+// UID mirror (NTAG213/215/216 datasheet 8.7): with MIRROR_CONF 01 the tag
+// returns the 7-byte UID as 14 uppercase ASCII hex characters in place of
+// the user memory starting at MIRROR_PAGE, byte MIRROR_BYTE
+const (
+	uidMirrorToken = "{uid-mirror}" // URL placeholder the mirror fills in at read time
+	uidMirrorLen   = 14
+	mirrorConfUID  = 0x01
+)
+
+// readConfig reads CFG0 and CFG1 and refuses configuration the writer can't
+// change: pages behind AUTH0 (it has no password) or locked by CFGLCK
+func readConfig(card *scard.Card, tagType string) (cfg0, cfg1 []byte, err error) {
+	layout, ok := lockLayouts[tagType]
+	if !ok || layout.dynLockPage == 0 {
+		return nil, nil, fmt.Errorf("%s has no NTAG configuration pages", tagType)
+	}
+	cfg0Page, cfg1Page := byte(layout.dynLockPage+1), byte(layout.dynLockPage+2)
+
+	cfg0, err = readPage(card, cfg0Page)
+	if err != nil || len(cfg0) < 4 {
+		return nil, nil, fmt.Errorf("read CFG0: %v", err)
+	}
+	if cfg0[3] <= cfg1Page {
+		return nil, nil, fmt.Errorf("configuration is password protected from page %02X (AUTH0), the writer cannot authenticate", cfg0[3])
+	}
+	cfg1, err = readPage(card, cfg1Page)
+	if err != nil || len(cfg1) < 4 {
+		return nil, nil, fmt.Errorf("read CFG1: %v", err)
+	}
+	if cfg1[0]&accessCFGLCK != 0 {
+		return nil, nil, fmt.Errorf("configuration is permanently locked (CFGLCK set in ACCESS %02X)", cfg1[0])
+	}
+	return cfg0[:4], cfg1[:4], nil
+}
+
+// setCounterPwdProt sets or clears NFC_CNT_PWD_PROT with a read-modify-write
+// of CFG1, leaving the other ACCESS bits alone, and reads it back to confirm
+func setCounterPwdProt(card *scard.Card, tagType string, on bool) error {
+	_, cur, err := readConfig(card, tagType)
+	if err != nil {
+		return err
+	}
+	cfg1Page := byte(lockLayouts[tagType].dynLockPage + 2)
+
+	cfg1 := append([]byte(nil), cur...)
+	if on {
+		cfg1[0] |= accessNFCCntPwdProt
+	} else {
+		cfg1[0] &^= accessNFCCntPwdProt
+	}
+	if cfg1[0] != cur[0] {
+		if err := writePageAlternative(card, cfg1Page, cfg1); err != nil {
+			return fmt.Errorf("write CFG1: %w", err)
+		}
+	}
+
+	got, err := readPage(card, cfg1Page)
+	if err != nil {
+		return fmt.Errorf("read CFG1 back: %w", err)
+	}
+	if len(got) < 4 || got[0] != cfg1[0] {
+		return fmt.Errorf("CFG1 reads back % X, want ACCESS %02X", got, cfg1[0])
+	}
+	if on && got[0]&accessNFCCntEn == 0 {
+		logger.Warnf("NFC counter is disabled (NFC_CNT_EN 0), the protection applies once it is enabled")
+	}
+	return nil
+}
+
+// mirrorPlacement returns the page and byte the UID mirror starts at for
+// offset, a data area byte counted from page 4, and the page it ends in
+func mirrorPlacement(offset int) (mirrorPage, mirrorByte, lastPage int) {
+	mirrorPage, mirrorByte = 4+offset/4, offset%4
+	return mirrorPage, mirrorByte, mirrorPage + (mirrorByte+uidMirrorLen-1)/4
+}
+
+// checkUIDMirror refuses a UID mirror at offset that setUIDMirror could not
+// set: a placeholder or UID that isn't 14 characters, a tag without writable
+// configuration pages, or a mirror running past the last user page. It runs
+// before the tag is formatted, so a refused mirror leaves the tag untouched.
+func checkUIDMirror(card *scard.Card, tagType string, offset, placeholderLen int, uidHex string) error {
+	if placeholderLen != uidMirrorLen || len(uidHex) != uidMirrorLen {
+		return fmt.Errorf("placeholder reserves %d bytes for a %d character UID, the UID mirror renders %d", placeholderLen, len(uidHex), uidMirrorLen)
+	}
+	if _, _, err := readConfig(card, tagType); err != nil {
+		return err
+	}
+	layout := lockLayouts[tagType]
+	if _, _, lastPage := mirrorPlacement(offset); lastPage > layout.lastUserPage {
+		return fmt.Errorf("mirror would run to page %02X, past the last user page %02X", lastPage, layout.lastUserPage)
+	}
+	return nil
+}
+
+// setUIDMirror points the UID mirror at offset, the data area byte (from
+// page 4) where the URL placeholder was written, then reads the placeholder
+// back to check the tag now renders uidHex there. checkUIDMirror must have
+// accepted the mirror.
+func setUIDMirror(card *scard.Card, tagType string, offset int, uidHex string) error {
+	cur, _, err := readConfig(card, tagType)
+	if err != nil {
+		return err
+	}
+	layout := lockLayouts[tagType]
+	mirrorPage, mirrorByte, lastPage := mirrorPlacement(offset)
+
+	cfg0Page := byte(layout.dynLockPage + 1)
+	cfg0 := append([]byte(nil), cur...)
+	cfg0[0] = mirrorConfUID<<6 | byte(mirrorByte)<<4 | cur[0]&0x0F // Keep STRG_MOD_EN and RFUI bits
+	cfg0[2] = byte(mirrorPage)
+	if !bytes.Equal(cfg0, cur) {
+		if err := writePageAlternative(card, cfg0Page, cfg0); err != nil {
+			return fmt.Errorf("write CFG0: %w", err)
+		}
+	}
+
+	var rendered []byte
+	for page := mirrorPage; page <= lastPage; page++ {
+		data, err := readPage(card, byte(page))
+		if err != nil {
+			return fmt.Errorf("read page %02X back: %w", page, err)
+		}
+		rendered = append(rendered, data...)
+	}
+	if got := string(rendered[mirrorByte : mirrorByte+uidMirrorLen]); got != uidHex {
+		return fmt.Errorf("mirror at page %02X byte %d reads %q, want %q", mirrorPage, mirrorByte, got, uidHex)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestMirrorPlacement(t *testing.T) {
+	tests := []struct {
+		offset                       int
+		mirrorPage, mirrorByte, last int
+	}{
+		{0, 4, 0, 7},   // 14 characters: pages 4-6 and 2 bytes of page 7
+		{2, 4, 2, 7},   // Ends on the last byte of page 7
+		{3, 4, 3, 8},   // One byte more spills into page 8
+		{13, 7, 1, 10}, // Typical URL placeholder position
+		{0x8D * 4, 0x91, 0, 0x94},
+	}
+	for _, tt := range tests {
+		page, b, last := mirrorPlacement(tt.offset)
+		if page != tt.mirrorPage || b != tt.mirrorByte || last != tt.last {
+			t.Errorf("mirrorPlacement(%d) = %02X byte %d to %02X, want %02X byte %d to %02X",
+				tt.offset, page, b, last, tt.mirrorPage, tt.mirrorByte, tt.last)
+		}
+	}
+}
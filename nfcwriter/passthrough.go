@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// errTagTimeout is returned by inDataExchange when the tag did not answer
+var errTagTimeout = errors.New("tag did not respond")
+
+// errTagRejected is returned by inDataExchange when the PN53x reports another
+// error status for the tag command, typically a NAK
+var errTagRejected = errors.New("tag command failed")
+
+// inDataExchange sends a native tag command (e.g. WRITE A2, GET_VERSION 60,
+// FAST_READ 3A) to target 1 through the PN53x InDataExchange pseudo-APDU
+// FF 00 00 00 Lc D4 40 01 <cmd>, which ACR122U readers need for commands
+// that have no PC/SC equivalent, and returns the tag's answer without the
+// D5 41 <status> framing
+func inDataExchange(card *scard.Card, cmd []byte) ([]byte, error) {
+	apdu := append([]byte{0xFF, 0x00, 0x00, 0x00, byte(len(cmd) + 3), 0xD4, 0x40, 0x01}, cmd...)
+	resp, err := transmit(card, apdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 3 || resp[0] != 0xD5 || resp[1] != 0x41 {
+		return nil, fmt.Errorf("unexpected InDataExchange response % X", resp)
+	}
+	switch resp[2] & 0x3F { // Bit 6 (MI) only flags more data to come
+	case 0x00:
+		return resp[3:], nil
+	case 0x01: // PN53x status 01: timeout
+		return nil, errTagTimeout
+	default:
+		return nil, fmt.Errorf("%w, PN53x status %02X", errTagRejected, resp[2])
+	}
+}
+
+// tagNAKed reports whether the tag itself refused or ignored a command
+// inDataExchange passed through, rather than the reader failing
+func tagNAKed(err error) bool {
+	return errors.Is(err, errTagTimeout) || errors.Is(err, errTagRejected)
+}
+
+// reselectTag re-activates the tag after it NAKed a native command. NTAG and
+// Ultralight drop to IDLE on a NAK and ignore every command until they are
+// selected again, which reconnecting with a card reset does.
+func reselectTag(card *scard.Card) {
+	err := card.Reconnect(scard.ShareExclusive, scard.ProtocolAny, scard.ResetCard)
+	if errors.Is(err, scard.ErrSharingViolation) {
+		err = card.Reconnect(scard.ShareShared, scard.ProtocolAny, scard.ResetCard)
+	}
+	if err != nil {
+		logger.Debugf("Re-selecting the tag after a NAK failed: %v", describePCSCError(err))
+	}
+}
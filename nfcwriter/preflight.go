@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// pcscRID is the PC/SC registered application provider id that readers put
+// in the ATR of contactless storage cards (PC/SC part 3, 3.1.3.2.3)
+var pcscRID = []byte{0xA0, 0x00, 0x00, 0x03, 0x06}
+
+// pcscCardNames maps the card name bytes that follow the RID and standard
+// byte to the tag family, and whether the writer can write it
+var pcscCardNames = map[uint16]struct {
+	name  string
+	type2 bool
+}{
+	0x0001: {"MIFARE Classic 1K", false},
+	0x0002: {"MIFARE Classic 4K", false},
+	0x0003: {"MIFARE Ultralight / NTAG", true},
+	0x0026: {"MIFARE Mini", false},
+	0x003A: {"MIFARE Ultralight C", true},
+	0x0036: {"MIFARE Plus 2K (SL1)", false},
+	0x0037: {"MIFARE Plus 4K (SL1)", false},
+	0x0038: {"MIFARE Plus 2K (SL2)", false},
+	0x0039: {"MIFARE Plus 4K (SL2)", false},
+	0xF004: {"Topaz/Jewel", false},
+	0xF011: {"FeliCa 212K", false},
+	0xF012: {"FeliCa 424K", false},
+}
+
+// atrCardName returns the tag family named in a PC/SC part 3 ATR
+// (3B 8F 80 01 80 4F 0C <RID> SS NN NN ...); ok is false for other ATRs,
+// such as ISO 14443-4 cards that report their historical bytes instead
+func atrCardName(atr []byte) (name string, type2, ok bool) {
+	i := bytes.Index(atr, pcscRID)
+	if i < 0 || len(atr) < i+len(pcscRID)+3 {
+		return "", false, false
+	}
+	nn := uint16(atr[i+len(pcscRID)+1])<<8 | uint16(atr[i+len(pcscRID)+2])
+	card, known := pcscCardNames[nn]
+	if !known {
+		return "", false, false
+	}
+	return card.name, card.type2, true
+}
+
+// checkTagFamily refuses tags this writer can't write before the first
+// write: families the reader names in the ATR (MIFARE Classic, FeliCa, ...)
+// and tags whose CC page can't be read. A readable page without the NDEF
+// magic or a GET_VERSION answer is only warned about, because a blank
+// Type 2 tag looks like that. A plain Ultralight NAKs GET_VERSION and goes
+// idle, so the tag is re-selected before it is written.
+func checkTagFamily(card *scard.Card) error {
+	if status, err := card.Status(); err == nil {
+		if name, type2, ok := atrCardName(status.Atr); ok {
+			if !type2 {
+				return fmt.Errorf("unsupported tag type for this writer: %s (use an NFC Forum Type 2 tag such as NTAG213/215/216 or MIFARE Ultralight)", name)
+			}
+			logger.Debugf("ATR names the tag %s", name)
+			return nil
+		}
+	}
+
+	cc, err := readPage(card, 0x03)
+	if err != nil || len(cc) < 4 {
+		return fmt.Errorf("unsupported tag type for this writer: page 3 cannot be read, so this is not an NFC Forum Type 2 tag (%v)", err)
+	}
+	if cc[0] == 0xE1 {
+		return nil
+	}
+	v, err := inDataExchange(card, []byte{0x60})
+	if err == nil && len(v) >= 3 && v[1] == 0x04 && (v[2] == 0x03 || v[2] == 0x04) {
+		return nil // NXP Ultralight (03) or NTAG (04) product type
+	}
+	if tagNAKed(err) {
+		reselectTag(card)
+	}
+	logger.Warnf("Cannot confirm an NFC Forum Type 2 tag (no NDEF CC, no GET_VERSION answer), writing anyway")
+	return nil
+}
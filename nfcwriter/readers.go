@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ebfe/scard"
+)
+
+// readerCardStatus describes whether a card is present on each reader
+func readerCardStatus(pcsc *scard.Context, readers []string) []string {
+	rs := make([]scard.ReaderState, len(readers))
+	for i, r := range readers {
+		rs[i] = scard.ReaderState{Reader: r, CurrentState: scard.StateUnaware}
+	}
+	status := make([]string, len(readers))
+	if err := pcsc.GetStatusChange(rs, 0); err != nil && err != scard.ErrTimeout {
+		for i := range status {
+			status[i] = "status unknown"
+		}
+		return status
+	}
+	for i, r := range rs {
+		switch {
+		case r.EventState&scard.StatePresent != 0:
+			status[i] = "card present"
+		case r.EventState&(scard.StateUnavailable|scard.StateUnknown) != 0:
+			status[i] = "unavailable"
+		default:
+			status[i] = "empty"
+		}
+	}
+	return status
+}
+
+// printReaderList prints each attached reader with its index and card status
+// on stdout, for -list-readers, and returns the exit code: 0 when at least
+// one reader is attached, 1 otherwise
+func printReaderList(pcsc *scard.Context) int {
+	readers, err := pcsc.ListReaders()
+	if err != nil && err != scard.ErrNoReadersAvailable {
+		logger.Errorf("pcsc ListReaders: %v", describePCSCError(err))
+		return 1
+	}
+	if len(readers) == 0 {
+		logger.Errorf("no PC/SC readers found")
+		return 1
+	}
+	status := readerCardStatus(pcsc, readers)
+	for i, r := range readers {
+		fmt.Printf("[%d] %s (%s)\n", i, r, status[i])
+	}
+	return 0
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// selfTestCase is one -selftest input: the records to encode and what each
+// record must decode back to ("uri:<uri>", "text:<lang>:<text>" or the raw kind)
+type selfTestCase struct {
+	name    string
+	records func() ([]ndefRecord, error)
+	want    []string
+}
+
+// uriCase encodes a URI with an explicit identifier code
+func uriCase(uri string, code byte) func() ([]ndefRecord, error) {
+	return func() ([]ndefRecord, error) {
+		rec, err := newURIRecordWithCode(uri, code)
+		return []ndefRecord{rec}, err
+	}
+}
+
+// autoURICase encodes a URI with the identifier code newURIRecord picks and
+// checks it picked code
+func autoURICase(uri string, code byte) func() ([]ndefRecord, error) {
+	return func() ([]ndefRecord, error) {
+		rec := newURIRecord(uri)
+		if rec.Payload[0] != code {
+			return nil, fmt.Errorf("identifier code 0x%02X, want 0x%02X", rec.Payload[0], code)
+		}
+		return []ndefRecord{rec}, nil
+	}
+}
+
+var selfTestCases = []selfTestCase{
+	{"uri default https", func() ([]ndefRecord, error) {
+		return []ndefRecord{newURIRecord("https://dnd.qrand.me/r/04A1B2C3D4E5F6")}, nil
+	}, []string{"uri:https://dnd.qrand.me/r/04A1B2C3D4E5F6"}},
+	{"uri auto http://", autoURICase("http://example.com", 0x03), []string{"uri:http://example.com"}},
+	{"uri auto tel:", autoURICase("tel:+15551234567", 0x05), []string{"uri:tel:+15551234567"}},
+	{"uri auto urn:nfc:", autoURICase("urn:nfc:sn:handover", 0x23), []string{"uri:urn:nfc:sn:handover"}},
+	{"uri auto smsto:", autoURICase("smsto:123", 0x00), []string{"uri:smsto:123"}},
+	{"uri auto whatsapp:", autoURICase("whatsapp://send?phone=15551234567", 0x00), []string{"uri:whatsapp://send?phone=15551234567"}},
+	{"uri auto intent:", autoURICase("intent://scan/#Intent;scheme=zxing;end", 0x00), []string{"uri:intent://scan/#Intent;scheme=zxing;end"}},
+	{"uri auto bare host", autoURICase("example.com/t/1", 0x04), []string{"uri:https://example.com/t/1"}},
+	{"uri auto host:port", autoURICase("example.com:8080/x", 0x04), []string{"uri:https://example.com:8080/x"}},
+	{"uri https://www.", uriCase("https://www.example.com/a?b=c", 0x02), []string{"uri:https://www.example.com/a?b=c"}},
+	{"uri http://", uriCase("http://example.com", 0x03), []string{"uri:http://example.com"}},
+	{"uri tel:", uriCase("tel:+15551234567", 0x05), []string{"uri:tel:+15551234567"}},
+	{"uri mailto:", uriCase("mailto:someone@example.com", 0x06), []string{"uri:mailto:someone@example.com"}},
+	{"uri urn:nfc:", uriCase("urn:nfc:sn:handover", 0x23), []string{"uri:urn:nfc:sn:handover"}},
+	{"uri verbatim", uriCase("geo:52.37,4.89", 0x00), []string{"uri:geo:52.37,4.89"}},
+	{"text", func() ([]ndefRecord, error) {
+		rec, err := newTextRecord("en", "Hello, NFC")
+		return []ndefRecord{rec}, err
+	}, []string{"text:en:Hello, NFC"}},
+	{"text utf-8", func() ([]ndefRecord, error) {
+		rec, err := newTextRecord("de", "Grüße ✓")
+		return []ndefRecord{rec}, err
+	}, []string{"text:de:Grüße ✓"}},
+	{"multi-record", func() ([]ndefRecord, error) {
+		text, err := newTextRecord("en", "Scan me")
+		if err != nil {
+			return nil, err
+		}
+		ext, err := newExternalRecord("example.com:tag", []byte{0x01, 0x02})
+		return []ndefRecord{newURIRecord("https://example.com"), text, ext}, err
+	}, []string{"uri:https://example.com", "text:en:Scan me", "external"}},
+	{"app launch (URI + AAR)", func() ([]ndefRecord, error) {
+		return newAppLaunchRecords("https://example.com/app", "com.example.app")
+	}, []string{"uri:https://example.com/app", "external"}},
+	{"record IDs", func() ([]ndefRecord, error) {
+		uri, err := newURIRecord("https://example.com").withID([]byte("0"))
+		if err != nil {
+			return nil, err
+		}
+		ext, err := newExternalRecord("example.com:carrier", []byte{0xAA})
+		if err != nil {
+			return nil, err
+		}
+		ext, err = ext.withID([]byte{0x00, 0xFF, 0x80}) // Binary ID
+		return []ndefRecord{uri, ext}, err
+	}, []string{"uri:https://example.com", "external"}},
+	{"largest single-byte TLV", func() ([]ndefRecord, error) {
+		// 3-byte short record header + "U" + 250-byte payload = 254-byte message
+		return []ndefRecord{{TNF: 0x01, Type: []byte("U"), Payload: append([]byte{0x04}, bytes.Repeat([]byte("a"), 249)...)}}, nil
+	}, []string{"uri:https://" + string(bytes.Repeat([]byte("a"), 249))}},
+	{"smallest 3-byte TLV", func() ([]ndefRecord, error) {
+		// 3-byte short record header + "U" + 251-byte payload = 255-byte message
+		return []ndefRecord{{TNF: 0x01, Type: []byte("U"), Payload: append([]byte{0x04}, bytes.Repeat([]byte("b"), 250)...)}}, nil
+	}, []string{"uri:https://" + string(bytes.Repeat([]byte("b"), 250))}},
+	{"long record in 3-byte TLV", func() ([]ndefRecord, error) {
+		rec, err := newTextRecord("en", string(bytes.Repeat([]byte("c"), 600)))
+		return []ndefRecord{rec}, err
+	}, []string{"text:en:" + string(bytes.Repeat([]byte("c"), 600))}},
+}
+
+// describeRecord renders a decoded record in the selfTestCase.want form
+func describeRecord(rec ndefRecord) (string, error) {
+	switch rec.Kind() {
+	case "uri":
+		uri, err := decodeURIPayload(rec.Payload)
+		return "uri:" + uri, err
+	case "text":
+		lang, text, err := decodeTextPayload(rec.Payload)
+		return "text:" + lang + ":" + text, err
+	default:
+		return rec.Kind(), nil
+	}
+}
+
+// runSelfTestCase encodes the records, wraps them as a TLV, walks the TLV back
+// out of the resulting pages and decodes the message
+func runSelfTestCase(tc selfTestCase) error {
+	records, err := tc.records()
+	if err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+	msg := encodeNDEFMessage(records)
+	area, err := wrapNDEFTLV(msg)
+	if err != nil {
+		return fmt.Errorf("wrap: %w", err)
+	}
+
+	readPage := func(page byte) ([]byte, error) {
+		off := int(page-0x04) * 4
+		return area[off : off+4], nil
+	}
+	got, _, err := findNDEFTLV(readPage, len(area))
+	if err != nil {
+		return fmt.Errorf("unwrap: %w", err)
+	}
+	if !bytes.Equal(got, msg) {
+		return fmt.Errorf("TLV value % X, encoded % X", got, msg)
+	}
+
+	// The same message behind a Lock Control TLV, laid out as the editor
+	// writes it: NULL TLVs up to the page the NDEF TLV starts on
+	control := []byte{0x01, 0x03, 0xA0, 0x10, 0x44}
+	prefixed := append(append(control, make([]byte, controlAreaSize(len(control))-len(control))...), area...)
+	readPrefixed := func(page byte) ([]byte, error) {
+		off := int(page-0x04) * 4
+		return prefixed[off : off+4], nil
+	}
+	inner, start, err := findNDEFTLV(readPrefixed, len(prefixed))
+	if err != nil {
+		return fmt.Errorf("unwrap after Lock Control TLV: %w", err)
+	}
+	if !bytes.Equal(inner, msg) || ndefStartPage(start) != ndefStartPage(len(control)) {
+		return fmt.Errorf("after Lock Control TLV: NDEF TLV at data area byte %d, want page %d", start, ndefStartPage(len(control)))
+	}
+
+	decoded, err := decodeNDEFRecords(got)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if len(decoded) != len(tc.want) {
+		return fmt.Errorf("decoded %d records, want %d", len(decoded), len(tc.want))
+	}
+	for i, rec := range decoded {
+		desc, err := describeRecord(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if desc != tc.want[i] {
+			return fmt.Errorf("record %d: got %q, want %q", i+1, desc, tc.want[i])
+		}
+		if !bytes.Equal(rec.ID, records[i].ID) {
+			return fmt.Errorf("record %d: ID % X, encoded % X", i+1, rec.ID, records[i].ID)
+		}
+		if !bytes.Equal(rec.Payload, records[i].Payload) || !bytes.Equal(rec.Type, records[i].Type) || rec.TNF != records[i].TNF {
+			return fmt.Errorf("record %d: fields differ after round trip", i+1)
+		}
+	}
+	return nil
+}
+
+// runSelfTest round-trips every sample through the codec, printing PASS/FAIL
+// per case, and reports whether all passed
+func runSelfTest() bool {
+	failed := 0
+	for _, tc := range selfTestCases {
+		if err := runSelfTestCase(tc); err != nil {
+			failed++
+			fmt.Printf("FAIL  %-26s %v\n", tc.name, err)
+			continue
+		}
+		fmt.Printf("PASS  %s\n", tc.name)
+	}
+	fmt.Printf("%d/%d cases passed\n", len(selfTestCases)-failed, len(selfTestCases))
+	return failed == 0
+}
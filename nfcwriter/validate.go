@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/ebfe/scard"
+)
+
+// validateReadBack reads the tag back the way a phone would and checks that
+// the first record carries the intended content. URIs and text are compared
+// after decoding, so only the content a phone acts on has to match.
+func validateReadBack(card *scard.Card, want ndefRecord) error {
+	cc, err := readCapabilityContainer(card)
+	if err != nil {
+		return err
+	}
+	msg, _, err := readNDEFMessage(card, int(cc[2])*8)
+	if err != nil {
+		return fmt.Errorf("read NDEF: %w", err)
+	}
+	records, err := decodeNDEFRecords(msg)
+	if err != nil {
+		return fmt.Errorf("parse NDEF: %w", err)
+	}
+	if len(records) == 0 {
+		return errors.New("NDEF message has no records")
+	}
+	got := records[0]
+	if got.Kind() != want.Kind() {
+		return fmt.Errorf("first record is %s, expected %s", got.Kind(), want.Kind())
+	}
+	if !bytes.Equal(got.ID, want.ID) {
+		return fmt.Errorf("record ID reads back as % X, expected % X", got.ID, want.ID)
+	}
+
+	switch want.Kind() {
+	case "uri":
+		gotURI, err := decodeURIPayload(got.Payload)
+		if err != nil {
+			return err
+		}
+		wantURI, _ := decodeURIPayload(want.Payload)
+		if gotURI != wantURI {
+			return fmt.Errorf("URI reads back as %q, expected %q", gotURI, wantURI)
+		}
+	case "text":
+		gotLang, gotText, err := decodeTextPayload(got.Payload)
+		if err != nil {
+			return err
+		}
+		wantLang, wantText, _ := decodeTextPayload(want.Payload)
+		if gotText != wantText || gotLang != wantLang {
+			return fmt.Errorf("text reads back as %q [%s], expected %q [%s]", gotText, gotLang, wantText, wantLang)
+		}
+	default:
+		if got.TNF != want.TNF || !bytes.Equal(got.Type, want.Type) || !bytes.Equal(got.Payload, want.Payload) {
+			return fmt.Errorf("record reads back as TNF %d type %q (%d bytes), expected TNF %d type %q (%d bytes)",
+				got.TNF, got.Type, len(got.Payload), want.TNF, want.Type, len(want.Payload))
+		}
+	}
+	return nil
+}
+
+// decodeURIPayload expands a URI record payload to the full URI
+func decodeURIPayload(payload []byte) (string, error) {
+	if len(payload) == 0 {
+		return "", errors.New("empty URI payload")
+	}
+	if int(payload[0]) >= len(uriPrefixes) {
+		return "", fmt.Errorf("unknown URI identifier code 0x%02X", payload[0])
+	}
+	return uriPrefixes[payload[0]] + string(payload[1:]), nil
+}
+
+// decodeTextPayload returns the language code and text of a Text record payload
+func decodeTextPayload(payload []byte) (string, string, error) {
+	if len(payload) == 0 {
+		return "", "", errors.New("empty text payload")
+	}
+	langLen := int(payload[0] & 0x3F)
+	if 1+langLen > len(payload) {
+		return "", "", errors.New("text payload shorter than its language code")
+	}
+	lang, text := string(payload[1:1+langLen]), payload[1+langLen:]
+	if payload[0]&0x80 == 0 {
+		return lang, string(text), nil
+	}
+
+	// UTF-16, big-endian unless a BOM says otherwise
+	if len(text)%2 != 0 {
+		return "", "", errors.New("odd-length UTF-16 text")
+	}
+	littleEndian := false
+	if len(text) >= 2 && (text[0] == 0xFF && text[1] == 0xFE || text[0] == 0xFE && text[1] == 0xFF) {
+		littleEndian = text[0] == 0xFF
+		text = text[2:]
+	}
+	units := make([]uint16, len(text)/2)
+	for i := range units {
+		if littleEndian {
+			units[i] = uint16(text[2*i]) | uint16(text[2*i+1])<<8
+		} else {
+			units[i] = uint16(text[2*i])<<8 | uint16(text[2*i+1])
+		}
+	}
+	return lang, string(utf16.Decode(units)), nil
+}
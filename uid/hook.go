@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// onReadCommand builds the OnReadCommand process for one read. On Unix the
+// command runs in sh with the UID as $1; on Windows cmd gets the UID as an
+// extra argument.
+func onReadCommand(ctx context.Context, command string, event UIDEvent) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command, event.Formatted)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command, "nfc-uid", event.Formatted)
+	}
+	cmd.Env = append(os.Environ(),
+		"NFC_UID="+event.Formatted,
+		"NFC_UID_HEX="+strings.ToUpper(hex.EncodeToString(event.UID)),
+		"NFC_READER="+event.Reader,
+		"NFC_URL="+event.URL,
+	)
+	return cmd
+}
+
+// runOnReadCommand starts OnReadCommand for the event in the background so
+// a slow command never holds up the read loop, and kills it after
+// OnReadTimeout. The exit status is logged at debug level.
+func (s *NFCService) runOnReadCommand(config Config, event UIDEvent) {
+	timeout := config.OnReadTimeout
+	if timeout <= 0 {
+		timeout = DefaultConfig().OnReadTimeout
+	}
+	s.hooks.Add(1)
+	go func() {
+		defer s.hooks.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		err := onReadCommand(ctx, config.OnReadCommand, event).Run()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			s.logger.Warnf("On-read command for %s killed after %v", event.Formatted, timeout)
+		case errors.As(err, &exitErr):
+			s.logger.Debugf("On-read command for %s exited with status %d after %v", event.Formatted, exitErr.ExitCode(), elapsed)
+		case err != nil:
+			s.logger.Warnf("On-read command for %s failed: %v", event.Formatted, err)
+		default:
+			s.logger.Debugf("On-read command for %s exited with status 0 after %v", event.Formatted, elapsed)
+		}
+	}()
+}
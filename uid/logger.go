@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// logLevel orders diagnostic messages by severity
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+	levelOff // Discard everything
+)
+
+// leveledLogger writes diagnostics at or above its level. Diagnostics go to
+// stderr so tag content printed on stdout can be piped on its own.
+type leveledLogger struct {
+	level logLevel
+	out   *log.Logger
+}
+
+// newLeveledLogger creates a logger writing to w with the given prefix and log flags
+func newLeveledLogger(w io.Writer, prefix string, flags int, level logLevel) *leveledLogger {
+	return &leveledLogger{level: level, out: log.New(w, prefix, flags)}
+}
+
+// Enabled reports whether messages at level are written
+func (l *leveledLogger) Enabled(level logLevel) bool {
+	return l.level != levelOff && level >= l.level
+}
+
+func (l *leveledLogger) logf(level logLevel, tag, format string, args ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.out.Output(3, tag+fmt.Sprintf(format, args...))
+}
+
+// Debugf logs detail that is only useful when troubleshooting
+func (l *leveledLogger) Debugf(format string, args ...any) {
+	l.logf(levelDebug, "DEBUG ", format, args...)
+}
+
+// Infof logs normal progress messages
+func (l *leveledLogger) Infof(format string, args ...any) {
+	l.logf(levelInfo, "", format, args...)
+}
+
+// Warnf logs recoverable problems
+func (l *leveledLogger) Warnf(format string, args ...any) {
+	l.logf(levelWarn, "WARN ", format, args...)
+}
+
+// Errorf logs failures of the current operation
+func (l *leveledLogger) Errorf(format string, args ...any) {
+	l.logf(levelError, "ERROR ", format, args...)
+}
+
+// parseLogLevel converts "debug", "info", "warn", "error" or "off" to a level
+func parseLogLevel(name string) (logLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return levelDebug, nil
+	case "info":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	case "off", "none":
+		return levelOff, nil
+	}
+	return levelInfo, fmt.Errorf("unknown log level %q (debug, info, warn, error, off)", name)
+}
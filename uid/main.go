@@ -1,40 +1,83 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/ebfe/scard"
 )
 
 // Service configuration
 type Config struct {
-	ServiceName   string
-	ReadInterval  time.Duration
-	RetryInterval time.Duration
-	MaxRetries    int
-	AutoPaste     bool
-	UIDFormat     string // "hex", "hex-reversed", "decimal"
-	LogLevel      string // "info", "debug", "error"
+	ServiceName      string
+	ReadInterval     time.Duration
+	RetryInterval    time.Duration
+	MaxRetries       int
+	AutoPaste        bool
+	NoClipboard      bool          // Type the UID as keystrokes instead of copying it to the clipboard
+	RestoreClipboard bool          // Put the previous clipboard text back after pasting the UID
+	PasteDelay       time.Duration // Wait before pasting or typing and again before Enter
+	UIDFormat        string        // "hex", "hex-reversed", "decimal"
+	DecimalWidth     int           // Zero-pad decimal output to this many digits (0 = no padding)
+	UIDBytes         string        // UID bytes to format: "all" (default), "firstN" or "lastN", e.g. "last4"
+	MinUIDLength     int           // Treat shorter UIDs as a partial read and read again (0 = accept any length)
+	Sinks            []string      // Enabled output sinks, see sinkNames
+	OutputFile       string        // File the "file" sink appends JSON lines to
+	WebhookURL       string        // URL the "webhook" sink POSTs events to
+	SinkMinInterval  time.Duration // Publish a UID to network sinks at most once per interval (0 = no limit)
+	SinkQueueSize    int           // Queue up to this many events per file/webhook sink while it fails (0 = no queue)
+	SinkQueueMaxAge  time.Duration // Drop queued events older than this (0 = no limit)
+	SinkQueueDir     string        // Keep queued events in files here so they survive a restart ("" = memory only)
+	UIDAttrib        scard.Attrib  // SCardGetAttrib id to read the UID from when FF CA fails (0 = off)
+	URLTemplate      string        // Output this URL with {uid} replaced instead of the bare UID ("" = UID)
+	LogLevel         string        // "debug", "info", "warn", "error" or "off" (silent, the default)
+	TraceAPDU        bool          // Write every APDU exchange to stderr, independent of LogLevel
+	OnReadCommand    string        // Shell command run in the background per read, UID as $1 and in NFC_UID ("" = off)
+	OnReadTimeout    time.Duration // Kill OnReadCommand after this long
+
+	WaitForReader     bool          // Poll until a reader is connected instead of failing at startup
+	ReaderWaitTimeout time.Duration // With WaitForReader, give up after this long (0 = wait forever)
+
+	// Readers overrides format, sinks and URL per reader, keyed by reader
+	// name or a substring of it; the fields above are the defaults
+	Readers map[string]ReaderConfig
 }
 
 // NFCService represents the background NFC UID service
 type NFCService struct {
-	config  Config
-	ctx     *scard.Context
-	reader  string
-	running bool
-	logger  *log.Logger
+	config Config
+	ctx    *scard.Context
+	reader string
+	logger *leveledLogger
+
+	mu     sync.Mutex         // Guards cancel, and ctx while Start is not running
+	cancel context.CancelFunc // Cancels the running Start loop, nil when Start is not running
+
+	// OnPresent and OnRemoved, when set, are called on presence transitions:
+	// once when a new distinct UID arrives and once when that tag leaves the
+	// reader. They are independent of the clipboard/paste handling and run on
+	// the service loop goroutine, so they should return quickly.
+	OnPresent func(uid []byte)
+	OnRemoved func(uid []byte)
+
+	present     []byte                 // UID of the tag currently on the reader, nil if none
+	sinks       []namedSink            // Outputs each UID is fanned out to
+	readerSinks map[string][]namedSink // Built-in sinks of reader overrides, by Readers key
+	queues      []*queuedSink          // Queued sinks, drained on Stop
+	hooks       sync.WaitGroup         // Running OnReadCommand processes, waited for on Stop
 }
 
 // Default configuration
@@ -45,21 +88,25 @@ func DefaultConfig() Config {
 		RetryInterval: 2 * time.Second,
 		MaxRetries:    10,
 		AutoPaste:     true,
+		PasteDelay:    50 * time.Millisecond,
 		UIDFormat:     "hex",
-		LogLevel:      "info",
+		MinUIDLength:  4,
+		LogLevel:      "off",
+		Sinks:         []string{"clipboard"},
+		OnReadTimeout: 10 * time.Second,
+
+		SinkQueueMaxAge: 24 * time.Hour,
 	}
 }
 
 // NewNFCService creates a new NFC service instance
 func NewNFCService(config Config) *NFCService {
-	var logger *log.Logger
-
-	// In normal mode, discard all logs; in debug mode, log to stdout
-	if config.LogLevel == "debug" {
-		logger = log.New(os.Stdout, "[NFCUIDService] ", log.LstdFlags)
-	} else {
-		logger = log.New(io.Discard, "", 0)
+	// Silent by default so the service stays invisible; diagnostics go to stderr
+	level, err := parseLogLevel(config.LogLevel)
+	if err != nil {
+		level = levelOff
 	}
+	logger := newLeveledLogger(os.Stderr, "[NFCUIDService] ", log.LstdFlags, level)
 
 	return &NFCService{
 		config: config,
@@ -67,125 +114,314 @@ func NewNFCService(config Config) *NFCService {
 	}
 }
 
-// Initialize sets up the PC/SC context and finds available readers
-func (s *NFCService) Initialize() error {
-	s.logger.Printf("Initializing %s...", s.config.ServiceName)
+// InitSinks registers the built-in sinks named in the configuration and
+// builds the sinks of reader overrides that bring their own
+func (s *NFCService) InitSinks() error {
+	for _, name := range s.config.Sinks {
+		if s.skipClipboard(name, s.config) {
+			continue
+		}
+		sink, err := s.newSink(name, s.config)
+		if err != nil {
+			return err
+		}
+		s.sinks = append(s.sinks, namedSink{name: name, sink: sink, builtin: true})
+	}
+
+	for key, rc := range s.config.Readers {
+		if !rc.hasOwnSinks() {
+			continue
+		}
+		cfg := s.config.forReader(key)
+		if s.readerSinks == nil {
+			s.readerSinks = make(map[string][]namedSink)
+		}
+		for _, name := range cfg.Sinks {
+			if s.skipClipboard(name, cfg) {
+				continue
+			}
+			sink, err := s.newSink(name, cfg)
+			if err != nil {
+				return fmt.Errorf("reader %q: %w", key, err)
+			}
+			s.readerSinks[key] = append(s.readerSinks[key], namedSink{name: name, sink: sink, builtin: true})
+		}
+	}
+	return nil
+}
+
+// Initialize sets up the PC/SC context and finds available readers. A
+// -wait-for-reader wait ends early when ctx is cancelled.
+func (s *NFCService) Initialize(ctx context.Context) error {
+	s.logger.Infof("Initializing %s...", s.config.ServiceName)
 
 	// Establish PC/SC context
-	ctx, err := scard.EstablishContext()
+	pcsc, err := scard.EstablishContext()
 	if err != nil {
-		return fmt.Errorf("failed to establish PC/SC context: %w", err)
+		return fmt.Errorf("failed to establish PC/SC context: %w", describePCSCError(err))
 	}
-	s.ctx = ctx
+	s.ctx = pcsc
 
 	// Find available readers
-	if err := s.findReader(); err != nil {
-		ctx.Release()
+	if err := s.findReader(ctx); err != nil {
+		pcsc.Release()
+		s.ctx = nil
 		return fmt.Errorf("failed to find NFC reader: %w", err)
 	}
 
-	s.logger.Printf("Successfully initialized with reader: %s", s.reader)
+	s.logger.Infof("Successfully initialized with reader: %s", s.reader)
+	s.logReaderOverride()
 	return nil
 }
 
-// findReader discovers available PC/SC readers
-func (s *NFCService) findReader() error {
-	readers, err := s.ctx.ListReaders()
-	if err != nil {
-		return fmt.Errorf("failed to list readers: %w", err)
+// findReader discovers available PC/SC readers. A reader that is listed but
+// no longer present (e.g. briefly enumerated across suspend/resume) is skipped,
+// and discovery is retried rather than returning a stale name. Cancelling ctx
+// ends the wait with ctx.Err().
+func (s *NFCService) findReader(ctx context.Context) error {
+	const attempts = 3
+
+	var deadline time.Time
+	if s.config.ReaderWaitTimeout > 0 {
+		deadline = time.Now().Add(s.config.ReaderWaitTimeout)
+	}
+	announced := false
+	for attempt := 1; ; attempt++ {
+		readers, err := s.ctx.ListReaders()
+		if err == scard.ErrNoReadersAvailable && s.config.WaitForReader {
+			err = nil // pcsc-lite reports "no readers" as an error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list readers: %w", describePCSCError(err))
+		}
+
+		if len(readers) == 0 {
+			if !s.config.WaitForReader {
+				return fmt.Errorf("no PC/SC readers found")
+			}
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return fmt.Errorf("no PC/SC reader appeared within %v", s.config.ReaderWaitTimeout)
+			}
+			if !announced {
+				s.logger.Infof("Waiting for a PC/SC reader to be connected...")
+				announced = true
+			}
+			if err := sleepContext(ctx, s.config.RetryInterval); err != nil {
+				return err
+			}
+			attempt = 0 // Waiting for a reader doesn't use up the availability retries
+			continue
+		}
+
+		for _, reader := range readers {
+			if s.readerPresent(reader) {
+				s.reader = reader
+				s.logger.Infof("Found %d reader(s), using: %s", len(readers), s.reader)
+				return nil
+			}
+			s.logger.Warnf("Reader %s is listed but not available, skipping", reader)
+		}
+
+		if attempt == attempts {
+			return fmt.Errorf("none of the %d listed reader(s) is available", len(readers))
+		}
+		if err := sleepContext(ctx, s.config.RetryInterval); err != nil {
+			return err
+		}
 	}
+}
 
-	if len(readers) == 0 {
-		return fmt.Errorf("no PC/SC readers found")
+// logReaderOverride notes when the selected reader has its own configuration
+func (s *NFCService) logReaderOverride() {
+	if key, _, ok := s.config.readerOverride(s.reader); ok {
+		cfg := s.config.forReader(s.reader)
+		s.logger.Infof("Reader %s uses override %q: format=%s, sinks=%s", s.reader, key, cfg.UIDFormat, strings.Join(cfg.Sinks, ","))
 	}
+}
 
-	s.reader = readers[0]
-	s.logger.Printf("Found %d reader(s), using: %s", len(readers), s.reader)
-	return nil
+// readerPresent checks that a listed reader still exists by querying its
+// state with GetStatusChange(StateUnaware)
+func (s *NFCService) readerPresent(reader string) bool {
+	rs := []scard.ReaderState{{Reader: reader, CurrentState: scard.StateUnaware}}
+	if err := s.ctx.GetStatusChange(rs, 0); err != nil {
+		return false
+	}
+	return rs[0].EventState&(scard.StateUnknown|scard.StateIgnore|scard.StateUnavailable) == 0
 }
 
-// Start begins the background service loop
-func (s *NFCService) Start() error {
+// Start begins the background service loop and blocks until ctx is
+// cancelled, a shutdown signal arrives, or Stop is called
+func (s *NFCService) Start(ctx context.Context) error {
+	s.mu.Lock()
 	if s.ctx == nil {
+		s.mu.Unlock()
 		return fmt.Errorf("service not initialized")
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	// The loop may be inside GetStatusChange or recoverReader until it sees
+	// the cancellation, so the PC/SC context is released here, not in Stop
+	defer func() {
+		cancel()
+		s.mu.Lock()
+		s.cancel = nil
+		s.releaseContext()
+		s.mu.Unlock()
+	}()
 
-	s.running = true
-
-	s.logger.Printf("Starting %s in background mode...", s.config.ServiceName)
-	s.logger.Printf("Configuration: AutoPaste=%v, Format=%s", s.config.AutoPaste, s.config.UIDFormat)
+	s.logger.Infof("Starting %s in background mode...", s.config.ServiceName)
+	s.logger.Debugf("Configuration: AutoPaste=%v, Format=%s", s.config.AutoPaste, s.config.UIDFormat)
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
 	go func() {
-		<-sigChan
-		s.logger.Printf("Received shutdown signal, stopping service...")
-		s.Stop()
+		select {
+		case <-sigChan:
+			s.logger.Infof("Received shutdown signal, stopping service...")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 
 	// Main service loop
-	for s.running {
-		if err := s.processCardCycle(); err != nil {
-			s.logger.Printf("Card processing error: %v", err)
-
-			// Try to recover by reinitializing reader connection
-			if err := s.recoverReader(); err != nil {
-				s.logger.Printf("Reader recovery failed: %v", err)
-				time.Sleep(s.config.RetryInterval)
+	for ctx.Err() == nil {
+		if err := s.processCardCycle(ctx); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, errOutputFailed) {
+				// The reader works, only the outputs failed: no recovery
+				s.logger.Errorf("%v", err)
+			} else {
+				s.logger.Errorf("Card processing error: %v", err)
+
+				// Try to recover by reinitializing reader connection
+				if err := s.recoverReader(ctx); err != nil {
+					s.logger.Errorf("Reader recovery failed: %v", err)
+					sleepContext(ctx, s.config.RetryInterval)
+				}
 			}
 		}
 
-		time.Sleep(s.config.ReadInterval)
+		sleepContext(ctx, s.config.ReadInterval)
 	}
 
 	return nil
 }
 
-// Stop gracefully shuts down the service
+// Stop gracefully shuts down the service. A running Start loop is only
+// cancelled, which wakes any blocking PC/SC wait; Start releases the PC/SC
+// context once its loop has exited.
 func (s *NFCService) Stop() {
-	s.running = false
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	} else {
+		s.releaseContext()
+	}
+	s.mu.Unlock()
+	for _, q := range s.queues {
+		q.close()
+	}
+	s.hooks.Wait() // Bounded by OnReadTimeout
+	s.logger.Infof("Service stopped")
+}
+
+// releaseContext releases the PC/SC context. s.mu must be held.
+func (s *NFCService) releaseContext() {
 	if s.ctx != nil {
 		s.ctx.Release()
+		s.ctx = nil
 	}
-	s.logger.Printf("Service stopped")
 }
 
+// errOutputFailed wraps a processCardCycle error from formatting or
+// delivering the UID, which the reader connection can't be blamed for
+var errOutputFailed = errors.New("failed to process UID")
+
 // processCardCycle handles one complete card detection and processing cycle
-func (s *NFCService) processCardCycle() error {
+func (s *NFCService) processCardCycle(ctx context.Context) error {
 	// Wait for card presence
-	if !s.waitForCardPresent(5 * time.Second) {
-		return nil // Timeout, continue loop
+	if !s.waitForCardPresent(ctx, 5*time.Second) {
+		if ctx.Err() == nil {
+			s.markRemoved() // No card seen, so any previously present tag has left
+		}
+		return ctx.Err() // Timeout (nil) continues the loop, cancellation stops it
 	}
 
 	// Connect to card
-	card, err := s.connectToCard()
+	card, err := s.connectToCard(ctx)
+	if errors.Is(err, scard.ErrSharingViolation) {
+		// Another application holds the tag exclusively. The reader is fine,
+		// so wait for the tag to leave instead of recovering the reader.
+		s.logger.Warnf("Cannot read tag: %v", describePCSCError(err))
+		if s.waitForCardRemoval(ctx, 10*time.Second) {
+			s.markRemoved()
+		}
+		return ctx.Err()
+	}
 	if err != nil {
-		s.waitForCardRemoval(1 * time.Second) // Brief wait before continuing
-		return fmt.Errorf("failed to connect to card: %w", err)
+		if s.waitForCardRemoval(ctx, 1*time.Second) { // Brief wait before continuing
+			s.markRemoved()
+		}
+		return fmt.Errorf("failed to connect to card: %w", describePCSCError(err))
 	}
 	defer card.Disconnect(scard.LeaveCard)
 
 	// Read UID
-	uid, err := s.getUID(card)
+	uid, err := s.readUID(ctx, card)
 	if err != nil {
 		return fmt.Errorf("failed to read UID: %w", err)
 	}
+	s.markPresent(uid)
 
-	// Process UID
-	if err := s.processUID(uid); err != nil {
-		return fmt.Errorf("failed to process UID: %w", err)
-	}
+	// Process UID. Failed outputs are not a reader problem: still wait for
+	// removal so the same tag isn't processed in a loop, then report them.
+	procErr := s.processUID(uid)
 
 	// Wait for card removal to avoid re-processing
-	s.waitForCardRemoval(10 * time.Second)
+	if s.waitForCardRemoval(ctx, 10*time.Second) {
+		s.markRemoved()
+	}
 
+	if procErr != nil {
+		return fmt.Errorf("%w: %v", errOutputFailed, procErr)
+	}
 	return nil
 }
 
+// markPresent records uid as the tag on the reader, firing OnRemoved for a
+// different previous tag and OnPresent when uid is newly arrived
+func (s *NFCService) markPresent(uid []byte) {
+	if bytes.Equal(uid, s.present) {
+		return
+	}
+	s.markRemoved()
+	s.present = append([]byte(nil), uid...)
+	if s.OnPresent != nil {
+		s.OnPresent(s.present)
+	}
+}
+
+// markRemoved clears the present tag and fires OnRemoved for it
+func (s *NFCService) markRemoved() {
+	if s.present == nil {
+		return
+	}
+	uid := s.present
+	s.present = nil
+	if s.OnRemoved != nil {
+		s.OnRemoved(uid)
+	}
+}
+
 // connectToCard establishes connection to the card with retries
-func (s *NFCService) connectToCard() (*scard.Card, error) {
+func (s *NFCService) connectToCard(ctx context.Context) (*scard.Card, error) {
 	var card *scard.Card
 	var err error
 
@@ -194,18 +430,79 @@ func (s *NFCService) connectToCard() (*scard.Card, error) {
 		if err == nil {
 			return card, nil
 		}
-		time.Sleep(s.config.ReadInterval)
+		if cerr := sleepContext(ctx, s.config.ReadInterval); cerr != nil {
+			return nil, cerr
+		}
 	}
 
 	return nil, err
 }
 
-// getUID reads the UID from the connected card
+// readUID reads the UID, reading again while it is shorter than
+// MinUIDLength: a bad read can return a truncated UID that must not be
+// processed
+func (s *NFCService) readUID(ctx context.Context, card *scard.Card) ([]byte, error) {
+	var uid []byte
+	var err error
+
+	for i := 0; i < s.config.MaxRetries; i++ {
+		uid, err = s.getUID(card)
+		if err != nil {
+			return nil, err
+		}
+		if len(uid) >= s.config.MinUIDLength {
+			return uid, nil
+		}
+		s.logger.Debugf("Rejected %d-byte UID % X, shorter than %d bytes", len(uid), uid, s.config.MinUIDLength)
+		if cerr := sleepContext(ctx, s.config.ReadInterval); cerr != nil {
+			return nil, cerr
+		}
+	}
+
+	return nil, fmt.Errorf("UID is %d bytes, shorter than the minimum of %d (partial read)", len(uid), s.config.MinUIDLength)
+}
+
+// getUID reads the UID from the connected card, falling back to the
+// configured GetAttrib attribute for readers without FF CA support
 func (s *NFCService) getUID(card *scard.Card) ([]byte, error) {
+	uid, err := s.getUIDAPDU(card)
+	if err == nil && len(uid) > 0 {
+		return uid, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("reader returned an empty UID")
+	}
+	if s.config.UIDAttrib == 0 {
+		return nil, err
+	}
+	uid, aerr := card.GetAttrib(s.config.UIDAttrib)
+	if aerr == nil && len(uid) == 0 {
+		aerr = fmt.Errorf("empty attribute")
+	}
+	if aerr != nil {
+		return nil, fmt.Errorf("FF CA: %w; GetAttrib 0x%X: %v", err, uint32(s.config.UIDAttrib), describePCSCError(aerr))
+	}
+	s.logger.Debugf("Read UID via GetAttrib 0x%X", uint32(s.config.UIDAttrib))
+	return uid, nil
+}
+
+// transmit sends an APDU and returns the raw response, tracing the exchange
+// when TraceAPDU is set
+func (s *NFCService) transmit(card *scard.Card, apdu []byte) ([]byte, error) {
+	start := time.Now()
+	resp, err := card.Transmit(apdu)
+	if s.config.TraceAPDU {
+		traceAPDU(os.Stderr, apdu, resp, err, time.Since(start))
+	}
+	return resp, err
+}
+
+// getUIDAPDU reads the UID with the PC/SC pseudo-APDU FF CA 00 00 00
+func (s *NFCService) getUIDAPDU(card *scard.Card) ([]byte, error) {
 	// Use the ACR/PCSC pseudo-APDU FF CA 00 00 00 to fetch UID
-	resp, err := card.Transmit([]byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
+	resp, err := s.transmit(card, []byte{0xFF, 0xCA, 0x00, 0x00, 0x00})
 	if err != nil {
-		return nil, err
+		return nil, describePCSCError(err)
 	}
 
 	if len(resp) < 2 {
@@ -222,49 +519,69 @@ func (s *NFCService) getUID(card *scard.Card) ([]byte, error) {
 	return resp[:len(resp)-2], nil
 }
 
-// processUID handles the UID formatting, clipboard copy, and paste operations
+// uidSizeLabel describes a UID by its length and ISO 14443-3 cascade level
+// (4 bytes = single size, 7 = double size, 10 = triple size)
+func uidSizeLabel(uid []byte) string {
+	switch len(uid) {
+	case 4:
+		return "4-byte, single size"
+	case 7:
+		return "7-byte, double size"
+	case 10:
+		return "10-byte, triple size"
+	default:
+		return fmt.Sprintf("%d-byte, non-standard size", len(uid))
+	}
+}
+
+// processUID formats the UID and fans it out to all enabled sinks
 func (s *NFCService) processUID(uid []byte) error {
 	if len(uid) == 0 {
 		return fmt.Errorf("empty UID")
 	}
 
-	// Format UID according to configuration
-	formattedUID := s.formatUID(uid)
-
-	s.logger.Printf("Detected NFC UID: %s", formattedUID)
-
-	// Copy to clipboard
-	if err := clipboard.WriteAll(formattedUID); err != nil {
-		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	// Format UID according to the configuration for this reader
+	config := s.config.forReader(s.reader)
+	formattedUID, err := config.formatUID(uid)
+	if err != nil {
+		return fmt.Errorf("failed to format UID: %w", err)
 	}
 
-	s.logger.Printf("Copied UID to clipboard: %s", formattedUID)
+	s.logger.Infof("Detected NFC UID: %s (%s)", formattedUID, uidSizeLabel(uid))
 
-	// Auto-paste if enabled (default behavior)
-	if s.config.AutoPaste {
-		if err := s.performPaste(); err != nil {
-			s.logger.Printf("Auto-paste failed: %v", err)
-			// Don't return error here, clipboard copy was successful
-		} else {
-			s.logger.Printf("Auto-pasted UID and pressed Enter")
-		}
+	event := UIDEvent{
+		UID:       uid,
+		Formatted: formattedUID,
+		Reader:    s.reader,
+		Time:      time.Now(),
 	}
-
-	return nil
+	if config.URLTemplate != "" {
+		event.URL = strings.ReplaceAll(config.URLTemplate, "{uid}", formattedUID)
+		s.logger.Debugf("Composed URL: %s", event.URL)
+	}
+	if config.OnReadCommand != "" {
+		s.runOnReadCommand(config, event)
+	}
+	return s.dispatch(event)
 }
 
-// formatUID converts the raw UID bytes to the specified format
-func (s *NFCService) formatUID(uid []byte) string {
-	switch s.config.UIDFormat {
+// formatUID converts the raw UID bytes to the specified format, after
+// selecting the bytes configured by UIDBytes
+func (c Config) formatUID(uid []byte) (string, error) {
+	uid, err := selectUIDBytes(uid, c.UIDBytes)
+	if err != nil {
+		return "", err
+	}
+	switch c.UIDFormat {
 	case "hex":
-		return strings.ToUpper(hex.EncodeToString(uid))
+		return strings.ToUpper(hex.EncodeToString(uid)), nil
 	case "hex-reversed":
 		// Reverse the byte order
 		reversed := make([]byte, len(uid))
 		for i, j := 0, len(uid)-1; i < len(uid); i, j = i+1, j-1 {
 			reversed[i] = uid[j]
 		}
-		return strings.ToUpper(hex.EncodeToString(reversed))
+		return strings.ToUpper(hex.EncodeToString(reversed)), nil
 	case "decimal":
 		// Convert to decimal (for shorter UIDs)
 		if len(uid) <= 4 {
@@ -272,19 +589,69 @@ func (s *NFCService) formatUID(uid []byte) string {
 			for i, b := range uid {
 				val |= uint32(b) << (8 * (len(uid) - 1 - i))
 			}
-			return fmt.Sprintf("%d", val)
+			return padDecimal(fmt.Sprintf("%d", val), c.DecimalWidth)
 		}
 		// For longer UIDs, fall back to hex
-		return strings.ToUpper(hex.EncodeToString(uid))
+		return strings.ToUpper(hex.EncodeToString(uid)), nil
 	default:
-		return strings.ToUpper(hex.EncodeToString(uid))
+		return strings.ToUpper(hex.EncodeToString(uid)), nil
 	}
 }
 
+// parseUIDBytes parses a UIDBytes setting into the number of bytes to keep
+// and whether they are taken from the end; n is 0 for "all"
+func parseUIDBytes(spec string) (n int, fromEnd bool, err error) {
+	if spec == "" || spec == "all" {
+		return 0, false, nil
+	}
+	digits, fromEnd := strings.CutPrefix(spec, "last")
+	if !fromEnd {
+		var ok bool
+		if digits, ok = strings.CutPrefix(spec, "first"); !ok {
+			return 0, false, fmt.Errorf("invalid UID bytes %q (use all, firstN or lastN)", spec)
+		}
+	}
+	n, err = strconv.Atoi(digits)
+	if err != nil || n < 1 || n > 10 {
+		return 0, false, fmt.Errorf("invalid UID bytes %q (N must be 1-10)", spec)
+	}
+	return n, fromEnd, nil
+}
+
+// selectUIDBytes normalizes a UID to the length given by spec (see
+// parseUIDBytes): longer UIDs keep their first or last N bytes, shorter ones
+// are left-padded with zero bytes so their numeric value is unchanged
+func selectUIDBytes(uid []byte, spec string) ([]byte, error) {
+	n, fromEnd, err := parseUIDBytes(spec)
+	if err != nil || n == 0 {
+		return uid, err
+	}
+	switch {
+	case len(uid) < n:
+		return append(make([]byte, n-len(uid)), uid...), nil
+	case fromEnd:
+		return uid[len(uid)-n:], nil
+	default:
+		return uid[:n], nil
+	}
+}
+
+// padDecimal left-pads a decimal string with zeros to width digits.
+// A number that needs more than width digits is an error rather than being truncated.
+func padDecimal(digits string, width int) (string, error) {
+	if width <= 0 {
+		return digits, nil
+	}
+	if len(digits) > width {
+		return "", fmt.Errorf("decimal UID %s exceeds %d digits", digits, width)
+	}
+	return strings.Repeat("0", width-len(digits)) + digits, nil
+}
+
 // performPaste simulates Ctrl+V keypress to paste the clipboard content, then presses Enter
 func (s *NFCService) performPaste() error {
 	// Small delay to ensure the target application is ready
-	time.Sleep(50 * time.Millisecond)
+	time.Sleep(s.config.PasteDelay)
 
 	var pasteCmd *exec.Cmd
 	var enterCmd *exec.Cmd
@@ -326,7 +693,7 @@ func (s *NFCService) performPaste() error {
 	}
 
 	// Small delay between paste and enter
-	time.Sleep(50 * time.Millisecond)
+	time.Sleep(s.config.PasteDelay)
 
 	// Execute enter command
 	if enterCmd != nil {
@@ -340,13 +707,66 @@ func (s *NFCService) performPaste() error {
 	return nil
 }
 
-// waitForCardPresent blocks until a card is detected or timeout occurs
-func (s *NFCService) waitForCardPresent(timeout time.Duration) bool {
+// typeText types text as keystrokes followed by Enter, leaving the clipboard untouched
+func (s *NFCService) typeText(text string) error {
+	// Small delay to ensure the target application is ready
+	time.Sleep(s.config.PasteDelay)
+
+	var typeCmd *exec.Cmd
+	var enterCmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		// SendKeys treats +^%~(){}[] as commands, so wrap each in braces
+		var keys strings.Builder
+		for _, r := range text {
+			if strings.ContainsRune("+^%~(){}[]", r) {
+				keys.WriteString("{" + string(r) + "}")
+			} else {
+				keys.WriteRune(r)
+			}
+		}
+		literal := strings.ReplaceAll(keys.String(), "'", "''")
+		typeCmd = exec.Command("powershell", "-Command", "Add-Type -AssemblyName System.Windows.Forms; [System.Windows.Forms.SendKeys]::SendWait('"+literal+"')")
+		enterCmd = exec.Command("powershell", "-Command", "Add-Type -AssemblyName System.Windows.Forms; [System.Windows.Forms.SendKeys]::SendWait('{ENTER}')")
+	case "linux":
+		if _, err := exec.LookPath("xdotool"); err == nil {
+			typeCmd = exec.Command("xdotool", "type", "--", text)
+			enterCmd = exec.Command("xdotool", "key", "Return")
+		} else if _, err := exec.LookPath("xte"); err == nil {
+			typeCmd = exec.Command("xte", "str "+text)
+			enterCmd = exec.Command("xte", "key Return")
+		} else {
+			return fmt.Errorf("no suitable keyboard automation tool found (install xdotool or xautomation)")
+		}
+	case "darwin": // macOS
+		quoted := strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), `"`, `\"`)
+		typeCmd = exec.Command("osascript", "-e", `tell application "System Events" to keystroke "`+quoted+`"`)
+		enterCmd = exec.Command("osascript", "-e", `tell application "System Events" to keystroke return`)
+	default:
+		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+
+	if err := typeCmd.Run(); err != nil {
+		return fmt.Errorf("failed to type text: %w", err)
+	}
+
+	// Small delay between typing and enter
+	time.Sleep(s.config.PasteDelay)
+
+	if err := enterCmd.Run(); err != nil {
+		return fmt.Errorf("failed to press enter: %w", err)
+	}
+	return nil
+}
+
+// waitForCardPresent blocks until a card is detected, timeout occurs or ctx is cancelled
+func (s *NFCService) waitForCardPresent(ctx context.Context, timeout time.Duration) bool {
 	rs := []scard.ReaderState{{Reader: s.reader, CurrentState: scard.StateUnaware}}
 	deadline := time.Now().Add(timeout)
 
-	for time.Now().Before(deadline) && s.running {
-		err := s.ctx.GetStatusChange(rs, 500*time.Millisecond)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		err := waitStatusChange(ctx, s.ctx, rs, 500*time.Millisecond)
 		if err != nil {
 			continue
 		}
@@ -362,13 +782,13 @@ func (s *NFCService) waitForCardPresent(timeout time.Duration) bool {
 	return false
 }
 
-// waitForCardRemoval blocks until the card is removed or timeout occurs
-func (s *NFCService) waitForCardRemoval(timeout time.Duration) bool {
+// waitForCardRemoval blocks until the card is removed, timeout occurs or ctx is cancelled
+func (s *NFCService) waitForCardRemoval(ctx context.Context, timeout time.Duration) bool {
 	rs := []scard.ReaderState{{Reader: s.reader, CurrentState: scard.StateUnaware}}
 	deadline := time.Now().Add(timeout)
 
-	for time.Now().Before(deadline) && s.running {
-		err := s.ctx.GetStatusChange(rs, 500*time.Millisecond)
+	for time.Now().Before(deadline) && ctx.Err() == nil {
+		err := waitStatusChange(ctx, s.ctx, rs, 500*time.Millisecond)
 		if err != nil {
 			continue
 		}
@@ -384,9 +804,46 @@ func (s *NFCService) waitForCardRemoval(timeout time.Duration) bool {
 	return false
 }
 
-// recoverReader attempts to recover from reader disconnection
-func (s *NFCService) recoverReader() error {
-	s.logger.Printf("Attempting to recover reader connection...")
+// waitStatusChange calls GetStatusChange and uses the PC/SC cancel mechanism
+// to wake the blocking call early when ctx is cancelled
+func waitStatusChange(ctx context.Context, pcsc *scard.Context, rs []scard.ReaderState, timeout time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			pcsc.Cancel()
+		case <-done:
+		}
+	}()
+
+	if err := pcsc.GetStatusChange(rs, timeout); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return ctx.Err()
+}
+
+// sleepContext pauses for d, returning early with ctx.Err() if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// recoverReader attempts to recover from reader disconnection until ctx is
+// cancelled
+func (s *NFCService) recoverReader(ctx context.Context) error {
+	s.logger.Infof("Attempting to recover reader connection...")
 
 	// Release current context
 	if s.ctx != nil {
@@ -394,18 +851,19 @@ func (s *NFCService) recoverReader() error {
 	}
 
 	// Re-establish context
-	ctx, err := scard.EstablishContext()
+	pcsc, err := scard.EstablishContext()
 	if err != nil {
-		return fmt.Errorf("failed to re-establish PC/SC context: %w", err)
+		return fmt.Errorf("failed to re-establish PC/SC context: %w", describePCSCError(err))
 	}
-	s.ctx = ctx
+	s.ctx = pcsc
 
 	// Re-discover readers
-	if err := s.findReader(); err != nil {
+	if err := s.findReader(ctx); err != nil {
 		return fmt.Errorf("failed to rediscover readers: %w", err)
 	}
 
-	s.logger.Printf("Successfully recovered reader connection: %s", s.reader)
+	s.logger.Infof("Successfully recovered reader connection: %s", s.reader)
+	s.logReaderOverride()
 	return nil
 }
 
@@ -418,15 +876,41 @@ Usage: %s [options]
 Options:
   -h, --help           Show this help message
   -format string       UID format: hex, hex-reversed, decimal (default: hex)
+  -decimal-width N     Zero-pad decimal UIDs to N digits (e.g. 10)
+  -uid-bytes spec      Format only these UID bytes: all, firstN, lastN (e.g. last4 for 4-byte systems)
+  -min-uid-length N   Reject UIDs shorter than N bytes as partial reads and read again (default: 4, 0 = off)
   -no-paste           Disable automatic paste+enter functionality
+  -no-clipboard       Type the UID as keystrokes + Enter, never touching the clipboard
+  -stdout-only        Only print each formatted UID on its own line to stdout, for piping
+  -restore-clipboard  Put the previous clipboard text back after pasting the UID
+  -paste-delay d      Wait d before pasting or typing and again before Enter (default: 50ms)
+  -sinks list         Comma-separated outputs: clipboard, keyboard, stdout, stdout-json, file, webhook (default: clipboard)
+  -output-file path   File the "file" sink appends JSON lines to
+  -webhook-url url    URL the "webhook" sink POSTs JSON events to
+  -sink-interval d    Publish the same UID to the webhook at most once per d (e.g. 30s)
+  -sink-queue N       Queue up to N events per file/webhook sink and retry while it is down
+  -sink-queue-age d   Drop queued events older than d (default: 24h)
+  -sink-queue-dir dir Keep queued events in dir so they survive a restart
+  -uid-attrib id      Read the UID via SCardGetAttrib(id) when the reader lacks FF CA
+  -url-template url   Output this URL instead of the bare UID, {uid} = formatted UID
+  -on-read cmd        Run cmd in the background for each read, UID as $1 and in $NFC_UID
+  -on-read-timeout d  Kill the -on-read command after d (default: 10s)
+  -reader-config spec Per-reader overrides "name:format=..;sinks=..;webhook-url=.." (repeatable)
+  -wait-for-reader [d] Wait (up to d, e.g. 60s) for a reader at startup instead of failing
   -service            Run as background service (default)
-  -debug              Enable debug logging
+  -debug              Enable debug logging (to stderr)
+  -trace              Write every APDU and response with decoded status word to stderr
+  -log-level level    Log level: debug, info, warn, error, off (default: off)
+  -quiet              Disable all logging (default), same as -log-level off
   -test               Test mode - read one card and exit
+  -list-readers       Print the attached readers with their card status and exit
 
 Examples:
   %s                           # Run as service with default settings
   %s -format hex-reversed      # Use reversed hex format
+  %s -format decimal -decimal-width 10  # 10-digit zero-padded card number
   %s -no-paste                 # Only copy to clipboard, don't auto-paste+enter
+  %s -sinks clipboard,file -output-file uids.jsonl  # Clipboard plus a JSON log
   %s -test                     # Test mode - read one card and exit
 
 Service Installation:
@@ -434,7 +918,7 @@ Service Installation:
   Linux:   Use systemctl to manage the service
   macOS:   Use launchctl to manage the service
 
-`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
+`, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
 
 func main() {
@@ -449,6 +933,8 @@ func main() {
 	// Parse command line arguments
 	config := DefaultConfig()
 	testMode := false
+	stdoutOnly := false
+	listReaders := false
 
 	for i := 1; i < len(os.Args); i++ {
 		switch os.Args[i] {
@@ -457,45 +943,261 @@ func main() {
 				config.UIDFormat = os.Args[i+1]
 				i++ // Skip next argument as it's the format value
 			}
+		case "-decimal-width":
+			if i+1 < len(os.Args) {
+				width, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || width < 0 {
+					fmt.Printf("Invalid decimal width: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.DecimalWidth = width
+				i++
+			}
+		case "-uid-bytes":
+			if i+1 < len(os.Args) {
+				if _, _, err := parseUIDBytes(os.Args[i+1]); err != nil {
+					fmt.Printf("%v\n", err)
+					os.Exit(1)
+				}
+				config.UIDBytes = os.Args[i+1]
+				i++
+			}
+		case "-min-uid-length":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n < 0 {
+					fmt.Printf("Invalid minimum UID length: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.MinUIDLength = n
+				i++
+			}
+		case "-stdout-only":
+			stdoutOnly = true
 		case "-no-paste":
 			config.AutoPaste = false
+		case "-no-clipboard":
+			config.NoClipboard = true
+		case "-restore-clipboard":
+			config.RestoreClipboard = true
+		case "-url-template":
+			if i+1 < len(os.Args) {
+				config.URLTemplate = os.Args[i+1]
+				i++
+			}
+		case "-uid-attrib":
+			if i+1 < len(os.Args) {
+				id, err := strconv.ParseUint(os.Args[i+1], 0, 32)
+				if err != nil {
+					fmt.Printf("Invalid UID attribute: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.UIDAttrib = scard.Attrib(id)
+				i++
+			}
+		case "-sinks":
+			if i+1 < len(os.Args) {
+				config.Sinks = splitSinkNames(os.Args[i+1])
+				i++
+			}
+		case "-output-file":
+			if i+1 < len(os.Args) {
+				config.OutputFile = os.Args[i+1]
+				i++
+			}
+		case "-paste-delay":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d < 0 {
+					fmt.Printf("Invalid paste delay: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.PasteDelay = d
+				i++
+			}
+		case "-sink-interval":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d < 0 {
+					fmt.Printf("Invalid sink interval: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.SinkMinInterval = d
+				i++
+			}
+		case "-sink-queue":
+			if i+1 < len(os.Args) {
+				n, err := strconv.Atoi(os.Args[i+1])
+				if err != nil || n < 0 {
+					fmt.Printf("Invalid sink queue size: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.SinkQueueSize = n
+				i++
+			}
+		case "-sink-queue-age":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d < 0 {
+					fmt.Printf("Invalid sink queue age: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.SinkQueueMaxAge = d
+				i++
+			}
+		case "-sink-queue-dir":
+			if i+1 < len(os.Args) {
+				config.SinkQueueDir = os.Args[i+1]
+				i++
+			}
+		case "-reader-config":
+			if i+1 < len(os.Args) {
+				name, rc, err := parseReaderConfig(os.Args[i+1])
+				if err != nil {
+					fmt.Printf("Invalid reader config: %v\n", err)
+					os.Exit(1)
+				}
+				if config.Readers == nil {
+					config.Readers = make(map[string]ReaderConfig)
+				}
+				config.Readers[name] = rc
+				i++
+			}
+		case "-on-read":
+			if i+1 < len(os.Args) {
+				config.OnReadCommand = os.Args[i+1]
+				i++
+			}
+		case "-on-read-timeout":
+			if i+1 < len(os.Args) {
+				d, err := time.ParseDuration(os.Args[i+1])
+				if err != nil || d <= 0 {
+					fmt.Printf("Invalid on-read timeout: %s\n", os.Args[i+1])
+					os.Exit(1)
+				}
+				config.OnReadTimeout = d
+				i++
+			}
+		case "-webhook-url":
+			if i+1 < len(os.Args) {
+				config.WebhookURL = os.Args[i+1]
+				i++
+			}
 		case "-debug":
 			config.LogLevel = "debug"
+		case "-trace":
+			config.TraceAPDU = true
+		case "-quiet":
+			config.LogLevel = "off"
+		case "-log-level":
+			if i+1 < len(os.Args) {
+				if _, err := parseLogLevel(os.Args[i+1]); err != nil {
+					fmt.Printf("Invalid log level: %v\n", err)
+					os.Exit(1)
+				}
+				config.LogLevel = os.Args[i+1]
+				i++
+			}
+		case "-wait-for-reader":
+			config.WaitForReader = true
+			// Optional timeout, e.g. 30s
+			if i+1 < len(os.Args) {
+				if d, err := time.ParseDuration(os.Args[i+1]); err == nil {
+					config.ReaderWaitTimeout = d
+					i++
+				}
+			}
 		case "-test":
 			testMode = true
+		case "-list-readers":
+			listReaders = true
 		}
 	}
 
-	// Validate format
-	if config.UIDFormat != "hex" && config.UIDFormat != "hex-reversed" && config.UIDFormat != "decimal" {
-		fmt.Printf("Invalid format: %s. Use: hex, hex-reversed, or decimal\n", config.UIDFormat)
+	if listReaders {
+		pcsc, err := scard.EstablishContext()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pcsc EstablishContext: %v\n", describePCSCError(err))
+			os.Exit(1)
+		}
+		code := printReaderList(pcsc)
+		pcsc.Release()
+		os.Exit(code)
+	}
+
+	if stdoutOnly {
+		// Only the UID line on stdout: no clipboard, paste, file or webhook,
+		// also for readers with their own sinks
+		config.Sinks = []string{"stdout"}
+		config.AutoPaste = false
+		config.NoClipboard = false
+		config.RestoreClipboard = false
+		for name, rc := range config.Readers {
+			rc.Sinks, rc.OutputFile, rc.WebhookURL = nil, "", ""
+			config.Readers[name] = rc
+		}
+	}
+
+	if config.NoClipboard && !config.AutoPaste {
+		fmt.Printf("-no-clipboard types the UID and cannot be combined with -no-paste\n")
+		os.Exit(1)
+	}
+	if config.RestoreClipboard && !config.AutoPaste {
+		fmt.Printf("-restore-clipboard would undo the copy without a paste and cannot be combined with -no-paste\n")
 		os.Exit(1)
 	}
 
+	// Validate format, including per-reader overrides
+	formats := []string{config.UIDFormat}
+	for _, rc := range config.Readers {
+		if rc.UIDFormat != "" {
+			formats = append(formats, rc.UIDFormat)
+		}
+	}
+	for _, format := range formats {
+		if format != "hex" && format != "hex-reversed" && format != "decimal" {
+			fmt.Printf("Invalid format: %s. Use: hex, hex-reversed, or decimal\n", format)
+			os.Exit(1)
+		}
+	}
+
 	// Create and initialize service
 	service := NewNFCService(config)
-	if err := service.Initialize(); err != nil {
+	if err := service.InitSinks(); err != nil {
+		fmt.Printf("Invalid sink configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Cancel blocking waits, including a -wait-for-reader wait, on Ctrl+C / SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := service.Initialize(ctx); err != nil {
+		if ctx.Err() != nil {
+			service.Stop() // Interrupted while waiting for a reader
+			return
+		}
 		log.Fatalf("Failed to initialize service: %v", err)
 	}
 	defer service.Stop()
 
 	if testMode {
 		// Test mode - read one card and exit
-		service.logger.Printf("Running in test mode - will read one card and exit")
+		service.logger.Infof("Running in test mode - will read one card and exit")
 
-		if err := service.processCardCycle(); err != nil {
+		if err := service.processCardCycle(ctx); err != nil {
 			log.Fatalf("Test failed: %v", err)
 		}
 
-		service.logger.Printf("Test completed successfully")
+		service.logger.Infof("Test completed successfully")
 		return
 	}
 
 	// Normal service mode - startup messages only in debug
-	service.logger.Printf("NFC UID Service starting...")
-	service.logger.Printf("Place NFC tags on the reader to copy UIDs to clipboard")
+	service.logger.Infof("NFC UID Service starting...")
+	service.logger.Infof("Place NFC tags on the reader to copy UIDs to clipboard")
 
-	if err := service.Start(); err != nil {
+	if err := service.Start(ctx); err != nil {
 		log.Fatalf("Service failed: %v", err)
 	}
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormatUID(t *testing.T) {
+	uid4 := []byte{0x04, 0xA1, 0xB2, 0xC3}
+	uid7 := []byte{0x04, 0xA1, 0xB2, 0xC3, 0xD4, 0xE5, 0xF6}
+	tests := []struct {
+		name   string
+		config Config
+		uid    []byte
+		want   string
+	}{
+		{"hex 4-byte", Config{UIDFormat: "hex"}, uid4, "04A1B2C3"},
+		{"hex 7-byte", Config{UIDFormat: "hex"}, uid7, "04A1B2C3D4E5F6"},
+		{"hex-reversed 4-byte", Config{UIDFormat: "hex-reversed"}, uid4, "C3B2A104"},
+		{"hex-reversed 7-byte", Config{UIDFormat: "hex-reversed"}, uid7, "F6E5D4C3B2A104"},
+		{"decimal 4-byte", Config{UIDFormat: "decimal"}, uid4, "77705923"},
+		{"decimal 3-byte", Config{UIDFormat: "decimal"}, []byte{0x01, 0x02, 0x03}, "66051"},
+		{"decimal max 4-byte", Config{UIDFormat: "decimal"}, []byte{0xFF, 0xFF, 0xFF, 0xFF}, "4294967295"},
+		{"decimal zero", Config{UIDFormat: "decimal"}, []byte{0x00, 0x00, 0x00, 0x00}, "0"},
+		{"decimal 5-byte falls back to hex", Config{UIDFormat: "decimal"}, []byte{0x01, 0x02, 0x03, 0x04, 0x05}, "0102030405"},
+		{"decimal 7-byte falls back to hex", Config{UIDFormat: "decimal"}, uid7, "04A1B2C3D4E5F6"},
+		{"decimal padded", Config{UIDFormat: "decimal", DecimalWidth: 10}, uid4, "0077705923"},
+		{"decimal zero padded", Config{UIDFormat: "decimal", DecimalWidth: 10}, []byte{0x00, 0x00, 0x00, 0x00}, "0000000000"},
+		{"decimal exact width", Config{UIDFormat: "decimal", DecimalWidth: 10}, []byte{0xFF, 0xFF, 0xFF, 0xFF}, "4294967295"},
+		{"decimal last4 of 7-byte", Config{UIDFormat: "decimal", UIDBytes: "last4"}, uid7, "3285509622"},
+		{"hex first4 of 7-byte", Config{UIDFormat: "hex", UIDBytes: "first4"}, uid7, "04A1B2C3"},
+		{"hex-reversed last4 of 7-byte", Config{UIDFormat: "hex-reversed", UIDBytes: "last4"}, uid7, "F6E5D4C3"},
+		{"hex last8 pads 7-byte", Config{UIDFormat: "hex", UIDBytes: "last8"}, uid7, "0004A1B2C3D4E5F6"},
+		{"decimal first7 pads 4-byte", Config{UIDFormat: "decimal", UIDBytes: "first7"}, uid4, "00000004A1B2C3"},
+		{"unknown format is hex", Config{UIDFormat: "base64"}, uid4, "04A1B2C3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.formatUID(tt.uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("formatUID(% X) = %q, want %q", tt.uid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatUIDRoundTrip(t *testing.T) {
+	uids := [][]byte{
+		{0x00, 0x00, 0x00, 0x00},
+		{0x04, 0xA1, 0xB2, 0xC3},
+		{0xFF, 0xFF, 0xFF, 0xFF},
+		{0x04, 0xA1, 0xB2, 0xC3, 0xD4, 0xE5, 0xF6},
+		{0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09},
+	}
+	for _, uid := range uids {
+		hexUID, err := Config{UIDFormat: "hex"}.formatUID(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back, err := hex.DecodeString(hexUID); err != nil || !bytes.Equal(back, uid) {
+			t.Errorf("hex %q does not decode back to % X", hexUID, uid)
+		}
+
+		reversed, err := Config{UIDFormat: "hex-reversed"}.formatUID(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := hex.DecodeString(reversed)
+		slices.Reverse(back)
+		if err != nil || !bytes.Equal(back, uid) {
+			t.Errorf("hex-reversed %q does not decode back to % X", reversed, uid)
+		}
+
+		if len(uid) > 4 {
+			continue
+		}
+		decimal, err := Config{UIDFormat: "decimal", DecimalWidth: 10}.formatUID(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := strconv.ParseUint(decimal, 10, 32)
+		if err != nil {
+			t.Fatalf("decimal %q: %v", decimal, err)
+		}
+		if back := []byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}; !bytes.Equal(back, uid) {
+			t.Errorf("decimal %q reads back as % X, want % X", decimal, back, uid)
+		}
+	}
+}
+
+func TestFormatUIDErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		uid    []byte
+	}{
+		{"decimal wider than width", Config{UIDFormat: "decimal", DecimalWidth: 8}, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+		{"bad uid-bytes spec", Config{UIDFormat: "hex", UIDBytes: "middle4"}, []byte{0x04, 0xA1, 0xB2, 0xC3}},
+		{"uid-bytes out of range", Config{UIDFormat: "hex", UIDBytes: "last11"}, []byte{0x04, 0xA1, 0xB2, 0xC3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.config.formatUID(tt.uid); err == nil {
+				t.Errorf("formatUID(% X) = %q, want an error", tt.uid, got)
+			}
+		})
+	}
+}
+
+func TestOnReadCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	event := UIDEvent{UID: []byte{0x04, 0xa1, 0xb2}, Formatted: "04A1B2", Reader: "ACR122U"}
+	out, err := onReadCommand(context.Background(), `echo "$1 $NFC_UID_HEX $NFC_READER"`, event).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(out)), "04A1B2 04A1B2 ACR122U"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitSinkNames(t *testing.T) {
+	tests := map[string][]string{
+		"clipboard":             {"clipboard"},
+		"clipboard, webhook":    {"clipboard", "webhook"},
+		" file ,stdout-json,, ": {"file", "stdout-json"},
+		"":                      nil,
+	}
+	for list, want := range tests {
+		if got := splitSinkNames(list); !slices.Equal(got, want) {
+			t.Errorf("splitSinkNames(%q) = %q, want %q", list, got, want)
+		}
+	}
+}
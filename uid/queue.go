@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Delivery retry backoff of queued sinks, doubling from min to max
+const (
+	queueRetryMin = 1 * time.Second
+	queueRetryMax = 1 * time.Minute
+)
+
+// queueDrainTimeout is how long Stop waits for queued events to be delivered
+const queueDrainTimeout = 2 * time.Second
+
+// queuedEvent is an event waiting in a sink queue
+type queuedEvent struct {
+	seq   uint64 // Identifies the event while it is being delivered
+	event UIDEvent
+}
+
+// queuedSink decouples a network or file sink from the read loop: events are
+// queued and delivered in order by a background goroutine, which retries with
+// backoff while the backend is down. The queue holds at most maxLen events
+// (the oldest are dropped first) and evicts events older than maxAge. With a
+// spool file the pending events survive a restart.
+type queuedSink struct {
+	name   string
+	inner  Sink
+	maxLen int
+	maxAge time.Duration // 0 = keep until delivered or pushed out
+	spool  string        // File pending events are saved to, "" = memory only
+	logger *leveledLogger
+
+	mu      sync.Mutex
+	pending []queuedEvent
+	nextSeq uint64
+
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// queue wraps a file or network sink in a queue when SinkQueueSize is set.
+// target (the file path or URL) names the spool file so each destination
+// keeps its own; SinkQueueDir is created if it doesn't exist.
+func (s *NFCService) queue(name, target string, sink Sink) (Sink, error) {
+	if s.config.SinkQueueSize <= 0 {
+		return sink, nil
+	}
+	q := &queuedSink{
+		name:    name,
+		inner:   sink,
+		maxLen:  s.config.SinkQueueSize,
+		maxAge:  s.config.SinkQueueMaxAge,
+		logger:  s.logger,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if s.config.SinkQueueDir != "" {
+		if err := os.MkdirAll(s.config.SinkQueueDir, 0o755); err != nil {
+			return nil, fmt.Errorf("sink queue directory: %w", err)
+		}
+		sum := sha256.Sum256([]byte(target))
+		q.spool = filepath.Join(s.config.SinkQueueDir, fmt.Sprintf("%s-%x.jsonl", name, sum[:4]))
+		if err := q.load(); err != nil {
+			s.logger.Warnf("Sink %s: cannot load queued events from %s: %v", name, q.spool, err)
+		}
+	}
+	s.queues = append(s.queues, q)
+	go q.run()
+	return q, nil
+}
+
+// Handle queues the event and wakes the delivery goroutine. Queuing never
+// fails; delivery errors are logged by the goroutine.
+func (q *queuedSink) Handle(event UIDEvent) error {
+	q.mu.Lock()
+	q.nextSeq++
+	q.pending = append(q.pending, queuedEvent{seq: q.nextSeq, event: event})
+	q.evictLocked(time.Now())
+	depth := len(q.pending)
+	q.saveLocked()
+	q.mu.Unlock()
+
+	q.logger.Debugf("Sink %s: queued %s (queue depth %d)", q.name, event.Formatted, depth)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// evictLocked drops events older than maxAge and, past maxLen, the oldest
+// events. q.mu must be held.
+func (q *queuedSink) evictLocked(now time.Time) {
+	kept := q.pending[:0]
+	for _, qe := range q.pending {
+		if q.maxAge > 0 && now.Sub(qe.event.Time) > q.maxAge {
+			q.logger.Warnf("Sink %s: dropped %s read at %s, undelivered for longer than %v",
+				q.name, qe.event.Formatted, qe.event.Time.Format(time.RFC3339), q.maxAge)
+			continue
+		}
+		kept = append(kept, qe)
+	}
+	q.pending = kept
+	if over := len(q.pending) - q.maxLen; over > 0 {
+		q.logger.Warnf("Sink %s: queue full (%d events), dropped the %d oldest", q.name, q.maxLen, over)
+		q.pending = q.pending[over:]
+	}
+}
+
+// depth returns the number of undelivered events
+func (q *queuedSink) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// run delivers queued events in order until close is called
+func (q *queuedSink) run() {
+	defer close(q.stopped)
+	backoff := queueRetryMin
+	for {
+		q.mu.Lock()
+		q.evictLocked(time.Now())
+		if len(q.pending) == 0 {
+			q.saveLocked()
+			q.mu.Unlock()
+			select {
+			case <-q.wake:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+		head := q.pending[0]
+		q.mu.Unlock()
+
+		if err := q.inner.Handle(head.event); err != nil {
+			q.logger.Warnf("Sink %s failed, retrying in %v (%d queued): %v", q.name, backoff, q.depth(), err)
+			select {
+			case <-time.After(backoff):
+			case <-q.done:
+				return
+			}
+			backoff = min(backoff*2, queueRetryMax)
+			continue
+		}
+		backoff = queueRetryMin
+
+		// Remove by sequence number: Handle may have evicted it meanwhile
+		q.mu.Lock()
+		for i, qe := range q.pending {
+			if qe.seq == head.seq {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+		depth := len(q.pending)
+		q.saveLocked()
+		q.mu.Unlock()
+		q.logger.Debugf("Sink %s: delivered %s (queue depth %d)", q.name, head.event.Formatted, depth)
+	}
+}
+
+// close gives the queue a moment to drain, then stops the delivery goroutine.
+// Undelivered events stay in the spool file, without one they are lost.
+func (q *queuedSink) close() {
+	q.once.Do(func() {
+		for deadline := time.Now().Add(queueDrainTimeout); q.depth() > 0 && time.Now().Before(deadline); {
+			time.Sleep(50 * time.Millisecond)
+		}
+		close(q.done)
+		<-q.stopped
+
+		n := q.depth()
+		switch {
+		case n == 0:
+		case q.spool != "":
+			q.logger.Infof("Sink %s: %d undelivered events kept in %s", q.name, n, q.spool)
+		default:
+			q.logger.Warnf("Sink %s: %d undelivered events lost (set -sink-queue-dir to keep them)", q.name, n)
+		}
+	})
+}
+
+// saveLocked rewrites the spool file with the pending events. q.mu must be held.
+func (q *queuedSink) saveLocked() {
+	if q.spool == "" {
+		return
+	}
+	var buf bytes.Buffer
+	for _, qe := range q.pending {
+		line, err := marshalEvent(qe.event)
+		if err != nil {
+			continue
+		}
+		buf.Write(line)
+	}
+	tmp := q.spool + ".tmp"
+	err := os.WriteFile(tmp, buf.Bytes(), 0o644)
+	if err == nil {
+		err = os.Rename(tmp, q.spool)
+	}
+	if err != nil {
+		q.logger.Warnf("Sink %s: cannot save queue to %s: %v", q.name, q.spool, err)
+	}
+}
+
+// load restores events saved by a previous run from the spool file
+func (q *queuedSink) load() error {
+	data, err := os.ReadFile(q.spool)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		event, err := unmarshalEvent(scanner.Bytes())
+		if err != nil {
+			q.logger.Warnf("Sink %s: skipping unreadable queued event: %v", q.name, err)
+			continue
+		}
+		q.nextSeq++
+		q.pending = append(q.pending, queuedEvent{seq: q.nextSeq, event: event})
+	}
+	if len(q.pending) > 0 {
+		q.logger.Infof("Sink %s: %d queued events restored from %s", q.name, len(q.pending), q.spool)
+	}
+	return scanner.Err()
+}
+
+// unmarshalEvent decodes a JSON line written by marshalEvent
+func unmarshalEvent(line []byte) (UIDEvent, error) {
+	var ej eventJSON
+	if err := json.Unmarshal(line, &ej); err != nil {
+		return UIDEvent{}, err
+	}
+	uid, err := hex.DecodeString(ej.UID)
+	if err != nil {
+		return UIDEvent{}, fmt.Errorf("uid: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, ej.Time)
+	if err != nil {
+		return UIDEvent{}, fmt.Errorf("time: %w", err)
+	}
+	return UIDEvent{UID: uid, Formatted: ej.Formatted, URL: ej.URL, Reader: ej.Reader, Time: t}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink remembers the UIDs it was handed. With release set it
+// blocks each delivery until the channel is closed.
+type recordingSink struct {
+	mu      sync.Mutex
+	got     []string
+	release chan struct{}
+}
+
+func (r *recordingSink) Handle(event UIDEvent) error {
+	if r.release != nil {
+		<-r.release
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.got = append(r.got, event.Formatted)
+	return nil
+}
+
+func (r *recordingSink) delivered() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.got)
+}
+
+func testEvent(uid string, at time.Time) UIDEvent {
+	return UIDEvent{UID: []byte(uid), Formatted: uid, Time: at}
+}
+
+func newQueueService(t *testing.T, dir string) *NFCService {
+	t.Helper()
+	return NewNFCService(Config{SinkQueueSize: 3, SinkQueueMaxAge: time.Hour, SinkQueueDir: dir, LogLevel: "off"})
+}
+
+func TestQueueEviction(t *testing.T) {
+	s := newQueueService(t, "")
+	q := &queuedSink{name: "test", maxLen: 3, maxAge: time.Hour, logger: s.logger, wake: make(chan struct{}, 1)}
+
+	now := time.Now()
+	q.Handle(testEvent("OLD", now.Add(-2*time.Hour)))
+	for _, uid := range []string{"A", "B", "C", "D"} {
+		q.Handle(testEvent(uid, now))
+	}
+	var got []string
+	for _, qe := range q.pending {
+		got = append(got, qe.event.Formatted)
+	}
+	if want := []string{"B", "C", "D"}; !slices.Equal(got, want) {
+		t.Errorf("queue holds %v, want %v", got, want)
+	}
+}
+
+func TestQueueDrainsOnClose(t *testing.T) {
+	s := newQueueService(t, "")
+	inner := &recordingSink{}
+	sink, err := s.queue("test", "target", inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, uid := range []string{"A", "B", "C"} {
+		sink.Handle(testEvent(uid, time.Now()))
+	}
+	s.Stop()
+	if got, want := inner.delivered(), []string{"A", "B", "C"}; !slices.Equal(got, want) {
+		t.Errorf("delivered %v before close returned, want %v", got, want)
+	}
+}
+
+func TestQueueSpoolReload(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool") // Created by queue
+	first := newQueueService(t, dir)
+	stuck := &recordingSink{release: make(chan struct{})}
+	sink, err := first.queue("test", "target", stuck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Handle(testEvent("A", time.Now()))
+	sink.Handle(testEvent("B", time.Now()))
+
+	// A second run with the same target picks up both undelivered events
+	second := newQueueService(t, dir)
+	inner := &recordingSink{}
+	if _, err := second.queue("test", "target", inner); err != nil {
+		t.Fatal(err)
+	}
+	second.Stop()
+	if got, want := inner.delivered(), []string{"A", "B"}; !slices.Equal(got, want) {
+		t.Errorf("reloaded queue delivered %v, want %v", got, want)
+	}
+	spools, _ := filepath.Glob(filepath.Join(dir, "test-*.jsonl"))
+	if len(spools) != 1 {
+		t.Fatalf("spool files %v, want one", spools)
+	}
+	if data, err := os.ReadFile(spools[0]); err != nil || len(data) != 0 {
+		t.Errorf("spool after delivery: %q, %v; want empty", data, err)
+	}
+
+	close(stuck.release)
+	first.Stop()
+}
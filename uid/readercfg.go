@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReaderConfig overrides parts of the top-level Config for one reader. Zero
+// fields inherit the top-level value.
+type ReaderConfig struct {
+	UIDFormat    string
+	DecimalWidth int
+	UIDBytes     string
+	Sinks        []string
+	OutputFile   string
+	WebhookURL   string
+	URLTemplate  string
+}
+
+// readerOverride finds the override for a reader: an exact name match wins,
+// otherwise the longest key contained in the reader name, and of keys with
+// the same length the lexically first, so the choice doesn't depend on map order
+func (c Config) readerOverride(reader string) (string, ReaderConfig, bool) {
+	if rc, ok := c.Readers[reader]; ok {
+		return reader, rc, true
+	}
+	best := ""
+	for key := range c.Readers {
+		if key == "" || !strings.Contains(reader, key) {
+			continue
+		}
+		if best == "" || len(key) > len(best) || len(key) == len(best) && key < best {
+			best = key
+		}
+	}
+	if best == "" {
+		return "", ReaderConfig{}, false
+	}
+	return best, c.Readers[best], true
+}
+
+// forReader returns the configuration in effect for a reader, with its
+// override applied on top of the top-level defaults
+func (c Config) forReader(reader string) Config {
+	_, rc, ok := c.readerOverride(reader)
+	if !ok {
+		return c
+	}
+	if rc.UIDFormat != "" {
+		c.UIDFormat = rc.UIDFormat
+	}
+	if rc.DecimalWidth != 0 {
+		c.DecimalWidth = rc.DecimalWidth
+	}
+	if rc.UIDBytes != "" {
+		c.UIDBytes = rc.UIDBytes
+	}
+	if len(rc.Sinks) > 0 {
+		c.Sinks = rc.Sinks
+	}
+	if rc.OutputFile != "" {
+		c.OutputFile = rc.OutputFile
+	}
+	if rc.WebhookURL != "" {
+		c.WebhookURL = rc.WebhookURL
+	}
+	if rc.URLTemplate != "" {
+		c.URLTemplate = rc.URLTemplate
+	}
+	return c
+}
+
+// hasOwnSinks reports whether an override changes which sinks run or where they write
+func (rc ReaderConfig) hasOwnSinks() bool {
+	return len(rc.Sinks) > 0 || rc.OutputFile != "" || rc.WebhookURL != ""
+}
+
+// parseReaderConfig parses a -reader-config value of the form
+// "name:key=value;key=value", e.g. "ACR122U:format=decimal;sinks=clipboard"
+func parseReaderConfig(spec string) (string, ReaderConfig, error) {
+	var rc ReaderConfig
+	name, settings, ok := strings.Cut(spec, ":")
+	if !ok || name == "" {
+		return "", rc, fmt.Errorf("%q: expected reader:key=value;...", spec)
+	}
+	for _, setting := range strings.Split(settings, ";") {
+		if setting == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return "", rc, fmt.Errorf("%q: expected key=value", setting)
+		}
+		switch key {
+		case "format":
+			rc.UIDFormat = value
+		case "decimal-width":
+			width, err := strconv.Atoi(value)
+			if err != nil || width < 0 {
+				return "", rc, fmt.Errorf("invalid decimal-width %q", value)
+			}
+			rc.DecimalWidth = width
+		case "uid-bytes":
+			if _, _, err := parseUIDBytes(value); err != nil {
+				return "", rc, err
+			}
+			rc.UIDBytes = value
+		case "sinks":
+			rc.Sinks = splitSinkNames(value)
+		case "output-file":
+			rc.OutputFile = value
+		case "webhook-url":
+			rc.WebhookURL = value
+		case "url-template":
+			rc.URLTemplate = value
+		default:
+			return "", rc, fmt.Errorf("unknown setting %q (format, decimal-width, uid-bytes, sinks, output-file, webhook-url, url-template)", key)
+		}
+	}
+	return name, rc, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseReaderConfig(t *testing.T) {
+	name, rc, err := parseReaderConfig("ACR122U:format=decimal;decimal-width=10;uid-bytes=last4;sinks=stdout, webhook;webhook-url=https://example.com/nfc;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ReaderConfig{UIDFormat: "decimal", DecimalWidth: 10, UIDBytes: "last4", Sinks: []string{"stdout", "webhook"}, WebhookURL: "https://example.com/nfc"}
+	if name != "ACR122U" || rc.UIDFormat != want.UIDFormat || rc.DecimalWidth != want.DecimalWidth || rc.UIDBytes != want.UIDBytes ||
+		!slices.Equal(rc.Sinks, want.Sinks) || rc.WebhookURL != want.WebhookURL {
+		t.Errorf("got %q %+v, want ACR122U %+v", name, rc, want)
+	}
+
+	for _, bad := range []string{
+		"format=hex",              // No reader name
+		":format=hex",             // Empty reader name
+		"ACR122U:format",          // No value
+		"ACR122U:decimal-width=x", // Bad width
+		"ACR122U:uid-bytes=mid4",  // Bad selection
+		"ACR122U:color=red",       // Unknown key
+	} {
+		if _, _, err := parseReaderConfig(bad); err == nil {
+			t.Errorf("parseReaderConfig(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestReaderOverride(t *testing.T) {
+	c := DefaultConfig()
+	c.Readers = map[string]ReaderConfig{
+		"ACR122U":           {UIDFormat: "decimal"},
+		"ACS ACR122U PICC":  {UIDFormat: "hex-reversed"},
+		"ACS":               {UIDFormat: "hex"},
+		"PICC":              {UIDBytes: "last4"},
+		"SCL3711":           {Sinks: []string{"stdout"}},
+		"SCL3711 Reader 00": {DecimalWidth: 8},
+	}
+	tests := []struct {
+		reader, key string
+	}{
+		{"ACS ACR122U PICC", "ACS ACR122U PICC"},    // Exact match
+		{"ACS ACR122U PICC 01", "ACS ACR122U PICC"}, // Longest match
+		{"ACS ACR1252 PICC", "PICC"},                // "PICC" is longer than "ACS"
+		{"SCM SCL3711 Reader 00 00", "SCL3711 Reader 00"},
+		{"Other reader", ""},
+	}
+	for _, tt := range tests {
+		key, _, ok := c.readerOverride(tt.reader)
+		if key != tt.key || ok != (tt.key != "") {
+			t.Errorf("readerOverride(%q) = %q, %v; want %q", tt.reader, key, ok, tt.key)
+		}
+	}
+
+	// Keys of equal length: the lexically first wins, every time
+	c.Readers = map[string]ReaderConfig{"ACR1": {}, "ACR2": {}, "CR12": {}}
+	for range 20 {
+		if key, _, _ := c.readerOverride("ACR122 ACR2 CR12"); key != "ACR1" {
+			t.Fatalf("tie broken as %q, want ACR1", key)
+		}
+	}
+}
+
+func TestForReader(t *testing.T) {
+	c := DefaultConfig()
+	c.URLTemplate = "https://example.com/{uid}"
+	c.Readers = map[string]ReaderConfig{"ACR122U": {UIDFormat: "decimal", Sinks: []string{"stdout"}}}
+
+	got := c.forReader("ACS ACR122U PICC")
+	if got.UIDFormat != "decimal" || !slices.Equal(got.Sinks, []string{"stdout"}) || got.URLTemplate != c.URLTemplate {
+		t.Errorf("override not applied on top of the defaults: %+v", got)
+	}
+	if got := c.forReader("Other"); got.UIDFormat != "hex" || !slices.Equal(got.Sinks, c.Sinks) {
+		t.Errorf("reader without override changed: %+v", got)
+	}
+}
+
+func TestSinksFor(t *testing.T) {
+	c := DefaultConfig()
+	c.Sinks = []string{"stdout"}
+	c.Readers = map[string]ReaderConfig{
+		"ACR122U": {Sinks: []string{"stdout-json"}},
+		"SCL3711": {UIDFormat: "decimal"}, // Format only: keeps the default sinks
+	}
+	s := NewNFCService(c)
+	if err := s.InitSinks(); err != nil {
+		t.Fatal(err)
+	}
+	s.AddSink("custom", &textSink{})
+
+	names := func(sinks []namedSink) []string {
+		var n []string
+		for _, ns := range sinks {
+			n = append(n, ns.name)
+		}
+		return n
+	}
+	for reader, want := range map[string][]string{
+		"ACS ACR122U PICC": {"stdout-json", "custom"},
+		"SCM SCL3711":      {"stdout", "custom"},
+		"Other":            {"stdout", "custom"},
+	} {
+		if got := names(s.sinksFor(reader)); !slices.Equal(got, want) {
+			t.Errorf("sinksFor(%q) = %q, want %q", reader, got, want)
+		}
+	}
+}
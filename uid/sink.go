@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+// UIDEvent describes one tag read, as handed to every sink
+type UIDEvent struct {
+	UID       []byte    // Raw UID bytes
+	Formatted string    // UID in the configured output format
+	URL       string    // URLTemplate with {uid} filled in, empty when no template is set
+	Reader    string    // PC/SC reader name
+	Time      time.Time // When the UID was read
+}
+
+// Sink receives UID events. A sink that fails does not affect the others.
+type Sink interface {
+	Handle(UIDEvent) error
+}
+
+// sinkNames lists the built-in sinks selectable with -sinks
+var sinkNames = []string{"clipboard", "keyboard", "stdout", "stdout-json", "file", "webhook"}
+
+// splitSinkNames splits a comma-separated -sinks list, trimming spaces
+// around each name so "clipboard, webhook" works, and dropping empty names
+func splitSinkNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// namedSink pairs a sink with the name used in logs
+type namedSink struct {
+	name    string
+	sink    Sink
+	builtin bool // Built from Config.Sinks, replaced by a reader override's own sinks
+}
+
+// AddSink registers an additional sink; events fan out to sinks in registration order
+func (s *NFCService) AddSink(name string, sink Sink) {
+	s.sinks = append(s.sinks, namedSink{name: name, sink: sink})
+}
+
+// newSink builds the built-in sink called name from the configuration
+func (s *NFCService) newSink(name string, config Config) (Sink, error) {
+	switch name {
+	case "clipboard":
+		if config.NoClipboard {
+			return &keyboardSink{typeText: s.typeText, logger: s.logger}, nil
+		}
+		return &clipboardSink{autoPaste: config.AutoPaste, restore: config.RestoreClipboard, paste: s.performPaste, logger: s.logger}, nil
+	case "keyboard":
+		return &keyboardSink{typeText: s.typeText, logger: s.logger}, nil
+	case "stdout":
+		return &textSink{w: os.Stdout}, nil
+	case "stdout-json":
+		return &jsonSink{w: os.Stdout}, nil
+	case "file":
+		if config.OutputFile == "" {
+			return nil, fmt.Errorf("file sink needs -output-file")
+		}
+		return s.queue(name, config.OutputFile, &fileSink{path: config.OutputFile})
+	case "webhook":
+		if config.WebhookURL == "" {
+			return nil, fmt.Errorf("webhook sink needs -webhook-url")
+		}
+		webhook := &webhookSink{url: config.WebhookURL, client: &http.Client{Timeout: 5 * time.Second}}
+		// Rate limit inside the queue: a failed delivery isn't recorded as
+		// published, so the queue's retry still gets through the limiter
+		return s.queue(name, config.WebhookURL, s.rateLimit(name, webhook))
+	default:
+		return nil, fmt.Errorf("unknown sink %q (available: %s)", name, strings.Join(sinkNames, ", "))
+	}
+}
+
+// skipClipboard reports whether the clipboard sink should be left out
+// because no clipboard tool is installed (headless Linux) while other sinks
+// are enabled; a clipboard that fails at runtime only fails that sink
+func (s *NFCService) skipClipboard(name string, config Config) bool {
+	if name != "clipboard" || config.NoClipboard || !clipboard.Unsupported || len(config.Sinks) < 2 {
+		return false
+	}
+	s.logger.Warnf("No clipboard available (install xclip, xsel or wl-clipboard), continuing without the clipboard sink")
+	return true
+}
+
+// rateLimit wraps a network sink so the same UID is published at most once
+// per SinkMinInterval; other sinks are not limited
+func (s *NFCService) rateLimit(name string, sink Sink) Sink {
+	if s.config.SinkMinInterval <= 0 {
+		return sink
+	}
+	return &rateLimitedSink{
+		name:     name,
+		inner:    sink,
+		interval: s.config.SinkMinInterval,
+		last:     make(map[string]time.Time),
+		logger:   s.logger,
+	}
+}
+
+// sinksFor returns the sinks for a reader: its override's own built-in sinks
+// in place of the default ones when it has them, plus any AddSink sinks
+func (s *NFCService) sinksFor(reader string) []namedSink {
+	key, _, ok := s.config.readerOverride(reader)
+	own, hasOwn := s.readerSinks[key]
+	if !ok || !hasOwn {
+		return s.sinks
+	}
+	sinks := append([]namedSink(nil), own...)
+	for _, ns := range s.sinks {
+		if !ns.builtin {
+			sinks = append(sinks, ns)
+		}
+	}
+	return sinks
+}
+
+// dispatch hands the event to every sink for its reader, logging failures
+// per sink. It only fails when no sink handled the event.
+func (s *NFCService) dispatch(event UIDEvent) error {
+	sinks := s.sinksFor(event.Reader)
+	if len(sinks) == 0 {
+		return nil
+	}
+	var lastErr error
+	handled := 0
+	for _, ns := range sinks {
+		if err := ns.sink.Handle(event); err != nil {
+			s.logger.Warnf("Sink %s failed: %v", ns.name, err)
+			lastErr = fmt.Errorf("%s: %w", ns.name, err)
+			continue
+		}
+		handled++
+	}
+	if handled == 0 {
+		return fmt.Errorf("all sinks failed, last error: %w", lastErr)
+	}
+	return nil
+}
+
+// Text returns what text outputs should emit: the composed URL if set, otherwise the formatted UID
+func (e UIDEvent) Text() string {
+	if e.URL != "" {
+		return e.URL
+	}
+	return e.Formatted
+}
+
+// eventJSON is the JSON form of a UIDEvent used by the stdout, file and webhook sinks
+type eventJSON struct {
+	UID       string `json:"uid"`
+	Formatted string `json:"formatted"`
+	URL       string `json:"url,omitempty"`
+	Reader    string `json:"reader"`
+	Time      string `json:"time"`
+}
+
+// marshalEvent encodes an event as a single JSON line
+func marshalEvent(event UIDEvent) ([]byte, error) {
+	data, err := json.Marshal(eventJSON{
+		UID:       strings.ToUpper(hex.EncodeToString(event.UID)),
+		Formatted: event.Formatted,
+		URL:       event.URL,
+		Reader:    event.Reader,
+		Time:      event.Time.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// clipboardRestoreDelay gives the target application time to read the pasted
+// UID before the previous clipboard contents are put back
+const clipboardRestoreDelay = 300 * time.Millisecond
+
+// clipboardSink copies the UID to the clipboard and optionally pastes it followed by Enter
+type clipboardSink struct {
+	autoPaste bool
+	restore   bool // Put the previous clipboard text back after pasting
+	paste     func() error
+	logger    *leveledLogger
+}
+
+func (c *clipboardSink) Handle(event UIDEvent) error {
+	var prev string
+	canRestore := false
+	if c.restore {
+		text, err := clipboard.ReadAll()
+		switch {
+		case err != nil:
+			c.logger.Warnf("Cannot save clipboard, it will not be restored: %v", err)
+		case text == "":
+			c.logger.Debugf("Clipboard is empty or not text, nothing to restore")
+		default:
+			prev, canRestore = text, true
+		}
+	}
+
+	if err := clipboard.WriteAll(event.Text()); err != nil {
+		return fmt.Errorf("failed to copy to clipboard: %w", err)
+	}
+	c.logger.Infof("Copied to clipboard: %s", event.Text())
+
+	if c.autoPaste {
+		if err := c.paste(); err != nil {
+			c.logger.Warnf("Auto-paste failed: %v", err)
+			// Don't return error here, clipboard copy was successful
+		} else {
+			c.logger.Infof("Auto-pasted UID and pressed Enter")
+		}
+	}
+
+	if canRestore {
+		time.Sleep(clipboardRestoreDelay)
+		if err := clipboard.WriteAll(prev); err != nil {
+			c.logger.Warnf("Failed to restore clipboard: %v", err)
+		} else {
+			c.logger.Debugf("Restored previous clipboard contents")
+		}
+	}
+	return nil
+}
+
+// keyboardSink types the UID as keystrokes followed by Enter. It is slower
+// than pasting but nothing is left behind in the clipboard.
+type keyboardSink struct {
+	typeText func(string) error
+	logger   *leveledLogger
+}
+
+func (k *keyboardSink) Handle(event UIDEvent) error {
+	if err := k.typeText(event.Text()); err != nil {
+		return err
+	}
+	k.logger.Infof("Typed UID and pressed Enter")
+	return nil
+}
+
+// textSink writes the event text alone, one line per event, for piping
+type textSink struct {
+	w io.Writer
+}
+
+func (t *textSink) Handle(event UIDEvent) error {
+	_, err := fmt.Fprintln(t.w, event.Text())
+	return err
+}
+
+// jsonSink writes one JSON object per event
+type jsonSink struct {
+	w io.Writer
+}
+
+func (j *jsonSink) Handle(event UIDEvent) error {
+	line, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(line)
+	return err
+}
+
+// fileSink appends one JSON line per event to a file
+type fileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (f *fileSink) Handle(event UIDEvent) error {
+	line, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rateLimitedSink drops events for a UID published less than interval ago.
+// Suppressed events are not failures and are only logged at debug level.
+type rateLimitedSink struct {
+	name     string
+	inner    Sink
+	interval time.Duration
+	last     map[string]time.Time // Last publish time per UID (hex)
+	logger   *leveledLogger
+}
+
+func (r *rateLimitedSink) Handle(event UIDEvent) error {
+	key := hex.EncodeToString(event.UID)
+	if prev, ok := r.last[key]; ok && event.Time.Sub(prev) < r.interval {
+		r.logger.Debugf("Sink %s: suppressed %s, published %v ago (limit %v)",
+			r.name, event.Formatted, event.Time.Sub(prev).Round(time.Millisecond), r.interval)
+		return nil
+	}
+	if err := r.inner.Handle(event); err != nil {
+		return err // Not recorded, so a retry isn't suppressed
+	}
+	r.last[key] = event.Time
+
+	// Forget UIDs whose window has passed so the map stays small
+	for uid, t := range r.last {
+		if event.Time.Sub(t) >= r.interval {
+			delete(r.last, uid)
+		}
+	}
+	return nil
+}
+
+// webhookSink POSTs each event as JSON to a URL
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhookSink) Handle(event UIDEvent) error {
+	body, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// traceAPDU writes one exchange to w: the command, then the response with
+// its decoded status word, or the transmit error
+func traceAPDU(w io.Writer, cmd, resp []byte, err error, elapsed time.Duration) {
+	if w == nil {
+		return
+	}
+	now := time.Now().Format("15:04:05.000")
+	fmt.Fprintf(w, "[trace] %s > % X\n", now, cmd)
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "[trace] %s < error: %v [%v]\n", now, err, elapsed.Round(time.Microsecond))
+	case len(resp) < 2:
+		fmt.Fprintf(w, "[trace] %s < % X (no status word) [%v]\n", now, resp, elapsed.Round(time.Microsecond))
+	default:
+		sw := uint16(resp[len(resp)-2])<<8 | uint16(resp[len(resp)-1])
+		fmt.Fprintf(w, "[trace] %s < % X  SW=%04X %s [%v]\n", now, resp, sw, statusWordText(sw), elapsed.Round(time.Microsecond))
+	}
+}
+
+// statusWordText describes the ISO 7816-4 status words PC/SC readers return
+func statusWordText(sw uint16) string {
+	switch sw >> 8 {
+	case 0x61:
+		return fmt.Sprintf("%d more bytes, GET RESPONSE", sw&0xFF)
+	case 0x6C:
+		return fmt.Sprintf("wrong Le, exact length %d", sw&0xFF)
+	}
+	switch sw {
+	case 0x9000:
+		return "success"
+	case 0x6281:
+		return "part of returned data may be corrupted"
+	case 0x6282:
+		return "end of file reached before Le bytes"
+	case 0x6300:
+		return "operation failed"
+	case 0x6581:
+		return "memory failure"
+	case 0x6700:
+		return "wrong length"
+	case 0x6800:
+		return "CLA function not supported"
+	case 0x6981:
+		return "command incompatible with file structure"
+	case 0x6982:
+		return "security status not satisfied"
+	case 0x6985:
+		return "conditions of use not satisfied"
+	case 0x6986:
+		return "command not allowed"
+	case 0x6A81:
+		return "function not supported"
+	case 0x6A82:
+		return "file or application not found"
+	case 0x6A86:
+		return "incorrect P1/P2"
+	case 0x6B00:
+		return "wrong parameters P1/P2"
+	case 0x6D00:
+		return "instruction not supported"
+	case 0x6E00:
+		return "class not supported"
+	}
+	return "unknown"
+}